@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/migrations"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// GET /api/admin/schema
+//
+// Reports the applied schema version alongside the migrations embedded in
+// this build, so a deploy that skipped migrating is easy to spot.
+func GetSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	all, err := models.LoadMigrations(migrations.FS, ".")
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status, err := models.GetSchemaStatus(db, all)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
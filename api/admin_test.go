@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lawn-chair/mealplan/migrations"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSchemaHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	all, err := models.LoadMigrations(migrations.FS, ".")
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	rows := sqlmock.NewRows([]string{"version_id", "is_applied"})
+	for _, m := range all[:len(all)-1] {
+		rows.AddRow(m.Version, true)
+	}
+	mock.ExpectQuery("SELECT to_regclass").WithArgs("goose_db_version").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT DISTINCT ON").WillReturnRows(rows)
+
+	req := httptest.NewRequest("GET", "/api/admin/schema", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "db", db))
+	rec := httptest.NewRecorder()
+	GetSchemaHandler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var status models.SchemaStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, all[len(all)-2].Version, status.Version)
+	assert.Equal(t, all[len(all)-1].Version, status.LatestVersion)
+	assert.Equal(t, []string{all[len(all)-1].Name}, status.Pending)
+	assert.False(t, status.UpToDate)
+}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/clerk/clerk-sdk-go/v2/user"
 	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/utils"
 )
 
 var RequiresAuthentication = func(r *http.Request) (*clerk.User, error) {
@@ -38,3 +40,29 @@ func GetHouseholdIDForUser(db *sqlx.DB, userID string) (int, error) {
 	}
 	return householdID, nil
 }
+
+// optionalHousehold returns the household of the signed-in user on routes
+// that also serve anonymous requests. It is 0 when there is no user or the
+// user has no household.
+func optionalHousehold(r *http.Request, db *sqlx.DB) int {
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		return 0
+	}
+	householdID, err := GetHouseholdIDForUser(db, user.ID)
+	if err != nil {
+		return 0
+	}
+	return householdID
+}
+
+// IsAdmin reports whether a user may manage the server itself. Admins are
+// listed by user ID in ADMIN_USER_IDS, separated by commas.
+func IsAdmin(userID string) bool {
+	for _, id := range strings.Split(utils.GetEnv("ADMIN_USER_IDS", ""), ",") {
+		if id = strings.TrimSpace(id); id != "" && id == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// GET /api/collections
+func GetCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	user := r.Context().Value("user").(*clerk.User)
+
+	collections, err := models.GetCollections(db, user.ID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(collections)
+}
+
+// POST /api/collections
+func CreateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	user := r.Context().Value("user").(*clerk.User)
+
+	data := new(models.Collection)
+	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collection, err := models.CreateCollection(db, user.ID, data)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCollection) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// GET /api/collections/{collection}
+//
+// The collection is named by id or by name, so /api/collections/favorites
+// always works.
+func GetCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	user := r.Context().Value("user").(*clerk.User)
+
+	collection, err := models.GetCollection(db, user.ID, chi.URLParam(r, "collection"))
+	if err != nil {
+		collectionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(collection)
+}
+
+// DELETE /api/collections/{collection}
+func DeleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	user := r.Context().Value("user").(*clerk.User)
+
+	collection, err := models.GetCollection(db, user.ID, chi.URLParam(r, "collection"))
+	if err != nil {
+		collectionError(w, err)
+		return
+	}
+	if collection.ID != 0 {
+		if err := models.DeleteCollection(db, user.ID, collection.ID); err != nil {
+			collectionError(w, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/collections/{collection}/items
+func AddCollectionItemHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	user := r.Context().Value("user").(*clerk.User)
+
+	item := new(models.CollectionItem)
+	if err := json.NewDecoder(r.Body).Decode(item); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collection, err := models.AddToCollection(db, user.ID, chi.URLParam(r, "collection"), item)
+	if err != nil {
+		collectionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(collection)
+}
+
+// DELETE /api/collections/{collection}/items?recipe_id=|meal_id=
+func RemoveCollectionItemHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	user := r.Context().Value("user").(*clerk.User)
+
+	item := models.CollectionItem{}
+	for param, field := range map[string]**int{"recipe_id": &item.RecipeID, "meal_id": &item.MealID} {
+		if value := r.URL.Query().Get(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				ErrorResponse(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			*field = &id
+		}
+	}
+
+	collection, err := models.RemoveFromCollection(db, user.ID, chi.URLParam(r, "collection"), &item)
+	if err != nil {
+		collectionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(collection)
+}
+
+func collectionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidCollection):
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		ErrorResponse(w, "Collection not found", http.StatusNotFound)
+	default:
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// requestCollection loads the collection named by a list handler's
+// collection= parameter. It returns nil when the parameter isn't set, and
+// false after writing an error response when the collection can't be used.
+func requestCollection(w http.ResponseWriter, r *http.Request, db *sqlx.DB) (*models.Collection, bool) {
+	ref := r.URL.Query().Get("collection")
+	if ref == "" {
+		return nil, true
+	}
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return nil, false
+	}
+	collection, err := models.GetCollection(db, user.ID, ref)
+	if err != nil {
+		collectionError(w, err)
+		return nil, false
+	}
+	return collection, true
+}
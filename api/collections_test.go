@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRecipesByCollection(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/recipes?collection=weeknight", nil)
+		rec := httptest.NewRecorder()
+		GetRecipes(rec, req.WithContext(context.WithValue(req.Context(), "db", db)))
+		return rec
+	}
+
+	t.Run("requires sign in", func(t *testing.T) {
+		orig := RequiresAuthentication
+		RequiresAuthentication = func(r *http.Request) (*clerk.User, error) { return nil, assert.AnError }
+		defer func() { RequiresAuthentication = orig }()
+
+		assert.Equal(t, http.StatusUnauthorized, request().Code)
+	})
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	t.Run("filters", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM collections").WithArgs("test-user", "weeknight").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(3, "test-user", "Weeknight"))
+		mock.ExpectQuery("SELECT recipe_id, meal_id FROM collection_items").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "meal_id"}).AddRow(2, nil))
+		mock.ExpectQuery("SELECT \\* FROM recipes").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).
+				AddRow(1, "Soup", "", "soup", nil).
+				AddRow(2, "Tacos", "", "tacos", nil))
+
+		rec := request()
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var recipes []models.Recipe
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&recipes))
+		require.Len(t, recipes, 1)
+		assert.Equal(t, "Tacos", recipes[0].Name)
+	})
+
+	t.Run("unknown collection", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM collections").WithArgs("test-user", "weeknight").
+			WillReturnError(sql.ErrNoRows)
+
+		assert.Equal(t, http.StatusNotFound, request().Code)
+	})
+}
+
+func TestCreateCollectionHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("user1", "Weeknight").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	req := httptest.NewRequest("POST", "/api/collections", bytes.NewBufferString(`{"name": "Weeknight"}`))
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+	rec := httptest.NewRecorder()
+	CreateCollectionHandler(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveCollectionItemHandler(t *testing.T) {
+	db, _ := setupMockDB(t)
+	defer db.Close()
+
+	req := httptest.NewRequest("DELETE", "/api/collections/3/items?recipe_id=abc", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("collection", "3")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "db", db)
+	ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+	rec := httptest.NewRecorder()
+	RemoveCollectionItemHandler(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
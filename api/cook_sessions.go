@@ -0,0 +1,199 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// CookSessionPollInterval is how often a session stream checks for changes
+// made from other devices. Polling the database keeps streams working
+// whichever server instance handled the change.
+var CookSessionPollInterval = time.Second
+
+// cookSessionKeepAlive is how often an idle stream writes a comment, so
+// proxies don't close it
+const cookSessionKeepAlive = 15 * time.Second
+
+// cookSessionError writes the response for a failed session lookup or update
+func cookSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidCookSession):
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		ErrorResponse(w, "Cook session not found", http.StatusNotFound)
+	default:
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// cookSessionID reads the session named in the URL. It returns false after
+// writing an error response.
+func cookSessionID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		ErrorResponse(w, "Invalid session ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// getUserCookSession loads the user's session named in the URL, writing an
+// error response and returning nil if it isn't theirs or isn't for the
+// recipe in the URL.
+func getUserCookSession(w http.ResponseWriter, r *http.Request, userID string) *models.CookSession {
+	db := r.Context().Value("db").(*sqlx.DB)
+	recipeID := r.Context().Value("id").(int)
+
+	id, ok := cookSessionID(w, r)
+	if !ok {
+		return nil
+	}
+	session, err := models.GetCookSession(db, userID, id)
+	if err == nil && session.RecipeID != recipeID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		cookSessionError(w, err)
+		return nil
+	}
+	return session
+}
+
+// POST /api/recipes/{id}/cook-sessions
+//
+// Starts following the recipe, or returns the session already under way.
+func StartCookSession(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+
+	session, created, err := models.StartCookSession(db, user.ID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ErrorResponse(w, "Recipe not found", http.StatusNotFound)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(session)
+}
+
+// GET /api/recipes/{id}/cook-sessions/{sessionID}
+func GetCookSession(w http.ResponseWriter, r *http.Request) {
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	session := getUserCookSession(w, r, user.ID)
+	if session == nil {
+		return
+	}
+	json.NewEncoder(w).Encode(session)
+}
+
+// PATCH /api/recipes/{id}/cook-sessions/{sessionID}
+func UpdateCookSession(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	session := getUserCookSession(w, r, user.ID)
+	if session == nil {
+		return
+	}
+
+	data := new(models.CookSessionUpdate)
+	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err = models.UpdateCookSession(db, user.ID, session.ID, data)
+	if err != nil {
+		cookSessionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(session)
+}
+
+// GET /api/recipes/{id}/cook-sessions/{sessionID}/stream
+//
+// Streams the session as server-sent events: once when connected, then each
+// time it changes. The stream ends after the session is finished.
+func StreamCookSession(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	session := getUserCookSession(w, r, user.ID)
+	if session == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		ErrorResponse(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(session *models.CookSession) {
+		data, _ := json.Marshal(session)
+		fmt.Fprintf(w, "event: session\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+	send(session)
+
+	poll := time.NewTicker(CookSessionPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(cookSessionKeepAlive)
+	defer keepAlive.Stop()
+	for session.FinishedAt == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-poll.C:
+			latest, err := models.GetCookSession(db, user.ID, session.ID)
+			if err != nil {
+				// The session is gone with its recipe
+				fmt.Println("Error polling cook session:", err)
+				return
+			}
+			if latest.UpdatedAt.Equal(session.UpdatedAt) {
+				continue
+			}
+			session = latest
+			send(session)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var cookSessionColumns = []string{"id", "user_id", "recipe_id", "current_step", "checked_ingredients", "timers", "started_at", "updated_at", "finished_at"}
+
+func cookSessionRequest(db *sqlx.DB, method, path, sessionID string, recipeID int, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("sessionID", sessionID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "db", db)
+	ctx = context.WithValue(ctx, "id", recipeID)
+	return req.WithContext(ctx)
+}
+
+func TestUpdateCookSessionOtherRecipe(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE id=\\$1 AND user_id=\\$2").WithArgs(4, "test-user").
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "test-user", 1, 1, "{}", []byte(`[]`), now, now, nil))
+
+	rec := httptest.NewRecorder()
+	UpdateCookSession(rec, cookSessionRequest(db, "PATCH", "/api/recipes/2/cook-sessions/4", "4", 2, `{"current_step":2}`))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamCookSession(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+	origInterval := CookSessionPollInterval
+	CookSessionPollInterval = time.Millisecond
+	defer func() { CookSessionPollInterval = origInterval }()
+
+	started := time.Now()
+	advanced := started.Add(time.Minute)
+	mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE id=\\$1 AND user_id=\\$2").WithArgs(4, "test-user").
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "test-user", 1, 1, "{}", []byte(`[]`), started, started, nil))
+	mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE id=\\$1 AND user_id=\\$2").WithArgs(4, "test-user").
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "test-user", 1, 1, "{}", []byte(`[]`), started, started, nil))
+	mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE id=\\$1 AND user_id=\\$2").WithArgs(4, "test-user").
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "test-user", 1, 2, "{}", []byte(`[]`), started, advanced, nil))
+	mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE id=\\$1 AND user_id=\\$2").WithArgs(4, "test-user").
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "test-user", 1, 2, "{}", []byte(`[]`), started, advanced.Add(time.Second), advanced))
+
+	rec := httptest.NewRecorder()
+	StreamCookSession(rec, cookSessionRequest(db, "GET", "/api/recipes/1/cook-sessions/4/stream", "4", 1, ""))
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	// Connected, advanced to step 2, then finished; the unchanged poll sends nothing
+	assert.Equal(t, 3, strings.Count(rec.Body.String(), "event: session\n"))
+	assert.Contains(t, rec.Body.String(), `"current_step":2`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
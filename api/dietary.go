@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// GET /api/household/dietary-profiles
+func GetDietaryProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	profiles, err := models.GetDietaryProfiles(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// PUT /api/household/dietary-profiles/{userID}
+func UpdateDietaryProfileHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	profile := new(models.DietaryProfile)
+	if err := json.NewDecoder(r.Body).Decode(profile); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	profile.UserID = chi.URLParam(r, "userID")
+	profile.HouseholdID = householdID
+
+	profile, err := models.SaveDietaryProfile(db, profile)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrUnknownDiet):
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		case err == models.ErrNotHouseholdMember:
+			ErrorResponse(w, err.Error(), http.StatusNotFound)
+		default:
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(profile)
+}
+
+// GET /api/ingredient-catalog
+func GetIngredientCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	catalog, err := models.GetIngredientCatalog(db)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(catalog)
+}
+
+// PUT /api/ingredient-catalog
+//
+// Replaces the categories of each ingredient in the body; other catalog
+// entries are left alone. The catalog is shared by every household, so only
+// admins may change it.
+func UpdateIngredientCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	entries := []models.IngredientCategories{}
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SaveIngredientCategories(db, entries); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	GetIngredientCatalogHandler(w, r)
+}
+
+// withMealWarnings attaches dietary warnings for the requesting user's
+// household. Meals can be read anonymously, in which case householdID is 0
+// and there is nothing to check against.
+func withMealWarnings(db *sqlx.DB, householdID int, meal *models.Meal) *models.Meal {
+	if householdID == 0 {
+		return meal
+	}
+
+	warnings, err := models.MealWarnings(db, householdID, meal)
+	if err != nil {
+		fmt.Println("Error checking meal against dietary profiles:", err)
+		return meal
+	}
+	meal.Warnings = warnings
+	return meal
+}
+
+// withPlanWarnings attaches dietary warnings to a plan. Like nutrition, a
+// failed check is logged rather than failing the request.
+func withPlanWarnings(db *sqlx.DB, householdID int, plan *models.Plan) *models.Plan {
+	warnings, err := models.PlanWarnings(db, householdID, plan.Meals)
+	if err != nil {
+		fmt.Println("Error checking plan against dietary profiles:", err)
+		return plan
+	}
+	plan.Warnings = warnings
+	return plan
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var profileColumns = []string{"user_id", "household_id", "allergens", "excluded_ingredients", "diets"}
+
+func TestUpdateDietaryProfileHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	request := func(userID string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/household/dietary-profiles/"+userID, bytes.NewBufferString(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("userID", userID)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, "db", db)
+		ctx = context.WithValue(ctx, "household", 42)
+		rec := httptest.NewRecorder()
+		UpdateDietaryProfileHandler(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("saves", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(42, "user1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO dietary_profiles").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rec := request("user1", `{"allergens":["Peanut"],"diets":["vegetarian"]}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var profile models.DietaryProfile
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+		assert.Equal(t, "user1", profile.UserID)
+		assert.Equal(t, 42, profile.HouseholdID)
+		assert.Equal(t, pq.StringArray{"peanut"}, profile.Allergens)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown diet", func(t *testing.T) {
+		rec := request("user1", `{"diets":["carnivore"]}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(42, "stranger").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		rec := request("stranger", `{}`)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdatePlanRejectsViolations(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(1, start, start.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+
+	// PlanWarnings
+	mock.ExpectQuery("SELECT p.\\* FROM dietary_profiles").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(profileColumns).
+			AddRow("user1", 42, "{peanut}", "{}", "{}"))
+	mock.ExpectQuery("SELECT name, category FROM ingredient_categories").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "category"}).AddRow("peanut", "peanut"))
+	mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image", "servings"}).
+			AddRow(7, "Satay", "", "satay", nil, 4))
+	mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "name", "meal_id"}).
+			AddRow(1, "1/2 cup", "peanut sauce", 7))
+	mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "text", "order", "meal_id"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+	mock.ExpectQuery("SELECT t.name FROM tags t").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	// The meal is only planned for a day, not listed in meals
+	body := `{"start_date":"2026-10-19","end_date":"2026-10-25","meals":[],"slots":[{"date":"2026-10-20","slot":"dinner","meal_id":7}]}`
+	req := httptest.NewRequest("PUT", "/api/plans/1?on_violation=reject", bytes.NewBufferString(body))
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "id", 1)
+	ctx = context.WithValue(ctx, "household", 42)
+	ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+	rec := httptest.NewRecorder()
+	UpdatePlan(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	var resp struct {
+		Warnings []models.DietaryWarning `json:"warnings"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Warnings, 1)
+	assert.Equal(t, models.DietaryWarning{UserID: "user1", MealID: 7, Ingredient: "peanut sauce", Kind: models.WarningAllergen, Detail: "peanut"}, resp.Warnings[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// GET /api/household/equipment
+func GetHouseholdEquipmentHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	equipment, err := models.GetHouseholdEquipment(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(equipment)
+}
+
+// PUT /api/household/equipment
+func UpdateHouseholdEquipmentHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	data := []string{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	equipment, err := models.SetHouseholdEquipment(db, householdID, data)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(equipment)
+}
+
+// requestOwnedEquipment loads the household's equipment when a list handler
+// is asked for owned_equipment=true. It returns nil when the parameter isn't
+// set or the household hasn't listed any equipment, and false after writing
+// an error response.
+func requestOwnedEquipment(w http.ResponseWriter, r *http.Request, db *sqlx.DB) ([]string, bool) {
+	if r.URL.Query().Get("owned_equipment") != "true" {
+		return nil, true
+	}
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return nil, false
+	}
+	householdID, err := GetHouseholdIDForUser(db, user.ID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if householdID == 0 {
+		ErrorResponse(w, "Join a household to filter by its equipment", http.StatusForbidden)
+		return nil, false
+	}
+	equipment, err := models.GetHouseholdEquipment(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(equipment) == 0 {
+		return nil, true
+	}
+	return equipment, true
+}
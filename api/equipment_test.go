@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMealsOwnedEquipment(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	mock.ExpectQuery("SELECT household_id FROM household_members").WithArgs("test-user").
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(42))
+	mock.ExpectQuery("SELECT equipment FROM households WHERE id=\\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"equipment"}).AddRow("{oven}"))
+	mock.ExpectQuery("SELECT \\* FROM meals WHERE deleted_at IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug"}).
+			AddRow(1, "Roast", "Sunday", "roast").
+			AddRow(2, "Chili", "Slow", "chili"))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	// The chili's recipe needs a slow cooker
+	mock.ExpectQuery("SELECT id AS meal_id, equipment FROM meals").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "equipment"}).
+			AddRow(1, "{oven}").
+			AddRow(2, "{}").
+			AddRow(2, "{\"slow cooker\"}"))
+
+	req := httptest.NewRequest("GET", "/api/meals?owned_equipment=true", nil)
+	rec := httptest.NewRecorder()
+	GetMealsHandler(rec, req.WithContext(context.WithValue(req.Context(), "db", db)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var meals []models.Meal
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &meals))
+	require.Len(t, meals, 1)
+	assert.Equal(t, "Roast", meals[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateHouseholdEquipmentHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE households SET equipment=\\$1 WHERE id=\\$2").WithArgs(sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest("PUT", "/api/household/equipment", strings.NewReader(`["Instapot", "Oven", "oven"]`))
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "household", 42)
+	rec := httptest.NewRecorder()
+	UpdateHouseholdEquipmentHandler(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `["instant pot", "oven"]`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
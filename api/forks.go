@@ -0,0 +1,93 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// POST /api/recipes/{id}/fork
+func ForkRecipe(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	householdID, err := GetHouseholdIDForUser(db, user.ID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if householdID == 0 {
+		ErrorResponse(w, "Join a household to fork recipes", http.StatusForbidden)
+		return
+	}
+
+	recipe, err := models.ForkRecipe(db, id, householdID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ErrorResponse(w, "Recipe not found", http.StatusNotFound)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(recipe)
+}
+
+// GET /api/recipes/{id}/upstream
+func GetRecipeUpstream(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	upstream, err := models.GetRecipeUpstream(db, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ErrorResponse(w, "Recipe not found", http.StatusNotFound)
+		case errors.Is(err, models.ErrNotForked):
+			ErrorResponse(w, err.Error(), http.StatusNotFound)
+		default:
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(upstream)
+}
+
+// canEditRecipe checks that a fork belongs to the user's household before it
+// is changed. Shared recipes can be changed by anyone. It returns false
+// after writing an error response.
+func canEditRecipe(w http.ResponseWriter, db *sqlx.DB, id int, user *clerk.User) bool {
+	owner, err := models.GetRecipeOwner(db, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ErrorResponse(w, "Recipe not found", http.StatusNotFound)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return false
+	}
+	if owner == nil {
+		return true
+	}
+	householdID, err := GetHouseholdIDForUser(db, user.ID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if householdID != *owner {
+		ErrorResponse(w, "This recipe belongs to another household; fork it to make your own changes", http.StatusForbidden)
+		return false
+	}
+	return true
+}
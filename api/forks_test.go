@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForkRecipeWithoutHousehold(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	mock.ExpectQuery("SELECT household_id FROM household_members").WithArgs("test-user").
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}))
+
+	req := httptest.NewRequest("POST", "/api/recipes/1/fork", nil)
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "id", 1)
+	rec := httptest.NewRecorder()
+	ForkRecipe(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRecipeOwnedByAnotherHousehold(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	mock.ExpectQuery("SELECT household_id FROM recipes WHERE id=\\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(7))
+	mock.ExpectQuery("SELECT household_id FROM household_members").WithArgs("test-user").
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(42))
+
+	req := httptest.NewRequest("PUT", "/api/recipes/5", strings.NewReader(`{"name":"Stew","description":"Mine now"}`))
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "id", 5)
+	rec := httptest.NewRecorder()
+	UpdateRecipe(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
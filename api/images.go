@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
 
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
@@ -29,47 +32,83 @@ func PostImageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
+	storageURL, err := StoreImage(ctx, fileHeader.Filename, file, fileHeader.Size)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"url": storageURL})
+}
+
+// StoreImage uploads an image to object storage and returns its public URL.
+// It is a variable so tests and bulk imports can substitute their own storage.
+var StoreImage = func(ctx context.Context, filename string, file io.Reader, size int64) (string, error) {
 	goEnv := utils.GetEnv("GO_ENV", "development")
 	storageEndpoint := utils.GetEnv("AWS_ENDPOINT_URL_S3", "localhost:9000")
-	storageBucket := utils.GetEnv("BUCKET_NAME", "mp-images")
-
-	useSSL := false
-	if goEnv == "production" {
-		useSSL = true
-	}
 
-	minioClient, err := minio.New(storageEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(utils.GetEnv("AWS_ACCESS_KEY_ID", ""), utils.GetEnv("AWS_SECRET_ACCESS_KEY", ""), ""),
-		Secure: useSSL,
-		Region: "auto",
-	})
+	minioClient, storageBucket, err := ImageStorage()
 	if err != nil {
-		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", err
 	}
 
 	uuid := uuid.New()
 
 	_, err = minioClient.PutObject(ctx,
 		storageBucket,
-		uuid.String()+fileHeader.Filename,
+		uuid.String()+filename,
 		file,
-		fileHeader.Size,
+		size,
 		minio.PutObjectOptions{ContentType: "application/octet-stream"})
 
 	if err != nil {
 		fmt.Println("Error uploading file:", err)
-		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", err
 	}
-	fmt.Println("Uploaded file: ", uuid.String()+fileHeader.Filename)
+	fmt.Println("Uploaded file: ", uuid.String()+filename)
 
 	var storageURL string
 	if goEnv == "production" {
-		storageURL = "https://" + storageBucket + "." + storageEndpoint + "/" + uuid.String() + fileHeader.Filename
+		storageURL = "https://" + storageBucket + "." + storageEndpoint + "/" + uuid.String() + filename
 	} else {
-		storageURL = "http://" + storageEndpoint + "/" + storageBucket + "/" + uuid.String() + fileHeader.Filename
+		storageURL = "http://" + storageEndpoint + "/" + storageBucket + "/" + uuid.String() + filename
 	}
+	return storageURL, nil
+}
 
-	json.NewEncoder(w).Encode(map[string]string{"url": storageURL})
+// DeleteImage removes an image uploaded by StoreImage, given its public URL.
+// It is a variable for the same reason as StoreImage.
+var DeleteImage = func(ctx context.Context, storageURL string) error {
+	u, err := url.Parse(storageURL)
+	if err != nil {
+		return err
+	}
+
+	minioClient, storageBucket, err := ImageStorage()
+	if err != nil {
+		return err
+	}
+
+	err = minioClient.RemoveObject(ctx, storageBucket, path.Base(u.Path), minio.RemoveObjectOptions{})
+	if err != nil {
+		fmt.Println("Error deleting file:", err)
+		return err
+	}
+	return nil
+}
+
+// ImageStorage returns a client for the image bucket and the bucket's name
+func ImageStorage() (*minio.Client, string, error) {
+	storageEndpoint := utils.GetEnv("AWS_ENDPOINT_URL_S3", "localhost:9000")
+	storageBucket := utils.GetEnv("BUCKET_NAME", "mp-images")
+
+	client, err := minio.New(storageEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(utils.GetEnv("AWS_ACCESS_KEY_ID", ""), utils.GetEnv("AWS_SECRET_ACCESS_KEY", ""), ""),
+		Secure: utils.GetEnv("GO_ENV", "development") == "production",
+		Region: "auto",
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return client, storageBucket, nil
 }
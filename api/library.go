@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// Largest library archive accepted by ImportLibraryHandler
+const maxLibrarySize = 100 << 20
+
+// FetchExportImage downloads recipe and meal images for zip exports. Image
+// URLs are user supplied, so only public addresses are fetched.
+var FetchExportImage models.Fetcher = models.HTTPFetcher(models.PublicHTTPClient(30*time.Second), models.MaxLibraryImageSize)
+
+// GET /api/export?format=json|zip
+func ExportLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "zip" {
+		ErrorResponse(w, "unknown export format, expected json or zip", http.StatusBadRequest)
+		return
+	}
+
+	lib, err := models.ExportLibrary(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("mealplan-library-%s.%s", lib.ExportedAt.Format("2006-01-02"), format)
+
+	if format == "zip" {
+		var b bytes.Buffer
+		if err := models.WriteLibraryArchive(r.Context(), &b, lib, FetchExportImage); err != nil {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		w.Write(b.Bytes())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	json.NewEncoder(w).Encode(lib)
+}
+
+// POST /api/import?strategy=skip|rename|overwrite
+//
+// The body is either a JSON library document or a zip archive produced by
+// the export endpoint. Images inside an archive are uploaded to storage for
+// the recipes and meals that are saved, and deleted again if the import fails.
+func ImportLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	strategy := r.URL.Query().Get("strategy")
+	if strategy == "" {
+		strategy = models.ConflictSkip
+	}
+	if !models.ValidConflictStrategy(strategy) {
+		ErrorResponse(w, models.ErrUnknownConflictStrategy.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLibrarySize))
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lib, images, err := models.ReadLibrary(data)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upload, undo := ArchiveImageUploader(r.Context(), lib, images)
+	result, err := models.ImportLibrary(db, householdID, lib, strategy, upload)
+	if err != nil {
+		undo()
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// ArchiveImageUploader returns a function for models.ImportLibrary that
+// uploads an archive's image with StoreImage once a record using it is saved,
+// and keeps URLs that aren't in the archive. undo deletes every uploaded
+// image, for when the import fails.
+func ArchiveImageUploader(ctx context.Context, lib *models.Library, images map[string][]byte) (upload func(imageURL string) (string, error), undo func()) {
+	uploaded := map[string]string{}
+	upload = func(imageURL string) (string, error) {
+		if newURL, ok := uploaded[imageURL]; ok {
+			return newURL, nil
+		}
+		image, ok := images[imageURL]
+		if !ok {
+			return imageURL, nil
+		}
+		newURL, err := StoreImage(ctx, path.Base(lib.Images[imageURL]), bytes.NewReader(image), int64(len(image)))
+		if err != nil {
+			return "", err
+		}
+		uploaded[imageURL] = newURL
+		return newURL, nil
+	}
+	undo = func() {
+		for _, newURL := range uploaded {
+			if err := DeleteImage(ctx, newURL); err != nil {
+				fmt.Println("Error deleting image:", newURL, err)
+			}
+		}
+	}
+	return upload, undo
+}
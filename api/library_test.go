@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportLibraryHandler(t *testing.T) {
+	const householdID = 42
+
+	t.Run("json", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		mock.ExpectQuery(`SELECT id FROM recipes WHERE deleted_at IS NULL ORDER BY id ASC`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT id FROM meals WHERE deleted_at IS NULL ORDER BY id ASC`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT name FROM tags`).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("quick"))
+		mock.ExpectQuery(`SELECT id FROM plans WHERE household_id=\$1`).WithArgs(householdID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT \* FROM pantry WHERE household_id = \$1`).WithArgs(householdID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "household_id"}).AddRow(1, householdID))
+		mock.ExpectQuery(`SELECT item_name FROM pantry_items WHERE pantry_id = \$1`).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"item_name"}).AddRow("salt"))
+
+		req := httptest.NewRequest("GET", "/api/export", nil)
+		ctx := context.WithValue(req.Context(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rec := httptest.NewRecorder()
+		ExportLibraryHandler(rec, req.WithContext(ctx))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), "mealplan-library-")
+
+		var lib models.Library
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &lib))
+		assert.Equal(t, models.LibraryVersion, lib.Version)
+		assert.Equal(t, []string{"quick"}, lib.Tags)
+		assert.Equal(t, []string{"salt"}, lib.Pantry)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		sqlxDB, _ := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		req := httptest.NewRequest("GET", "/api/export?format=xml", nil)
+		ctx := context.WithValue(req.Context(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rec := httptest.NewRecorder()
+		ExportLibraryHandler(rec, req.WithContext(ctx))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestImportLibraryHandler(t *testing.T) {
+	const householdID = 42
+
+	post := func(url string, body []byte, ctx context.Context) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+		ImportLibraryHandler(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("zip archive re-uploads images", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		lib := &models.Library{
+			Version: models.LibraryVersion,
+			Recipes: []models.Recipe{{ID: 1, Name: "Toast", Description: "Hot", Slug: "toast", Image: models.NullStringWrapper("https://old.example.com/toast.jpg")}},
+		}
+		var archive bytes.Buffer
+		require.NoError(t, models.WriteLibraryArchive(context.Background(), &archive, lib, func(ctx context.Context, u string) ([]byte, error) {
+			return []byte("jpeg"), nil
+		}))
+
+		origStore := StoreImage
+		var uploaded []byte
+		StoreImage = func(ctx context.Context, filename string, file io.Reader, size int64) (string, error) {
+			uploaded, _ = io.ReadAll(file)
+			return "http://localhost:9000/mp-images/" + filename, nil
+		}
+		defer func() { StoreImage = origStore }()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM recipes WHERE slug=\$1`).WithArgs("toast").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		// The overwritten version is kept as a revision
+		mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).AddRow(9, "Toast", "Old description", "old", nil))
+		mock.ExpectQuery("SELECT \\* FROM recipe_ingredients WHERE recipe_id=\\$1").WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "name", "amount", "calories"}))
+		mock.ExpectQuery("SELECT \\* FROM recipe_steps WHERE recipe_id=\\$1 ORDER BY").WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+		mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectExec("INSERT INTO revisions \\(recipe_id, revision, snapshot\\)").
+			WithArgs(9, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE recipes SET name=\$1, description=\$2, image=\$3, servings=\$4, prep_minutes=\$5, cook_minutes=\$6, equipment=\$7 WHERE id=\$8`).
+			WithArgs("Toast", "Hot", models.NullStringWrapper("http://localhost:9000/mp-images/1-toast.jpg"), nil, nil, nil, sqlmock.AnyArg(), 9).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM recipe_ingredients`).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM recipe_steps`).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		ctx := context.WithValue(context.Background(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rec := post("/api/import?strategy=overwrite", archive.Bytes(), ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []byte("jpeg"), uploaded)
+		var result models.ImportResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Overwritten["recipes"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed import deletes uploaded images", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		lib := &models.Library{
+			Version: models.LibraryVersion,
+			Recipes: []models.Recipe{{ID: 1, Name: "Toast", Description: "Hot", Slug: "toast", Image: models.NullStringWrapper("https://old.example.com/toast.jpg")}},
+		}
+		var archive bytes.Buffer
+		require.NoError(t, models.WriteLibraryArchive(context.Background(), &archive, lib, func(ctx context.Context, u string) ([]byte, error) {
+			return []byte("jpeg"), nil
+		}))
+
+		origStore, origDelete := StoreImage, DeleteImage
+		StoreImage = func(ctx context.Context, filename string, file io.Reader, size int64) (string, error) {
+			return "http://localhost:9000/mp-images/" + filename, nil
+		}
+		var deleted []string
+		DeleteImage = func(ctx context.Context, storageURL string) error {
+			deleted = append(deleted, storageURL)
+			return nil
+		}
+		defer func() { StoreImage, DeleteImage = origStore, origDelete }()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM recipes WHERE slug=\$1`).WithArgs("toast").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT id FROM recipes WHERE slug=\$1`).WithArgs("toast").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(`INSERT INTO recipes`).WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		ctx := context.WithValue(context.Background(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rec := post("/api/import", archive.Bytes(), ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, []string{"http://localhost:9000/mp-images/1-toast.jpg"}, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		sqlxDB, _ := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		ctx := context.WithValue(context.Background(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rec := post("/api/import?strategy=merge", []byte(`{"version": 1}`), ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		sqlxDB, _ := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		ctx := context.WithValue(context.Background(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rec := post("/api/import", []byte(`{"version": 7}`), ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
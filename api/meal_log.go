@@ -0,0 +1,121 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// GET /api/meal-log?meal_id=&plan_id=
+func GetMealLogHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	mealID, _ := strconv.Atoi(r.URL.Query().Get("meal_id"))
+	planID, _ := strconv.Atoi(r.URL.Query().Get("plan_id"))
+
+	entries, err := models.GetMealLog(db, householdID, mealID, planID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+// POST /api/meal-log
+func CreateMealLogHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+	user := r.Context().Value("user").(*clerk.User)
+
+	data := new(models.MealLogEntry)
+	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := models.CreateMealLogEntry(db, householdID, user.ID, data)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidMealLog) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DELETE /api/meal-log/{entryID}
+//
+// Members can only delete their own entries.
+func DeleteMealLogHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+	user := r.Context().Value("user").(*clerk.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "entryID"))
+	if err != nil {
+		ErrorResponse(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.DeleteMealLogEntry(db, householdID, user.ID, id); err != nil {
+		if err == sql.ErrNoRows {
+			ErrorResponse(w, "Entry not found", http.StatusNotFound)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/meal-log/stats
+func GetMealStatsHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	stats, err := models.GetMealStats(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	list := []models.MealStats{}
+	for _, s := range stats {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].MealID < list[j].MealID })
+	json.NewEncoder(w).Encode(list)
+}
+
+// withMealStats adds the household's cooking history to meals. Like
+// nutrition, a failure is logged rather than failing the request.
+func withMealStats(db *sqlx.DB, householdID int, meals []models.Meal) {
+	if householdID == 0 || len(meals) == 0 {
+		return
+	}
+	stats, err := models.GetMealStats(db, householdID)
+	if err != nil {
+		fmt.Println("Error loading meal stats:", err)
+		return
+	}
+	models.ApplyMealStats(meals, stats)
+}
+
+func withOneMealStats(db *sqlx.DB, householdID int, meal *models.Meal) *models.Meal {
+	meals := []models.Meal{*meal}
+	withMealStats(db, householdID, meals)
+	return &meals[0]
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMealLogHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	request := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/meal-log", bytes.NewBufferString(body))
+		ctx := context.WithValue(req.Context(), "db", db)
+		ctx = context.WithValue(ctx, "household", 42)
+		ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+		rec := httptest.NewRecorder()
+		CreateMealLogHandler(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("invalid rating", func(t *testing.T) {
+		rec := request(`{"meal_id": 7, "status": "cooked", "rating": 9}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("logs a cooked meal", func(t *testing.T) {
+		cookedOn := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("INSERT INTO meal_log").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "user_id", "meal_id", "plan_id", "status", "replacement_meal_id", "rating", "notes", "cooked_on", "created_at"}).
+				AddRow(5, 42, "user1", 7, nil, "cooked", nil, 5, "", cookedOn, time.Now()))
+
+		rec := request(`{"meal_id": 7, "status": "cooked", "rating": 5, "cooked_on": "2026-10-17"}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var entry models.MealLogEntry
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&entry))
+		assert.Equal(t, 5, entry.ID)
+		assert.Equal(t, 5, *entry.Rating)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteMealLogHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM meal_log").WithArgs(3, 42, "user1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req := httptest.NewRequest("DELETE", "/api/meal-log/3", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("entryID", "3")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "db", db)
+	ctx = context.WithValue(ctx, "household", 42)
+	ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+	rec := httptest.NewRecorder()
+	DeleteMealLogHandler(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/jmoiron/sqlx"
@@ -22,13 +23,68 @@ func GetMealsHandler(w http.ResponseWriter, r *http.Request) {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		json.NewEncoder(w).Encode(meal)
+		householdID := optionalHousehold(r, db)
+		meal = withOneMealStats(db, householdID, meal)
+		json.NewEncoder(w).Encode(withMealCost(db, householdID, withOneMealTimes(db, withMealWarnings(db, householdID, withMealNutrition(db, meal)))))
 	} else {
+		collection, ok := requestCollection(w, r, db)
+		if !ok {
+			return
+		}
+		maxTime, ok := requestMaxTime(w, r)
+		if !ok {
+			return
+		}
+		owned, ok := requestOwnedEquipment(w, r, db)
+		if !ok {
+			return
+		}
 		meals, err := models.GetMeals(db)
 		if err != nil {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if collection != nil {
+			filtered := []models.Meal{}
+			for _, meal := range *meals {
+				if collection.Contains(models.CollectionItem{MealID: &meal.ID}) {
+					filtered = append(filtered, meal)
+				}
+			}
+			meals = &filtered
+		}
+		if owned != nil {
+			// A meal also needs whatever its recipes need
+			needs, err := models.GetMealEquipment(db)
+			if err != nil {
+				ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			filtered := []models.Meal{}
+			for _, meal := range *meals {
+				if len(models.MissingEquipment(needs[meal.ID], owned)) == 0 {
+					filtered = append(filtered, meal)
+				}
+			}
+			meals = &filtered
+		}
+		timed, err := withMealTimes(db, *meals, maxTime)
+		if err != nil {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		meals = &timed
+
+		// Cooking history is per household. Anonymous requests get none, so
+		// history sort keys treat every meal as never cooked.
+		sortKey := r.URL.Query().Get("sort")
+		withMealStats(db, optionalHousehold(r, db), *meals)
+		if sortKey != "" {
+			if err := models.SortMeals(*meals, sortKey); err != nil {
+				ErrorResponse(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 		json.NewEncoder(w).Encode(meals)
 	}
 }
@@ -42,7 +98,12 @@ func GetMealHandler(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(meal)
+	if inTrash(w, meal.DeletedAt) {
+		return
+	}
+	householdID := optionalHousehold(r, db)
+	meal = withOneMealStats(db, householdID, meal)
+	json.NewEncoder(w).Encode(withMealCost(db, householdID, withOneMealTimes(db, withMealWarnings(db, householdID, withMealNutrition(db, meal)))))
 }
 
 func UpdateMealHandler(w http.ResponseWriter, r *http.Request) {
@@ -63,7 +124,7 @@ func UpdateMealHandler(w http.ResponseWriter, r *http.Request) {
 
 	meal, err := models.UpdateMeal(db, id, data)
 	if err != nil {
-		if err == models.ErrValidation {
+		if err == models.ErrValidation || errors.Is(err, models.ErrInvalidTime) {
 			ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		} else {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
@@ -89,7 +150,7 @@ func CreateMealHandler(w http.ResponseWriter, r *http.Request) {
 
 	meal, err := models.CreateMeal(db, data)
 	if err != nil {
-		if err == models.ErrValidation {
+		if err == models.ErrValidation || errors.Is(err, models.ErrInvalidTime) {
 			ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		} else {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
@@ -103,15 +164,21 @@ func DeleteMealHandler(w http.ResponseWriter, r *http.Request) {
 	db := r.Context().Value("db").(*sqlx.DB)
 	id := r.Context().Value("id").(int)
 
-	_, err := RequiresAuthentication(r)
+	user, err := RequiresAuthentication(r)
 	if err != nil {
 		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
 		return
 	}
 
+	if !confirmDeletion(w, r, user, func(householdID int) (*models.DeletionImpact, error) {
+		return models.MealDeletionImpact(db, id, householdID)
+	}) {
+		return
+	}
+
 	err = models.DeleteMeal(db, id)
 	if err != nil {
-		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		trashError(w, err)
 		return
 	}
 
@@ -224,26 +224,20 @@ func TestCreateMealHandler(t *testing.T) {
 		},
 	}
 
-	// Mock for slug check and insert
+	// Mock transaction for CreateMeal: slug check and insert
+	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT id FROM meals WHERE slug=\\$1").
 		WithArgs("new-test-meal").
 		WillReturnError(fmt.Errorf("not found"))
-
-	// Mock transaction for CreateMeal
-	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO meals").
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
-
-	// Mock GetMealIdFromSlug
 	mock.ExpectQuery("SELECT id FROM meals WHERE slug=\\$1").
 		WithArgs("new-test-meal").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	// Mock for UpdateMeal
-	mock.ExpectBegin()
+	// The rest of the meal is written in the same transaction
 	mock.ExpectExec("UPDATE meals SET").
-		WithArgs(newMeal.Name, newMeal.Description, newMeal.Image, 1).
+		WithArgs(newMeal.Name, newMeal.Description, newMeal.Image, nil, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for ingredients
@@ -251,7 +245,7 @@ func TestCreateMealHandler(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("INSERT INTO meal_ingredients").
-		WithArgs(1, "Ingredient 1", "1 cup").
+		WithArgs(1, "Ingredient 1", "1 cup", "", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for steps
@@ -259,7 +253,7 @@ func TestCreateMealHandler(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("INSERT INTO meal_steps").
-		WithArgs(1, "Step 1", 1).
+		WithArgs(1, "Step 1", 1, "", nil, nil, "").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for recipes
@@ -370,10 +364,25 @@ func TestUpdateMealHandler(t *testing.T) {
 		},
 	}
 
+	// The current version is kept as a revision
+	mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).AddRow(1, "Old Meal", "Old description", "old-meal", nil))
+	mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "meal_id", "name", "amount"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1 ORDER BY").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "meal_id", "order", "text"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
 	// Mock for UpdateMeal
 	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO revisions \\(meal_id, revision, snapshot\\)").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("UPDATE meals SET").
-		WithArgs(updateMeal.Name, updateMeal.Description, updateMeal.Image, 1).
+		WithArgs(updateMeal.Name, updateMeal.Description, updateMeal.Image, nil, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for ingredients
@@ -381,7 +390,7 @@ func TestUpdateMealHandler(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("INSERT INTO meal_ingredients").
-		WithArgs(1, "Updated Ingredient", "2 cups").
+		WithArgs(1, "Updated Ingredient", "2 cups", "", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for steps
@@ -389,7 +398,7 @@ func TestUpdateMealHandler(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("INSERT INTO meal_steps").
-		WithArgs(1, "Updated Step", 1).
+		WithArgs(1, "Updated Step", 1, "", nil, nil, "").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for recipes
@@ -483,27 +492,13 @@ func TestDeleteMealHandler(t *testing.T) {
 	RequiresAuthentication = mockAuthFunc
 	defer func() { RequiresAuthentication = originalFunc }()
 
-	// Mock for DeleteMeal
-	mock.ExpectBegin()
-	mock.ExpectExec("DELETE FROM meal_ingredients").
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 2))
-	mock.ExpectExec("DELETE FROM meal_steps").
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectExec("DELETE FROM meal_recipes").
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectExec("DELETE FROM plan_meals").
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec("DELETE FROM meals").
+	// Confirmed, so the meal goes to the trash without checking its plans
+	mock.ExpectExec("UPDATE meals SET deleted_at=NOW\\(\\)").
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectCommit()
 
 	// Create request
-	req := httptest.NewRequest("DELETE", "/api/meals/1", nil)
+	req := httptest.NewRequest("DELETE", "/api/meals/1?confirm=true", nil)
 	rec := httptest.NewRecorder()
 
 	// Set up context with mocked DB and ID
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// withRecipeNutrition attaches nutrition totals to a recipe. Nutrition is
+// supplementary, so a failed lookup is logged and the recipe returned as is.
+func withRecipeNutrition(db *sqlx.DB, recipe *models.Recipe) *models.Recipe {
+	summary, err := models.RecipeNutrition(db, recipe)
+	if err != nil {
+		fmt.Println("Error computing recipe nutrition:", err)
+		return recipe
+	}
+	recipe.Nutrition = summary
+	return recipe
+}
+
+// withMealNutrition is withRecipeNutrition for meals
+func withMealNutrition(db *sqlx.DB, meal *models.Meal) *models.Meal {
+	summary, err := models.MealNutrition(db, meal)
+	if err != nil {
+		fmt.Println("Error computing meal nutrition:", err)
+		return meal
+	}
+	meal.Nutrition = summary
+	return meal
+}
+
+// GET /api/plans/{id}/nutrition
+func GetPlanNutrition(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+	household := r.Context().Value("household").(int)
+
+	plan, err := models.GetPlan(db, id)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if plan.HouseholdID != household {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	if inTrash(w, plan.DeletedAt) {
+		return
+	}
+
+	report, err := models.PlanNutrition(db, plan)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPlanNutrition(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	nutritionColumns := []string{"name", "amount", "unit", "calories", "protein", "fat", "carbs", "fiber", "sodium", "source"}
+
+	expectPlan := func(householdID int) {
+		mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+				AddRow(1, start, start.AddDate(0, 0, 1), householdID))
+		mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}).AddRow(1, 1, 7))
+	}
+
+	request := func(householdID int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/plans/1/nutrition", nil)
+		ctx := context.WithValue(req.Context(), "db", db)
+		ctx = context.WithValue(ctx, "id", 1)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rec := httptest.NewRecorder()
+		GetPlanNutrition(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("report", func(t *testing.T) {
+		expectPlan(42)
+
+		// GetMeal
+		mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image", "servings"}).
+				AddRow(7, "Eggs on Toast", "Breakfast", "eggs-on-toast", nil, 2))
+		mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "name", "meal_id"}).
+				AddRow(1, "4", "eggs", 7).
+				AddRow(2, "a splash", "hot sauce", 7))
+		mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "text", "order", "meal_id"}))
+		mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+		mock.ExpectQuery("SELECT t.name FROM tags t").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		// MealNutrition
+		mock.ExpectQuery("SELECT \\* FROM ingredient_nutrition").
+			WillReturnRows(sqlmock.NewRows(nutritionColumns).AddRow("egg", 1, "", 72, 6, 5, 0, 0, 70, ""))
+
+		rec := request(42)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var report models.PlanNutritionReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, 2, report.Days)
+		assert.Equal(t, 288.0, report.Total.Calories)
+		assert.Equal(t, 144.0, report.PerDay.Calories)
+		// Two servings, so each person gets 144 over two days
+		assert.Equal(t, 72.0, report.PerPersonPerDay.Calories)
+		require.Len(t, report.Meals, 1)
+		assert.Equal(t, "Eggs on Toast", report.Meals[0].Name)
+		assert.Equal(t, []string{"hot sauce"}, report.Unmatched)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("other household", func(t *testing.T) {
+		expectPlan(7)
+		rec := request(42)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
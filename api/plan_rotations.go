@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// getHouseholdRotation loads the rotation named in the URL, writing an error
+// response and returning nil if it isn't the household's.
+func getHouseholdRotation(w http.ResponseWriter, r *http.Request) *models.PlanRotation {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "rotationID"))
+	if err != nil {
+		ErrorResponse(w, "Invalid rotation ID", http.StatusBadRequest)
+		return nil
+	}
+	rotation, err := models.GetPlanRotation(db, id)
+	if err != nil || rotation.HouseholdID != householdID {
+		ErrorResponse(w, "Rotation not found", http.StatusNotFound)
+		return nil
+	}
+	return rotation
+}
+
+// GET /api/plans/rotations
+func GetPlanRotations(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	rotations, err := models.GetPlanRotations(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(rotations)
+}
+
+// POST /api/plans/rotations
+func CreatePlanRotation(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	data := new(models.PlanRotation)
+	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rotation, err := models.CreatePlanRotation(db, householdID, data)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidRotation) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rotation)
+}
+
+// DELETE /api/plans/rotations/{rotationID}
+func DeletePlanRotation(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	rotation := getHouseholdRotation(w, r)
+	if rotation == nil {
+		return
+	}
+	if err := models.DeletePlanRotation(db, rotation.ID); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/plans/rotations/{rotationID}/roll
+//
+// Creates the rotation's next plan now rather than waiting for it to be due.
+func RollPlanRotation(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	rotation := getHouseholdRotation(w, r)
+	if rotation == nil {
+		return
+	}
+	plan, err := models.RollRotation(db, rotation, time.Now())
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rotationColumns = []string{"id", "household_id", "name", "next_start", "position", "active"}
+
+func rotationRequest(db *sqlx.DB, method, path, rotationID string, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("rotationID", rotationID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "db", db)
+	ctx = context.WithValue(ctx, "household", 42)
+	return req.WithContext(ctx)
+}
+
+func TestGetPlanRotations(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	next := time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM plan_rotations WHERE household_id=\\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(rotationColumns).AddRow(3, 42, "Fortnight", next, 1, true))
+	mock.ExpectQuery("SELECT plan_id FROM plan_rotation_entries").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"plan_id"}).AddRow(1).AddRow(2))
+
+	rec := httptest.NewRecorder()
+	GetPlanRotations(rec, rotationRequest(db, "GET", "/api/plans/rotations", "", ""))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var rotations []models.PlanRotation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rotations))
+	require.Len(t, rotations, 1)
+	assert.Equal(t, "Fortnight", rotations[0].Name)
+	assert.Equal(t, []int{1, 2}, rotations[0].Plans)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePlanRotation(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	t.Run("created", func(t *testing.T) {
+		next := time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT COUNT\\(DISTINCT id\\) FROM plans").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO plan_rotations").WithArgs(42, "Fortnight", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectExec("INSERT INTO plan_rotation_entries").WithArgs(3, 0, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO plan_rotation_entries").WithArgs(3, 1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT \\* FROM plan_rotations WHERE id=\\$1").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(rotationColumns).AddRow(3, 42, "Fortnight", next, 0, true))
+		mock.ExpectQuery("SELECT plan_id FROM plan_rotation_entries").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"plan_id"}).AddRow(1).AddRow(2))
+
+		rec := httptest.NewRecorder()
+		CreatePlanRotation(rec, rotationRequest(db, "POST", "/api/plans/rotations", "",
+			`{"name": "Fortnight", "next_start": "2026-10-26", "plans": [1, 2]}`))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var rotation models.PlanRotation
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rotation))
+		assert.Equal(t, 3, rotation.ID)
+		assert.Equal(t, []int{1, 2}, rotation.Plans)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		CreatePlanRotation(rec, rotationRequest(db, "POST", "/api/plans/rotations", "", `{"name": "Fortnight"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		// Another household's plan
+		mock.ExpectQuery("SELECT COUNT\\(DISTINCT id\\) FROM plans").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		rec = httptest.NewRecorder()
+		CreatePlanRotation(rec, rotationRequest(db, "POST", "/api/plans/rotations", "",
+			`{"name": "Fortnight", "next_start": "2026-10-26", "plans": [1, 99]}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeletePlanRotation(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	next := time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)
+
+	t.Run("deleted", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM plan_rotations WHERE id=\\$1").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(rotationColumns).AddRow(3, 42, "Fortnight", next, 0, true))
+		mock.ExpectQuery("SELECT plan_id FROM plan_rotation_entries").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"plan_id"}).AddRow(1))
+		mock.ExpectExec("DELETE FROM plan_rotations WHERE id=\\$1").WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rec := httptest.NewRecorder()
+		DeletePlanRotation(rec, rotationRequest(db, "DELETE", "/api/plans/rotations/3", "3", ""))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("other household", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM plan_rotations WHERE id=\\$1").WithArgs(4).
+			WillReturnRows(sqlmock.NewRows(rotationColumns).AddRow(4, 7, "Theirs", next, 0, true))
+		mock.ExpectQuery("SELECT plan_id FROM plan_rotation_entries").WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"plan_id"}).AddRow(9))
+
+		rec := httptest.NewRecorder()
+		DeletePlanRotation(rec, rotationRequest(db, "DELETE", "/api/plans/rotations/4", "4", ""))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		DeletePlanRotation(rec, rotationRequest(db, "DELETE", "/api/plans/rotations/abc", "abc", ""))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestRollPlanRotation(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 3)
+	sourceStart := next.AddDate(0, 0, -14)
+
+	mock.ExpectQuery("SELECT \\* FROM plan_rotations WHERE id=\\$1").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(rotationColumns).AddRow(3, 42, "Fortnight", next, 0, true))
+	mock.ExpectQuery("SELECT plan_id FROM plan_rotation_entries").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"plan_id"}).AddRow(1).AddRow(2))
+
+	// RollRotation copies plan 1 to the rotation's next start
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(1, sourceStart, sourceStart.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}).AddRow(1, 1, 7))
+	mock.ExpectExec("UPDATE plan_rotations SET position").
+		WithArgs(1, next.AddDate(0, 0, 7), 3, 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3").
+		WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}))
+	mock.ExpectExec("INSERT INTO plans").
+		WithArgs(models.Date{Time: next}, models.Date{Time: next.AddDate(0, 0, 6)}, 42).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM plans WHERE start_date").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM plan_meals WHERE plan_id").WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO plan_meals \\(plan_id, meal_id\\)").WithArgs(5, 7).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(5, next, next.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}).AddRow(2, 5, 7))
+
+	rec := httptest.NewRecorder()
+	RollPlanRotation(rec, rotationRequest(db, "POST", "/api/plans/rotations/3/roll", "3", ""))
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var plan models.Plan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &plan))
+	assert.Equal(t, 5, plan.ID)
+	assert.Equal(t, []int{7}, plan.Meals)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRollPlanRotationNotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM plan_rotations WHERE id=\\$1").WithArgs(8).
+		WillReturnRows(sqlmock.NewRows(rotationColumns))
+
+	rec := httptest.NewRecorder()
+	RollPlanRotation(rec, rotationRequest(db, "POST", "/api/plans/rotations/8/roll", "8", ""))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,7 +2,9 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/jmoiron/sqlx"
@@ -45,7 +47,7 @@ func GetPlans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	plans, err := models.GetPlans(db, householdID)
+	plans, err := models.GetPlans(db, householdID, r.URL.Query().Get("archived") == "true")
 	if err != nil {
 		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -67,7 +69,10 @@ func GetPlan(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
 		return
 	}
-	json.NewEncoder(w).Encode(plan)
+	if inTrash(w, plan.DeletedAt) {
+		return
+	}
+	json.NewEncoder(w).Encode(withPlanCost(db, withPlanWarnings(db, household, plan)))
 }
 
 func UpdatePlan(w http.ResponseWriter, r *http.Request) {
@@ -92,8 +97,33 @@ func UpdatePlan(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
 		return
 	}
+	if inTrash(w, plan.DeletedAt) {
+		return
+	}
+	if err := models.CanEditPlan(plan, planOptions(r)); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusConflict)
+		return
+	}
 	data.HouseholdID = householdID
 
+	// With ?on_violation=reject, meals that conflict with a member's dietary
+	// profile are refused instead of being saved with warnings
+	if r.URL.Query().Get("on_violation") == "reject" {
+		warnings, err := models.PlanWarnings(db, householdID, data.MealIDs())
+		if err != nil {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(warnings) > 0 {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":    "plan contains meals that conflict with dietary profiles",
+				"warnings": warnings,
+			})
+			return
+		}
+	}
+
 	plan, err = models.UpdatePlan(db, id, data)
 	if err != nil {
 		if err == models.ErrValidation {
@@ -103,7 +133,7 @@ func UpdatePlan(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	json.NewEncoder(w).Encode(plan)
+	json.NewEncoder(w).Encode(withPlanWarnings(db, householdID, plan))
 }
 
 func CreatePlan(w http.ResponseWriter, r *http.Request) {
@@ -121,18 +151,146 @@ func CreatePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	plan, err := models.CreatePlan(db, data, householdID)
+	// With ?on_overlap=merge, a plan overlapping existing ones is merged into
+	// them rather than rejected
+	var plan *models.Plan
+	var err error
+	if r.URL.Query().Get("on_overlap") == "merge" {
+		plan, err = models.MergePlan(db, data, householdID, planOptions(r))
+	} else {
+		plan, err = models.CreatePlanWithOptions(db, data, householdID, planOptions(r))
+	}
 	if err != nil {
-		if err == models.ErrValidation {
+		if overlapResponse(w, err) {
+			return
+		}
+		if err == models.ErrValidation || errors.Is(err, models.ErrInvalidPlanDates) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(plan)
+}
+
+// GET /api/plans/timeline?from=&to=
+//
+// Both dates are optional; the default is the two weeks starting today.
+func GetPlanTimeline(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			ErrorResponse(w, "from must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+	to := from.AddDate(0, 0, 13)
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			ErrorResponse(w, "to must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+
+	timeline, err := models.GetPlanTimeline(db, householdID, from, to)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidTimeline) {
 			ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		} else {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
+	json.NewEncoder(w).Encode(timeline)
+}
+
+// POST /api/plans/{id}/copy
+func CopyPlan(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+	householdID := r.Context().Value("household").(int)
+	user, ok := r.Context().Value("user").(*clerk.User)
+	if !ok || user == nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+
+	data := struct {
+		StartDate models.Date `json:"start_date"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if data.StartDate.IsZero() {
+		ErrorResponse(w, "start_date is required", http.StatusBadRequest)
+		return
+	}
+
+	source, err := models.GetPlan(db, id)
+	if err != nil || source.HouseholdID != householdID {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	if inTrash(w, source.DeletedAt) {
+		return
+	}
+
+	plan, err := models.CopyPlan(db, source, data.StartDate)
+	if err != nil {
+		if overlapResponse(w, err) {
+			return
+		}
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(plan)
 }
 
+// POST /api/plans/generate
+func GeneratePlan(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	user, ok := r.Context().Value("user").(*clerk.User)
+	householdID := r.Context().Value("household").(int)
+	if !ok || user == nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+
+	req := new(models.PlanGenerationRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	generated, err := models.GeneratePlan(db, householdID, req)
+	if err != nil {
+		if overlapResponse(w, err) {
+			return
+		}
+		if errors.Is(err, models.ErrInvalidGeneration) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if generated.Plan.ID != 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(generated)
+}
+
 func DeletePlan(w http.ResponseWriter, r *http.Request) {
 	db := r.Context().Value("db").(*sqlx.DB)
 	id := r.Context().Value("id").(int)
@@ -148,10 +306,13 @@ func DeletePlan(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
 		return
 	}
+	if inTrash(w, plan.DeletedAt) {
+		return
+	}
 
 	err = models.DeletePlan(db, id)
 	if err != nil {
-		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		trashError(w, err)
 		return
 	}
 
@@ -170,3 +331,57 @@ func GetPlanIngredients(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewEncoder(w).Encode(*ingredients)
 }
+
+// overlapResponse reports a plan overlap as 409 Conflict with the plans in
+// the way. It returns false, writing nothing, for any other error.
+func overlapResponse(w http.ResponseWriter, err error) bool {
+	var overlap *models.PlanOverlapError
+	if !errors.As(err, &overlap) {
+		return false
+	}
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+		"plans": overlap.Plans,
+	})
+	return true
+}
+
+// planOptions reads the ?past=true flag that allows creating plans in the
+// past and correcting plans that have ended
+func planOptions(r *http.Request) models.PlanOptions {
+	return models.PlanOptions{AllowPast: r.URL.Query().Get("past") == "true"}
+}
+
+// POST /api/plans/{id}/archive archives a plan that has ended, hiding it from
+// the default plan list; DELETE restores it.
+func ArchivePlan(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+	householdID := r.Context().Value("household").(int)
+	user, ok := r.Context().Value("user").(*clerk.User)
+	if !ok || user == nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+
+	plan, err := models.GetPlan(db, id)
+	if err != nil || plan.HouseholdID != householdID {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	if inTrash(w, plan.DeletedAt) {
+		return
+	}
+
+	plan, err = models.SetPlanArchived(db, plan, r.Method != http.MethodDelete)
+	if err != nil {
+		if err == models.ErrPlanNotEnded {
+			ErrorResponse(w, err.Error(), http.StatusConflict)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(plan)
+}
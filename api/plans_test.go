@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -267,6 +268,9 @@ func TestCreatePlan(t *testing.T) {
 
 	// Mock for CreatePlan
 	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3").
+		WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}))
 	mock.ExpectExec("INSERT INTO plans").
 		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 42).
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -461,15 +465,10 @@ func TestDeletePlan(t *testing.T) {
 		WithArgs(1).
 		WillReturnRows(mealRows)
 
-	// Mock for DeletePlan
-	mock.ExpectBegin()
-	mock.ExpectExec("DELETE FROM plan_meals").
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 2))
-	mock.ExpectExec("DELETE FROM plans").
+	// Mock for DeletePlan, which moves the plan to the trash
+	mock.ExpectExec("UPDATE plans SET deleted_at=NOW\\(\\)").
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectCommit()
 
 	// Create request
 	req := httptest.NewRequest("DELETE", "/api/plans/1", nil)
@@ -498,7 +497,7 @@ func TestGetPlanIngredients(t *testing.T) {
 		AddRow("Ingredient 1", "1 cup").
 		AddRow("Ingredient 2", "2 tbsp")
 
-	mock.ExpectQuery("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE").
+	mock.ExpectQuery("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p").
 		WithArgs(1, 42).
 		WillReturnRows(rows)
 
@@ -530,3 +529,192 @@ func TestGetPlanIngredients(t *testing.T) {
 	assert.Equal(t, "Ingredient 2", ingredients[1].Name)
 	assert.Equal(t, "2 tbsp", ingredients[1].Amount)
 }
+
+func TestGeneratePlan(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	request := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/plans/generate", bytes.NewBufferString(body))
+		ctx := context.WithValue(req.Context(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", 42)
+		ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+		rec := httptest.NewRecorder()
+		GeneratePlan(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("draft", func(t *testing.T) {
+		start := time.Now().AddDate(0, 0, 1)
+		mock.ExpectQuery("SELECT id, name FROM meals WHERE deleted_at IS NULL ORDER BY id").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(7, "Tacos"))
+		mock.ExpectQuery("SELECT mt.meal_id, t.name FROM meal_tags").
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}))
+		mock.ExpectQuery("SELECT meal_id, name FROM meal_ingredients").
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}))
+		mock.ExpectQuery("SELECT p.\\* FROM dietary_profiles").WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "allergens", "excluded_ingredients", "diets"}))
+		mock.ExpectQuery("SELECT equipment FROM households WHERE id=\\$1").WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"equipment"}).AddRow("{}"))
+
+		body := fmt.Sprintf(`{"start_date":%q,"end_date":%q,"seed":5}`,
+			start.Format("2006-01-02"), start.AddDate(0, 0, 1).Format("2006-01-02"))
+		rec := request(body)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var generated models.GeneratedPlan
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &generated))
+		assert.Equal(t, []int{7, 7}, generated.Plan.Meals)
+		assert.Equal(t, int64(5), generated.Seed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid dates", func(t *testing.T) {
+		rec := request(`{"start_date":"2020-01-01","end_date":"2020-01-07"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestCopyPlan(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	request := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/plans/1/copy", bytes.NewBufferString(body))
+		ctx := context.WithValue(req.Context(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "id", 1)
+		ctx = context.WithValue(ctx, "household", 42)
+		ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+		rec := httptest.NewRecorder()
+		CopyPlan(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("missing start date", func(t *testing.T) {
+		rec := request(`{}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("other household", func(t *testing.T) {
+		start := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT \\* FROM plans WHERE id").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+				AddRow(1, start, start.AddDate(0, 0, 6), 7))
+		mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+
+		rec := request(`{"start_date":"2030-01-07"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCreatePlanOverlap(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	start := time.Now().AddDate(0, 0, 3)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(9, start, start.AddDate(0, 0, 6), 42))
+	mock.ExpectRollback()
+
+	body := fmt.Sprintf(`{"start_date":%q,"end_date":%q}`, start.Format("2006-01-02"), start.AddDate(0, 0, 6).Format("2006-01-02"))
+	req := httptest.NewRequest("POST", "/api/plans", bytes.NewBufferString(body))
+	ctx := context.WithValue(req.Context(), "db", sqlxDB)
+	ctx = context.WithValue(ctx, "household", 42)
+	ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+	rec := httptest.NewRecorder()
+	CreatePlan(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	var resp struct {
+		Plans []models.Plan `json:"plans"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Plans, 1)
+	assert.Equal(t, 9, resp.Plans[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPlanTimeline(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	request := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/plans/timeline?"+query, nil)
+		ctx := context.WithValue(req.Context(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", 42)
+		rec := httptest.NewRecorder()
+		GetPlanTimeline(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id FROM plans WHERE household_id").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		rec := request("from=2026-11-01&to=2026-11-03")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var timeline models.Timeline
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &timeline))
+		assert.Len(t, timeline.Days, 3)
+		require.Len(t, timeline.Gaps, 1)
+		assert.Equal(t, 3, timeline.Gaps[0].Days)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("bad dates", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request("from=yesterday").Code)
+		assert.Equal(t, http.StatusBadRequest, request("from=2026-11-03&to=2026-11-01").Code)
+	})
+}
+
+func TestUpdateEndedPlan(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	start := time.Now().AddDate(0, 0, -10)
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(1, start, start.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+
+	req := httptest.NewRequest("PUT", "/api/plans/1", bytes.NewBufferString(`{"meals":[3]}`))
+	ctx := context.WithValue(req.Context(), "db", sqlxDB)
+	ctx = context.WithValue(ctx, "id", 1)
+	ctx = context.WithValue(ctx, "household", 42)
+	ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+	rec := httptest.NewRecorder()
+	UpdatePlan(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "past=true")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestArchivePlan(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	start := time.Now().AddDate(0, 0, 1)
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(1, start, start.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+
+	req := httptest.NewRequest("POST", "/api/plans/1/archive", nil)
+	ctx := context.WithValue(req.Context(), "db", sqlxDB)
+	ctx = context.WithValue(ctx, "id", 1)
+	ctx = context.WithValue(ctx, "household", 42)
+	ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+	rec := httptest.NewRecorder()
+	ArchivePlan(rec, req.WithContext(ctx))
+
+	// The plan hasn't ended yet
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+type positionRequest struct {
+	Position int `json:"position"`
+}
+
+// readPosition parses the ingredient ID and the new position of a reorder
+// request. It returns false after writing an error response.
+func readPosition(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	ingredientID, err := strconv.Atoi(chi.URLParam(r, "ingredientID"))
+	if err != nil {
+		ErrorResponse(w, "Invalid ingredient ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	data := positionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return 0, 0, false
+	}
+	if data.Position < 1 {
+		ErrorResponse(w, "Position must be 1 or more", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return ingredientID, data.Position, true
+}
+
+// PUT /api/recipes/{id}/ingredients/{ingredientID}/position
+func MoveRecipeIngredient(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	ingredientID, position, ok := readPosition(w, r)
+	if !ok {
+		return
+	}
+	if !canEditRecipe(w, db, id, user) {
+		return
+	}
+
+	recipe, err := models.MoveRecipeIngredient(db, id, ingredientID, position)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ErrorResponse(w, "Ingredient not found", http.StatusNotFound)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(recipe)
+}
+
+// PUT /api/meals/{id}/ingredients/{ingredientID}/position
+func MoveMealIngredient(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	_, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	ingredientID, position, ok := readPosition(w, r)
+	if !ok {
+		return
+	}
+
+	meal, err := models.MoveMealIngredient(db, id, ingredientID, position)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ErrorResponse(w, "Ingredient not found", http.StatusNotFound)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(meal)
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveRecipeIngredientInvalidPosition(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	request := func(ingredientID string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/recipes/1/ingredients/"+ingredientID+"/position", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("ingredientID", ingredientID)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, "db", db)
+		ctx = context.WithValue(ctx, "id", 1)
+		rec := httptest.NewRecorder()
+		MoveRecipeIngredient(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request("first", `{"position":1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request("10", `{"position":0}`).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
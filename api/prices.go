@@ -0,0 +1,162 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+type budgetRequest struct {
+	WeeklyBudget *float64 `json:"weekly_budget"`
+}
+
+// GET /api/prices?name=&store=&history=true
+//
+// Lists the latest price of each ingredient at each store, or with
+// history=true every price point, newest first.
+func GetPricesHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	query := r.URL.Query()
+	getPrices := models.GetCurrentPrices
+	if query.Get("history") == "true" {
+		getPrices = models.GetPriceHistory
+	}
+	prices, err := getPrices(db, householdID, query.Get("name"), query.Get("store"))
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(prices)
+}
+
+// POST /api/prices
+func CreatePriceHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	data := new(models.IngredientPrice)
+	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	price, err := models.RecordPrice(db, householdID, data)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidPrice) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(price)
+}
+
+// DELETE /api/prices/{priceID}
+func DeletePriceHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "priceID"))
+	if err != nil {
+		ErrorResponse(w, "Invalid price ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.DeletePrice(db, householdID, id); err != nil {
+		if err == sql.ErrNoRows {
+			ErrorResponse(w, "Price not found", http.StatusNotFound)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/household/budget
+func GetHouseholdBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	budget, err := models.GetHouseholdBudget(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(budgetRequest{WeeklyBudget: budget})
+}
+
+// PUT /api/household/budget
+//
+// A null weekly_budget clears the budget.
+func UpdateHouseholdBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	data := budgetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := models.SetHouseholdBudget(db, householdID, data.WeeklyBudget); err != nil {
+		if errors.Is(err, models.ErrInvalidBudget) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(data)
+}
+
+// withRecipeCost attaches the cost at the requesting household's prices.
+// Anonymous requests have householdID 0 and get no cost; like nutrition, a
+// failed lookup is logged and the recipe returned as is.
+func withRecipeCost(db *sqlx.DB, householdID int, recipe *models.Recipe) *models.Recipe {
+	if householdID == 0 {
+		return recipe
+	}
+	summary, err := models.RecipeCost(db, householdID, recipe)
+	if err != nil {
+		fmt.Println("Error computing recipe cost:", err)
+		return recipe
+	}
+	recipe.Cost = summary
+	return recipe
+}
+
+// withMealCost is withRecipeCost for meals
+func withMealCost(db *sqlx.DB, householdID int, meal *models.Meal) *models.Meal {
+	if householdID == 0 {
+		return meal
+	}
+	summary, err := models.MealCost(db, householdID, meal)
+	if err != nil {
+		fmt.Println("Error computing meal cost:", err)
+		return meal
+	}
+	meal.Cost = summary
+	return meal
+}
+
+// withPlanCost attaches the plan's projected cost and budget check
+func withPlanCost(db *sqlx.DB, plan *models.Plan) *models.Plan {
+	report, err := models.PlanCost(db, plan)
+	if err != nil {
+		fmt.Println("Error computing plan cost:", err)
+		return plan
+	}
+	plan.Cost = report
+	return plan
+}
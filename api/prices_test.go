@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var priceColumns = []string{"id", "household_id", "name", "store", "amount", "unit", "price", "observed_on", "created_at"}
+
+func TestCreatePriceHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	request := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/prices", strings.NewReader(body))
+		ctx := context.WithValue(req.Context(), "db", db)
+		ctx = context.WithValue(ctx, "household", 42)
+		rec := httptest.NewRecorder()
+		CreatePriceHandler(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("recorded", func(t *testing.T) {
+		day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("INSERT INTO ingredient_prices").
+			WithArgs(42, "flour", "Supermarket", 2.0, "kg", 3.0, sqlmock.AnyArg(), nil).
+			WillReturnRows(sqlmock.NewRows(priceColumns).
+				AddRow(1, 42, "flour", "Supermarket", 2, "kg", 3.0, day, time.Now()))
+
+		rec := request(`{"name": "Flour", "store": "Supermarket", "amount": 2, "unit": "kilograms", "price": 3, "observed_on": "2026-10-18"}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var price models.IngredientPrice
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &price))
+		assert.Equal(t, "flour", price.Name)
+		assert.Equal(t, "kg", price.Unit)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rec := request(`{"name": "flour", "price": -1}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestGetPricesHandlerHistory(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT \\* FROM ingredient_prices WHERE household_id=\\$1").WithArgs(42, "flour", "").
+		WillReturnRows(sqlmock.NewRows(priceColumns).
+			AddRow(2, 42, "flour", "Supermarket", 1, "kg", 1.80, now, now).
+			AddRow(1, 42, "flour", "Supermarket", 1, "kg", 1.50, now.AddDate(0, -1, 0), now))
+
+	req := httptest.NewRequest("GET", "/api/prices?name=Flour&history=true", nil)
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "household", 42)
+	rec := httptest.NewRecorder()
+	GetPricesHandler(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var prices []models.IngredientPrice
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &prices))
+	require.Len(t, prices, 2)
+	assert.Equal(t, 1.80, prices[0].Price)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPlanCost(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(1, start, start.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}).AddRow(1, 1, 7))
+	mock.ExpectQuery("SELECT p.\\* FROM dietary_profiles").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "allergens", "excluded_ingredients", "diets"}))
+
+	// PlanCost
+	mock.ExpectQuery("SELECT DISTINCT ON \\(name, store\\) \\* FROM ingredient_prices").WithArgs(42, "", "").
+		WillReturnRows(sqlmock.NewRows(priceColumns).
+			AddRow(1, 42, "steak", "Butcher", 1, "lb", 12.00, start, time.Now()))
+	mock.ExpectQuery("SELECT weekly_budget FROM households WHERE id=\\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"weekly_budget"}).AddRow(20.0))
+	mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image", "servings"}).
+			AddRow(7, "Steak Night", "Dinner", "steak-night", nil, 2))
+	mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "name", "meal_id"}).AddRow(1, "2 lb", "steak", 7))
+	mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "text", "order", "meal_id"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+	mock.ExpectQuery("SELECT t.name FROM tags t").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	req := httptest.NewRequest("GET", "/api/plans/1", nil)
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "id", 1)
+	ctx = context.WithValue(ctx, "household", 42)
+	rec := httptest.NewRecorder()
+	GetPlan(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var plan models.Plan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &plan))
+	require.NotNil(t, plan.Cost)
+	assert.Equal(t, 24.0, plan.Cost.Total)
+	assert.Equal(t, 20.0, *plan.Cost.Budget)
+	assert.True(t, plan.Cost.OverBudget)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateHouseholdBudgetHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	request := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/household/budget", strings.NewReader(body))
+		ctx := context.WithValue(req.Context(), "db", db)
+		ctx = context.WithValue(ctx, "household", 42)
+		rec := httptest.NewRecorder()
+		UpdateHouseholdBudgetHandler(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	mock.ExpectExec("UPDATE households SET weekly_budget=\\$1 WHERE id=\\$2").WithArgs(150.0, 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	rec := request(`{"weekly_budget": 150}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"weekly_budget": 150}`, rec.Body.String())
+
+	rec = request(`{"weekly_budget": -1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
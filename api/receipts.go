@@ -0,0 +1,129 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// Largest receipt body accepted by CreateReceiptHandler
+const maxReceiptSize = 1 << 20
+
+// GET /api/receipts
+func GetReceiptsHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	receipts, err := models.GetReceipts(db, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(receipts)
+}
+
+// POST /api/receipts?format=csv&store=&purchased_on=&mark_purchased=true&plan_id=
+//
+// Saves a receipt and records a price for each line matched to an
+// ingredient. The body is a receipt, or with format=csv a store's CSV
+// export, in which case store and purchased_on come from the query. With
+// mark_purchased=true, matching items on the shopping list of plan_id, or
+// of the next plan, are checked off.
+func CreateReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+	query := r.URL.Query()
+
+	receipt := new(models.Receipt)
+	body := http.MaxBytesReader(w, r.Body, maxReceiptSize)
+	if query.Get("format") == "csv" {
+		lines, err := models.LoadReceiptCSV(body)
+		if err != nil {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		receipt.Lines = lines
+		receipt.Store = query.Get("store")
+		if day := query.Get("purchased_on"); day != "" {
+			purchasedOn, err := time.Parse("2006-01-02", day)
+			if err != nil {
+				ErrorResponse(w, "purchased_on must be a date such as 2026-10-19", http.StatusBadRequest)
+				return
+			}
+			receipt.PurchasedOn = models.Date{Time: purchasedOn}
+		}
+	} else if err := json.NewDecoder(body).Decode(receipt); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Find the shopping list first, so nothing is saved for a missing plan
+	var plan *models.Plan
+	if query.Get("mark_purchased") == "true" {
+		var ok bool
+		if plan, ok = receiptPlan(w, r, db, householdID); !ok {
+			return
+		}
+	}
+
+	saved, err := models.SaveReceipt(db, householdID, receipt)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidReceipt) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The receipt is saved either way, so a failure here is only logged
+	if plan != nil {
+		purchased, err := models.MarkPurchased(db, householdID, plan.ID, saved.Ingredients())
+		if err != nil {
+			fmt.Println("Error marking receipt items purchased:", err)
+		} else {
+			saved.Purchased = purchased
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// receiptPlan loads the plan whose shopping list a receipt checks off: the
+// one named by plan_id, or else the next plan. It returns false after
+// writing an error response.
+func receiptPlan(w http.ResponseWriter, r *http.Request, db *sqlx.DB, householdID int) (*models.Plan, bool) {
+	value := r.URL.Query().Get("plan_id")
+	if value == "" {
+		plan, err := models.GetNextPlan(db, householdID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ErrorResponse(w, "no upcoming meal plan found", http.StatusNotFound)
+			} else {
+				ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			}
+			return nil, false
+		}
+		return plan, true
+	}
+
+	planID, err := strconv.Atoi(value)
+	if err != nil {
+		ErrorResponse(w, "Invalid plan ID", http.StatusBadRequest)
+		return nil, false
+	}
+	plan, err := models.GetPlan(db, planID)
+	if err != nil || plan.HouseholdID != householdID {
+		ErrorResponse(w, "Plan not found", http.StatusNotFound)
+		return nil, false
+	}
+	return plan, true
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateReceiptHandler(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	request := func(url string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		ctx := context.WithValue(req.Context(), "db", db)
+		ctx = context.WithValue(ctx, "household", 42)
+		rec := httptest.NewRecorder()
+		CreateReceiptHandler(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT name, category FROM ingredient_categories").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "category"}).AddRow("butter", "dairy"))
+		mock.ExpectQuery("SELECT DISTINCT ON \\(name, store\\) \\* FROM ingredient_prices").WithArgs(42, "", "").
+			WillReturnRows(sqlmock.NewRows(priceColumns))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO receipts").
+			WithArgs(42, "Corner Shop", sqlmock.AnyArg(), 4.49, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "store", "purchased_on", "total", "lines", "created_at"}).
+				AddRow(3, 42, "Corner Shop", day, 4.49,
+					[]byte(`[{"description":"SALTED BUTTER","ingredient":"butter","amount":250,"unit":"g","price":3.49},
+						{"description":"NEWSPAPER","ingredient":"","amount":1,"unit":"","price":1}]`), day))
+		mock.ExpectQuery("INSERT INTO ingredient_prices").
+			WithArgs(42, "butter", "Corner Shop", 250.0, "g", 3.49, sqlmock.AnyArg(), 3).
+			WillReturnRows(sqlmock.NewRows(priceColumns).AddRow(1, 42, "butter", "Corner Shop", 250, "g", 3.49, day, day))
+		mock.ExpectCommit()
+
+		rec := request("/api/receipts?format=csv&store=Corner+Shop&purchased_on=2026-10-18",
+			"Item,Quantity,Price\nSALTED BUTTER,250 g,3.49\nNEWSPAPER,,1.00\n")
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var receipt models.Receipt
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &receipt))
+		assert.Equal(t, 3, receipt.ID)
+		require.Len(t, receipt.Lines, 2)
+		assert.Equal(t, "butter", receipt.Lines[0].Ingredient)
+		assert.Empty(t, receipt.Lines[1].Ingredient)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no upcoming plan", func(t *testing.T) {
+		// Nothing is saved when there is no shopping list to check off
+		mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date > NOW\\(\\)").WithArgs(42).
+			WillReturnError(sql.ErrNoRows)
+
+		rec := request("/api/receipts?mark_purchased=true", `{"store": "Grocer", "lines": [{"description": "milk", "price": 1.10}]}`)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rec := request("/api/receipts", `{"store": "Grocer", "lines": []}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = request("/api/receipts?format=csv", "item,qty\nmilk,1\n")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// FetchRecipePage downloads pages for recipe import, from public addresses
+// only. It is a variable so tests can serve local fixtures instead of hitting
+// the network.
+var FetchRecipePage models.Fetcher = models.HTTPFetcher(models.PublicHTTPClient(15*time.Second), 5<<20)
+
+// POST /api/recipes/import
+//
+// Accepts either a URL to fetch or raw HTML and returns a draft recipe
+// extracted from the page's schema.org JSON-LD. Nothing is saved; the client
+// reviews the draft and submits it through CreateRecipe.
+//
+// With ?format=paprika|mealie|cooklang the body is instead a file exported
+// from that app, and an array of drafts is returned.
+func ImportRecipe(w http.ResponseWriter, r *http.Request) {
+	_, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" {
+		importRecipeFile(w, r, format)
+		return
+	}
+
+	var req struct {
+		URL  string `json:"url"`
+		HTML string `json:"html"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var recipe *models.Recipe
+	switch {
+	case req.HTML != "":
+		recipe, err = models.ParseRecipeHTML([]byte(req.HTML))
+	case req.URL != "":
+		recipe, err = models.ImportRecipeFromURL(r.Context(), FetchRecipePage, req.URL)
+	default:
+		ErrorResponse(w, "url or html is required", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		if err == models.ErrNoRecipeFound || err == models.ErrInvalidImportURL || errors.Is(err, models.ErrPrivateAddress) {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Println("Error importing recipe:", err)
+			ErrorResponse(w, err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(recipe)
+}
+
+// importRecipeFile converts a file from another recipe app into drafts.
+// Embedded photos are uploaded so the drafts can reference them by URL. With
+// ?save=true the recipes are created straight away instead, all or none.
+func importRecipeFile(w http.ResponseWriter, r *http.Request, format string) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, models.MaxRecipeFileSize))
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imported, err := models.DecodeRecipes(format, data)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	save := r.URL.Query().Get("save") == "true"
+	var db *sqlx.DB
+	if save {
+		db = r.Context().Value("db").(*sqlx.DB)
+	}
+
+	// Uploaded photos are deleted again if the import fails
+	pending := []string{}
+	fail := func(msg string, status int) {
+		for _, url := range pending {
+			if err := DeleteImage(r.Context(), url); err != nil {
+				fmt.Println("Error deleting image:", url, err)
+			}
+		}
+		ErrorResponse(w, msg, status)
+	}
+
+	recipes := []models.Recipe{}
+	for _, item := range imported {
+		recipe := item.Recipe
+		if len(item.Photo) > 0 {
+			url, err := StoreImage(r.Context(), item.PhotoName, bytes.NewReader(item.Photo), int64(len(item.Photo)))
+			if err != nil {
+				fail(err.Error(), http.StatusInternalServerError)
+				return
+			}
+			pending = append(pending, url)
+			recipe.Image = models.NullStringWrapper(url)
+		}
+		recipes = append(recipes, recipe)
+	}
+
+	if save {
+		recipes, err = models.CreateRecipes(db, recipes)
+		if err != nil {
+			if errors.Is(err, models.ErrValidation) || errors.Is(err, models.ErrSubRecipe) || errors.Is(err, models.ErrInvalidTime) {
+				fail(err.Error(), http.StatusBadRequest)
+			} else {
+				fmt.Println("Error saving imported recipes:", err)
+				fail(err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(recipes)
+}
+
+// GET /api/recipes/export?format=paprika|mealie|cooklang[&id=1&id=2]
+func ExportRecipes(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	format := r.URL.Query().Get("format")
+	ids := []int{}
+	for _, raw := range r.URL.Query()["id"] {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	recipes, err := models.GetRecipesWithDetails(db, ids)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var b bytes.Buffer
+	if err := models.EncodeRecipes(format, &b, recipes); err != nil {
+		if err == models.ErrUnknownRecipeFormat {
+			ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		} else {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, models.RecipeFormatFileName(format, recipes)))
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importFixture = `<html><head><script type="application/ld+json">
+{"@context": "https://schema.org", "@type": "Recipe", "name": "Toast",
+ "description": "Bread, but hot.", "keywords": "Breakfast",
+ "recipeIngredient": ["2 slices bread", "1 tbsp butter"],
+ "recipeInstructions": [{"@type": "HowToStep", "text": "Toast the bread."}, {"@type": "HowToStep", "text": "Butter it."}]}
+</script></head></html>`
+
+func TestImportRecipe(t *testing.T) {
+	origAuth := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = origAuth }()
+
+	origFetch := FetchRecipePage
+	FetchRecipePage = func(ctx context.Context, pageURL string) ([]byte, error) {
+		if pageURL == "https://example.com/toast" {
+			return []byte(importFixture), nil
+		}
+		return nil, errors.New("connection refused")
+	}
+	defer func() { FetchRecipePage = origFetch }()
+
+	post := func(body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/api/recipes/import", bytes.NewBuffer(b))
+		rec := httptest.NewRecorder()
+		ImportRecipe(rec, req)
+		return rec
+	}
+
+	t.Run("from url", func(t *testing.T) {
+		rec := post(map[string]string{"url": "https://example.com/toast"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var recipe models.Recipe
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recipe))
+		assert.Equal(t, "Toast", recipe.Name)
+		assert.Equal(t, 0, recipe.ID)
+		assert.Equal(t, []string{"breakfast"}, recipe.Tags)
+		require.Len(t, recipe.Ingredients, 2)
+		assert.Equal(t, "2 slices", recipe.Ingredients[0].Amount)
+		assert.Equal(t, "bread", recipe.Ingredients[0].Name)
+		require.Len(t, recipe.Steps, 2)
+		assert.Equal(t, "Butter it.", recipe.Steps[1].Text)
+	})
+
+	t.Run("from html", func(t *testing.T) {
+		rec := post(map[string]string{"html": importFixture})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("page without recipe", func(t *testing.T) {
+		rec := post(map[string]string{"html": "<html></html>"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("fetch failure", func(t *testing.T) {
+		rec := post(map[string]string{"url": "https://example.com/down"})
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+	})
+
+	t.Run("missing input", func(t *testing.T) {
+		rec := post(map[string]string{})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		RequiresAuthentication = func(r *http.Request) (*clerk.User, error) { return nil, assert.AnError }
+		defer func() { RequiresAuthentication = mockAuth }()
+
+		rec := post(map[string]string{"url": "https://example.com/toast"})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestImportRecipeFile(t *testing.T) {
+	origAuth := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = origAuth }()
+
+	origStore := StoreImage
+	stored := []string{}
+	StoreImage = func(ctx context.Context, filename string, file io.Reader, size int64) (string, error) {
+		stored = append(stored, filename)
+		return "https://images.example.com/" + filename, nil
+	}
+	defer func() { StoreImage = origStore }()
+
+	post := func(format string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/recipes/import?format="+format, bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		ImportRecipe(rec, req)
+		return rec
+	}
+
+	t.Run("paprika uploads embedded photos", func(t *testing.T) {
+		data, err := os.ReadFile("../models/testdata/sample.paprikarecipes")
+		require.NoError(t, err)
+
+		rec := post("paprika", data)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var recipes []models.Recipe
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recipes))
+		require.Len(t, recipes, 2)
+		assert.Equal(t, "Banana Bread", recipes[0].Name)
+		assert.Equal(t, "https://images.example.com/banana.jpg", recipes[0].Image.String)
+		assert.Equal(t, "https://example.com/salad.jpg", recipes[1].Image.String)
+		assert.Equal(t, []string{"banana.jpg"}, stored)
+	})
+
+	t.Run("save deletes uploaded photos when the batch isn't created", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		origDelete := DeleteImage
+		deleted := []string{}
+		DeleteImage = func(ctx context.Context, storageURL string) error {
+			deleted = append(deleted, storageURL)
+			return nil
+		}
+		defer func() { DeleteImage = origDelete }()
+
+		data, err := os.ReadFile("../models/testdata/sample.paprikarecipes")
+		require.NoError(t, err)
+
+		mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+
+		req := httptest.NewRequest("POST", "/api/recipes/import?format=paprika&save=true", bytes.NewReader(data))
+		req = req.WithContext(context.WithValue(req.Context(), "db", sqlxDB))
+		rec := httptest.NewRecorder()
+		ImportRecipe(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, []string{"https://images.example.com/banana.jpg"}, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("cooklang", func(t *testing.T) {
+		rec := post("cooklang", []byte(">> title: Toast\n\nToast @bread{2%slices} and spread with @butter{1%tbsp}.\n"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var recipes []models.Recipe
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recipes))
+		require.Len(t, recipes, 1)
+		assert.Equal(t, "Toast", recipes[0].Name)
+		assert.Equal(t, "2 slices", recipes[0].Ingredients[0].Amount)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		rec := post("evernote", []byte("{}"))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		rec := post("mealie", []byte("not json"))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestExportRecipes(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).
+			AddRow(3, "Toast", "Bread, but hot.", "toast", nil))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients WHERE recipe_id=\\$1").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "name", "amount"}).
+			AddRow(1, 3, "bread", "2 slices"))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps WHERE recipe_id=\\$1 ORDER BY").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}).
+			AddRow(1, 3, 1, "Toast the bread."))
+	mock.ExpectQuery("SELECT t.name FROM tags t").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("breakfast"))
+
+	req := httptest.NewRequest("GET", "/api/recipes/export?format=mealie&id=3", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "db", db))
+	rec := httptest.NewRecorder()
+	ExportRecipes(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="toast.json"`, rec.Header().Get("Content-Disposition"))
+
+	imported, err := models.DecodeMealie(rec.Body.Bytes())
+	require.NoError(t, err)
+	require.Len(t, imported, 1)
+	assert.Equal(t, "Toast", imported[0].Recipe.Name)
+	assert.Equal(t, []models.RecipeIngredient{{Name: "bread", Amount: "2 slices"}}, imported[0].Recipe.Ingredients)
+	assert.Equal(t, []string{"breakfast"}, imported[0].Recipe.Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Run("unknown format", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id FROM recipes WHERE deleted_at IS NULL ORDER BY id ASC").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		req := httptest.NewRequest("GET", "/api/recipes/export?format=evernote", nil)
+		req = req.WithContext(context.WithValue(req.Context(), "db", db))
+		rec := httptest.NewRecorder()
+		ExportRecipes(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
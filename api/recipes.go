@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/jmoiron/sqlx"
@@ -22,14 +23,49 @@ func GetRecipes(w http.ResponseWriter, r *http.Request) {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		json.NewEncoder(w).Encode(recipe)
+		json.NewEncoder(w).Encode(withRecipeCost(db, optionalHousehold(r, db), withRecipeNutrition(db, recipe)))
 	} else {
+		collection, ok := requestCollection(w, r, db)
+		if !ok {
+			return
+		}
+		maxTime, ok := requestMaxTime(w, r)
+		if !ok {
+			return
+		}
+		owned, ok := requestOwnedEquipment(w, r, db)
+		if !ok {
+			return
+		}
 		recipes, err := models.GetRecipes(db)
 		if err != nil {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		json.NewEncoder(w).Encode(recipes)
+		if collection != nil {
+			filtered := []models.Recipe{}
+			for _, recipe := range *recipes {
+				if collection.Contains(models.CollectionItem{RecipeID: &recipe.ID}) {
+					filtered = append(filtered, recipe)
+				}
+			}
+			recipes = &filtered
+		}
+		if owned != nil {
+			filtered := []models.Recipe{}
+			for _, recipe := range *recipes {
+				if len(models.MissingEquipment(recipe.Equipment, owned)) == 0 {
+					filtered = append(filtered, recipe)
+				}
+			}
+			recipes = &filtered
+		}
+		timed, err := withRecipeTimes(db, *recipes, maxTime)
+		if err != nil {
+			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(timed)
 	}
 }
 
@@ -42,18 +78,24 @@ func GetRecipe(w http.ResponseWriter, r *http.Request) {
 		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(recipe)
+	if inTrash(w, recipe.DeletedAt) {
+		return
+	}
+	json.NewEncoder(w).Encode(withRecipeCost(db, optionalHousehold(r, db), withRecipeNutrition(db, recipe)))
 }
 
 func UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 	db := r.Context().Value("db").(*sqlx.DB)
 	id := r.Context().Value("id").(int)
 
-	_, err := RequiresAuthentication(r)
+	user, err := RequiresAuthentication(r)
 	if err != nil {
 		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
 		return
 	}
+	if !canEditRecipe(w, db, id, user) {
+		return
+	}
 
 	data := new(models.Recipe)
 	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
@@ -63,7 +105,7 @@ func UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 
 	recipe, err := models.UpdateRecipe(db, id, data)
 	if err != nil {
-		if err == models.ErrValidation {
+		if err == models.ErrValidation || errors.Is(err, models.ErrSubRecipe) || errors.Is(err, models.ErrInvalidTime) {
 			ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		} else {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
@@ -89,7 +131,7 @@ func CreateRecipe(w http.ResponseWriter, r *http.Request) {
 
 	recipe, err := models.CreateRecipe(db, data)
 	if err != nil {
-		if err == models.ErrValidation {
+		if err == models.ErrValidation || errors.Is(err, models.ErrSubRecipe) || errors.Is(err, models.ErrInvalidTime) {
 			ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		} else {
 			ErrorResponse(w, err.Error(), http.StatusInternalServerError)
@@ -103,15 +145,24 @@ func DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 	db := r.Context().Value("db").(*sqlx.DB)
 	id := r.Context().Value("id").(int)
 
-	_, err := RequiresAuthentication(r)
+	user, err := RequiresAuthentication(r)
 	if err != nil {
 		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
 		return
 	}
+	if !canEditRecipe(w, db, id, user) {
+		return
+	}
+
+	if !confirmDeletion(w, r, user, func(householdID int) (*models.DeletionImpact, error) {
+		return models.RecipeDeletionImpact(db, id, householdID)
+	}) {
+		return
+	}
 
 	err = models.DeleteRecipe(db, id)
 	if err != nil {
-		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		trashError(w, err)
 		return
 	}
 
@@ -204,13 +204,11 @@ func TestCreateRecipe(t *testing.T) {
 		},
 	}
 
-	// Mock for slug check - recipe doesn't exist yet
+	// Mock transaction for CreateRecipe, starting with the slug check
+	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT id FROM recipes WHERE slug=\\$1").
 		WithArgs("new-test-recipe").
 		WillReturnError(fmt.Errorf("no rows in result set"))
-
-	// Mock transaction for CreateRecipe
-	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO recipes").
 		WithArgs("New Test Recipe", "New Description", "new-test-recipe").
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -220,12 +218,9 @@ func TestCreateRecipe(t *testing.T) {
 		WithArgs("new-test-recipe").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	mock.ExpectCommit()
-
-	// Mock for UpdateRecipe (called by CreateRecipe)
-	mock.ExpectBegin()
+	// The rest of the recipe is written in the same transaction
 	mock.ExpectExec("UPDATE recipes SET").
-		WithArgs(newRecipe.Name, newRecipe.Description, sqlmock.AnyArg(), 1).
+		WithArgs(newRecipe.Name, newRecipe.Description, sqlmock.AnyArg(), nil, nil, nil, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for ingredients
@@ -233,7 +228,7 @@ func TestCreateRecipe(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("INSERT INTO recipe_ingredients").
-		WithArgs(1, "Ingredient 1", "1 cup", sqlmock.AnyArg()).
+		WithArgs(1, "Ingredient 1", "1 cup", sqlmock.AnyArg(), nil, nil, "", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for steps
@@ -241,7 +236,7 @@ func TestCreateRecipe(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("INSERT INTO recipe_steps").
-		WithArgs(1, 1, "Step 1").
+		WithArgs(1, 1, "Step 1", "", nil, nil, "").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.ExpectCommit()
@@ -304,6 +299,26 @@ func TestCreateRecipe(t *testing.T) {
 	assert.Equal(t, 1, len(createdRecipe.Steps))
 }
 
+func TestCreateRecipeWithMissingSubRecipe(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	originalFunc := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = originalFunc }()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM recipes WHERE id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	body := `{"name":"Lasagna","description":"Layers","ingredients":[{"name":"béchamel","sub_recipe_id":99,"quantity":1}]}`
+	req := httptest.NewRequest("POST", "/api/recipes", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	CreateRecipe(rec, req.WithContext(context.WithValue(req.Context(), "db", sqlxDB)))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateRecipe(t *testing.T) {
 	sqlxDB, mock := setupMockDB(t)
 	defer sqlxDB.Close()
@@ -332,10 +347,27 @@ func TestUpdateRecipe(t *testing.T) {
 		},
 	}
 
+	// A shared recipe anyone can edit
+	mock.ExpectQuery("SELECT household_id FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(nil))
+
+	// The current version is kept as a revision
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).AddRow(1, "Old Recipe", "Old description", "old", nil))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients WHERE recipe_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "name", "amount", "calories"}))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps WHERE recipe_id=\\$1 ORDER BY").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
 	// Mock for UpdateRecipe
 	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO revisions \\(recipe_id, revision, snapshot\\)").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("UPDATE recipes SET").
-		WithArgs(updateRecipe.Name, updateRecipe.Description, updateRecipe.Image, 1).
+		WithArgs(updateRecipe.Name, updateRecipe.Description, updateRecipe.Image, nil, nil, nil, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for ingredients
@@ -343,7 +375,7 @@ func TestUpdateRecipe(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("INSERT INTO recipe_ingredients").
-		WithArgs(1, "Updated Ingredient", "2 cups", sqlmock.AnyArg()).
+		WithArgs(1, "Updated Ingredient", "2 cups", sqlmock.AnyArg(), nil, nil, "", 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Mock DELETE and INSERT for steps
@@ -351,7 +383,7 @@ func TestUpdateRecipe(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("INSERT INTO recipe_steps").
-		WithArgs(1, 1, "Updated Step").
+		WithArgs(1, 1, "Updated Step", "", nil, nil, "").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.ExpectCommit()
@@ -429,21 +461,18 @@ func TestDeleteRecipe(t *testing.T) {
 	RequiresAuthentication = mockAuthFunc
 	defer func() { RequiresAuthentication = originalFunc }()
 
-	// Mock for DeleteRecipe
-	mock.ExpectBegin()
-	mock.ExpectExec("DELETE FROM recipe_ingredients").
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 2))
-	mock.ExpectExec("DELETE FROM recipe_steps").
+	mock.ExpectQuery("SELECT household_id FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(nil))
+	// No meals use the recipe, so it goes straight to the trash
+	mock.ExpectQuery("SELECT household_id FROM household_members").
+		WithArgs("test-user-id").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT m.id, m.name, m.slug FROM meals m").
 		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 3))
-	mock.ExpectExec("DELETE FROM meal_recipes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug"}))
+	mock.ExpectExec("UPDATE recipes SET deleted_at=NOW\\(\\)").
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectExec("DELETE FROM recipes").
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectCommit()
 
 	// Create request
 	req := httptest.NewRequest("DELETE", "/api/recipes/1", nil)
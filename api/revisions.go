@@ -0,0 +1,101 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// revisionError writes the response for a failed revision lookup or restore
+func revisionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ErrorResponse(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, models.ErrValidation), errors.Is(err, models.ErrSubRecipe), errors.Is(err, models.ErrInvalidTime):
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+	default:
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GET /api/recipes/{id}/revisions
+func GetRecipeRevisions(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	revisions, err := models.GetRecipeRevisions(db, id)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// POST /api/recipes/{id}/revisions/{rev}/restore
+func RestoreRecipeRevision(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	rev, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil {
+		ErrorResponse(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+	if !canEditRecipe(w, db, id, user) {
+		return
+	}
+
+	recipe, err := models.RestoreRecipeRevision(db, id, rev)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(recipe)
+}
+
+// GET /api/meals/{id}/revisions
+func GetMealRevisions(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	revisions, err := models.GetMealRevisions(db, id)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// POST /api/meals/{id}/revisions/{rev}/restore
+func RestoreMealRevision(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	if _, err := RequiresAuthentication(r); err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	rev, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil {
+		ErrorResponse(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	meal, err := models.RestoreMealRevision(db, id, rev)
+	if err != nil {
+		revisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(meal)
+}
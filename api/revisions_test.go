@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreRecipeRevision(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	request := func(rev string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/recipes/1/revisions/"+rev+"/restore", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("rev", rev)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, "db", db)
+		ctx = context.WithValue(ctx, "id", 1)
+		rec := httptest.NewRecorder()
+		RestoreRecipeRevision(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request("latest").Code)
+
+	mock.ExpectQuery("SELECT household_id FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(nil))
+	mock.ExpectQuery("SELECT \\* FROM revisions WHERE recipe_id=\\$1 AND revision=\\$2").WithArgs(1, 3).
+		WillReturnError(sql.ErrNoRows)
+	assert.Equal(t, http.StatusNotFound, request("3").Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -21,6 +21,28 @@ func filter[T any](ss *[]T, test func(T) bool) *[]T {
 	return &rval
 }
 
+// pantryFilteredShoppingList loads the shopping list for a plan and drops any
+// ingredients the household already keeps in its pantry.
+func pantryFilteredShoppingList(db *sqlx.DB, planID int, householdID int) (*models.ShoppingList, error) {
+	pantry, err := models.GetPantry(db, householdID)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := models.GetShoppingList(db, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Ingredients = *filter(&list.Ingredients, func(i models.ShoppingListItem) bool {
+		return slices.ContainsFunc(pantry.Items, func(a string) bool {
+			return strings.Contains(strings.ToLower(i.Name), a)
+		}) == false
+	})
+
+	return list, nil
+}
+
 func GetShoppingList(w http.ResponseWriter, r *http.Request) {
 	db := r.Context().Value("db").(*sqlx.DB)
 	householdID := r.Context().Value("household").(int)
@@ -35,24 +57,12 @@ func GetShoppingList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pantry, err := models.GetPantry(db, householdID)
-	if err != nil {
-		ErrorResponse(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	list, err := models.GetShoppingList(db, plan.ID)
+	list, err := pantryFilteredShoppingList(db, plan.ID, householdID)
 	if err != nil {
 		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	list.Ingredients = *filter(&list.Ingredients, func(i models.ShoppingListItem) bool {
-		return slices.ContainsFunc(pantry.Items, func(a string) bool {
-			return strings.Contains(strings.ToLower(i.Name), a)
-		}) == false
-	})
-
 	json.NewEncoder(w).Encode(list)
 }
 
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// Share links stay valid for this long after the plan ends
+const shoppingListShareGrace = 24 * time.Hour
+
+func writeShoppingList(w http.ResponseWriter, list *models.ShoppingList, format string, attachment bool) {
+	contentType, err := models.ExportContentType(format)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if attachment {
+		filename := fmt.Sprintf("shopping-list-%s.%s", list.Plan.StartDate.Format("2006-01-02"), format)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	}
+
+	if err := models.RenderShoppingList(w, list, format); err != nil {
+		fmt.Println("Error rendering shopping list:", err)
+	}
+}
+
+// GET /api/shopping-list/export?format=txt|md|csv|html
+func ExportShoppingList(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.ExportFormatText
+	}
+	if _, err := models.ExportContentType(format); err != nil {
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := models.GetNextPlan(db, householdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ErrorResponse(w, "no upcoming meal plan found", http.StatusNotFound)
+			return
+		}
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, err := pantryFilteredShoppingList(db, plan.ID, householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeShoppingList(w, list, format, format != models.ExportFormatHTML)
+}
+
+// POST /api/shopping-list/share
+func ShareShoppingList(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	plan, err := models.GetNextPlan(db, householdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ErrorResponse(w, "no upcoming meal plan found", http.StatusNotFound)
+			return
+		}
+		ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := time.Until(plan.EndDate.Time) + shoppingListShareGrace
+	share, err := models.CreateShoppingListShare(db, plan.ID, householdID, duration)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      share.Token,
+		"url":        "/api/shared/shopping-list/" + share.Token,
+		"expires_at": share.ExpiresAt,
+	})
+}
+
+// GET /api/shared/shopping-list/{token}
+//
+// Renders a read-only shopping list without requiring a Clerk session. The
+// token itself is the credential, so this route must not be behind AuthCtx.
+func GetSharedShoppingList(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+
+	share, err := models.GetShoppingListShare(db, chi.URLParam(r, "token"))
+	if err != nil {
+		ErrorResponse(w, "shared shopping list not found or expired", http.StatusNotFound)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.ExportFormatHTML
+	}
+
+	list, err := pantryFilteredShoppingList(db, share.PlanID, share.HouseholdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeShoppingList(w, list, format, false)
+}
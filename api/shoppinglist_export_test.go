@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectPantryFilteredShoppingList sets up the queries made by pantryFilteredShoppingList
+func expectPantryFilteredShoppingList(mock sqlmock.Sqlmock, planID, householdID int, startDate, endDate time.Time) {
+	mock.ExpectQuery(`SELECT \* FROM pantry WHERE household_id = \$1`).
+		WithArgs(householdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "household_id"}).AddRow(1, householdID))
+	mock.ExpectQuery(`SELECT item_name FROM pantry_items WHERE pantry_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"item_name"}).AddRow("salt"))
+
+	mock.ExpectQuery(`SELECT \* FROM plans WHERE id = \$1`).
+		WithArgs(planID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "start_date", "end_date"}).
+			AddRow(planID, householdID, startDate, endDate))
+
+	status, _ := json.Marshal(models.Status{Items: []models.StatusItem{{Name: "Eggs", Amount: "2"}}})
+	mock.ExpectQuery(`SELECT \* FROM shopping_status WHERE plan_id = \$1`).
+		WithArgs(planID).
+		WillReturnRows(sqlmock.NewRows([]string{"plan_id", "status"}).AddRow(planID, status))
+
+	mock.ExpectQuery(`SELECT i.name, i.amount FROM meal_ingredients i`).
+		WithArgs(planID, householdID).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "amount"}).
+			AddRow("Eggs", "2").
+			AddRow("Kosher salt", "1 tsp").
+			AddRow("Carrots", "3"))
+}
+
+func TestExportShoppingList(t *testing.T) {
+	const householdID = 42
+	const planID = 1
+	startDate := time.Date(2030, 6, 14, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2030, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	request := func(sqlxDB *sqlx.DB, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		ctx := context.WithValue(req.Context(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "user", &clerk.User{ID: "user1"})
+		ctx = context.WithValue(ctx, "household", householdID)
+		rr := httptest.NewRecorder()
+		ExportShoppingList(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		mock.ExpectQuery(`SELECT \* FROM plans WHERE household_id`).
+			WithArgs(householdID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "start_date", "end_date"}).
+				AddRow(planID, householdID, startDate, endDate))
+		expectPantryFilteredShoppingList(mock, planID, householdID, startDate, endDate)
+
+		rr := request(sqlxDB, "/api/shopping-list/export?format=csv")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="shopping-list-2030-06-14.csv"`, rr.Header().Get("Content-Disposition"))
+		assert.Equal(t, "category,name,amount,checked\n"+
+			"Produce,Carrots,3,false\n"+
+			"Dairy & Eggs,Eggs,2,true\n", rr.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		rr := request(sqlxDB, "/api/shopping-list/export?format=pdf")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no upcoming plan", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		mock.ExpectQuery(`SELECT \* FROM plans WHERE household_id`).
+			WithArgs(householdID).
+			WillReturnError(sql.ErrNoRows)
+
+		rr := request(sqlxDB, "/api/shopping-list/export?format=md")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSharedShoppingList(t *testing.T) {
+	const householdID = 42
+	const planID = 1
+	startDate := time.Date(2030, 6, 14, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2030, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	t.Run("share", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		mock.ExpectQuery(`SELECT \* FROM plans WHERE household_id`).
+			WithArgs(householdID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "start_date", "end_date"}).
+				AddRow(planID, householdID, startDate, endDate))
+		mock.ExpectQuery(`INSERT INTO shopping_list_shares`).
+			WithArgs(sqlmock.AnyArg(), planID, householdID, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"token", "plan_id", "household_id", "created_at", "expires_at"}).
+				AddRow("TOKEN", planID, householdID, time.Now(), endDate))
+
+		req := httptest.NewRequest("POST", "/api/shopping-list/share", nil)
+		ctx := context.WithValue(req.Context(), "db", sqlxDB)
+		ctx = context.WithValue(ctx, "household", householdID)
+		rr := httptest.NewRecorder()
+		ShareShoppingList(rr, req.WithContext(ctx))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "TOKEN", resp["token"])
+		assert.Equal(t, "/api/shared/shopping-list/TOKEN", resp["url"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("view without session", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		mock.ExpectQuery(`SELECT s.\* FROM shopping_list_shares s JOIN plans p`).
+			WithArgs("TOKEN").
+			WillReturnRows(sqlmock.NewRows([]string{"token", "plan_id", "household_id", "created_at", "expires_at"}).
+				AddRow("TOKEN", planID, householdID, time.Now(), endDate))
+		expectPantryFilteredShoppingList(mock, planID, householdID, startDate, endDate)
+
+		r := chi.NewRouter()
+		r.Use(func(next http.Handler) http.Handler {
+			return mockDbCtx(next, sqlxDB)
+		})
+		r.Get("/shared/shopping-list/{token}", GetSharedShoppingList)
+
+		req := httptest.NewRequest("GET", "/shared/shopping-list/TOKEN", nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Empty(t, rr.Header().Get("Content-Disposition"))
+		assert.Contains(t, rr.Body.String(), "3 Carrots")
+		assert.NotContains(t, rr.Body.String(), "Kosher salt")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		sqlxDB, mock := setupMockDB(t)
+		defer sqlxDB.Close()
+
+		mock.ExpectQuery(`SELECT s.\* FROM shopping_list_shares s JOIN plans p`).
+			WithArgs("OLD").
+			WillReturnError(sql.ErrNoRows)
+
+		r := chi.NewRouter()
+		r.Use(func(next http.Handler) http.Handler {
+			return mockDbCtx(next, sqlxDB)
+		})
+		r.Get("/shared/shopping-list/{token}", GetSharedShoppingList)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/shared/shopping-list/OLD", nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -87,7 +87,7 @@ func TestGetShoppingList(t *testing.T) {
 			AddRow("Flour", "2 cups").
 			AddRow("Sugar", "1 cup"). // Will be filtered by pantry
 			AddRow("Eggs", "2")
-		mock.ExpectQuery(`SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE pm.plan_id=\$1 AND pm.household_id=\$2`).
+		mock.ExpectQuery(`SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p ON p.id = pm.plan_id JOIN meals m ON m.id = pm.meal_id WHERE pm.plan_id=\$1 AND p.household_id=\$2 AND m.deleted_at IS NULL`).
 			WithArgs(planID, householdID).
 			WillReturnRows(ingredientRows)
 
@@ -167,7 +167,7 @@ func TestGetShoppingList(t *testing.T) {
 		ingredientRows := sqlmock.NewRows([]string{"name", "amount"}).
 			AddRow("Flour", "2 cups").
 			AddRow("Eggs", "2")
-		mock.ExpectQuery(`SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE pm.plan_id=\$1 AND pm.household_id=\$2`).
+		mock.ExpectQuery(`SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p ON p.id = pm.plan_id JOIN meals m ON m.id = pm.meal_id WHERE pm.plan_id=\$1 AND p.household_id=\$2 AND m.deleted_at IS NULL`).
 			WithArgs(planID, householdID).
 			WillReturnRows(ingredientRows)
 
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// requestMaxTime reads a list handler's max_time= parameter, in minutes. It
+// returns nil when the parameter isn't set, and false after writing an
+// error response when it isn't a number of minutes.
+func requestMaxTime(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	value := r.URL.Query().Get("max_time")
+	if value == "" {
+		return nil, true
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		ErrorResponse(w, "max_time must be a number of minutes", http.StatusBadRequest)
+		return nil, false
+	}
+	return &minutes, true
+}
+
+// withRecipeTimes sets the time of listed recipes. Times are left out if
+// they can't be worked out, unless maxTime needs them.
+func withRecipeTimes(db *sqlx.DB, recipes []models.Recipe, maxTime *int) ([]models.Recipe, error) {
+	times, err := models.GetRecipeTimes(db)
+	if err != nil {
+		if maxTime != nil {
+			return nil, err
+		}
+		fmt.Println("Error computing recipe times:", err)
+		return recipes, nil
+	}
+	models.ApplyRecipeTimes(recipes, times)
+	if maxTime == nil {
+		return recipes, nil
+	}
+	filtered := []models.Recipe{}
+	for _, recipe := range recipes {
+		if models.WithinTime(recipe.Time, *maxTime) {
+			filtered = append(filtered, recipe)
+		}
+	}
+	return filtered, nil
+}
+
+// withMealTimes is withRecipeTimes for meals
+func withMealTimes(db *sqlx.DB, meals []models.Meal, maxTime *int) ([]models.Meal, error) {
+	ids := make([]int, len(meals))
+	for i, meal := range meals {
+		ids[i] = meal.ID
+	}
+	times, err := models.GetMealTimes(db, ids)
+	if err != nil {
+		if maxTime != nil {
+			return nil, err
+		}
+		fmt.Println("Error computing meal times:", err)
+		return meals, nil
+	}
+	models.ApplyMealTimes(meals, times)
+	if maxTime == nil {
+		return meals, nil
+	}
+	filtered := []models.Meal{}
+	for _, meal := range meals {
+		if models.WithinTime(meal.Time, *maxTime) {
+			filtered = append(filtered, meal)
+		}
+	}
+	return filtered, nil
+}
+
+func withOneMealTimes(db *sqlx.DB, meal *models.Meal) *models.Meal {
+	meals, _ := withMealTimes(db, []models.Meal{*meal}, nil)
+	return &meals[0]
+}
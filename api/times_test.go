@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lawn-chair/mealplan/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRecipesMaxTime(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	request := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/recipes?"+query, nil)
+		rec := httptest.NewRecorder()
+		GetRecipes(rec, req.WithContext(context.WithValue(req.Context(), "db", db)))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request("max_time=soon").Code)
+
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE deleted_at IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug"}).
+			AddRow(1, "Stir fry", "Quick", "stir-fry").
+			AddRow(2, "Stew", "Slow", "stew").
+			AddRow(3, "Salad", "Untimed", "salad"))
+	for _, id := range []int{1, 2, 3} {
+		mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	}
+	mock.ExpectQuery("SELECT id, prep_minutes, cook_minutes FROM recipes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "prep_minutes", "cook_minutes"}).
+			AddRow(1, nil, nil).AddRow(2, 20, 120).AddRow(3, nil, nil))
+	mock.ExpectQuery("SELECT recipe_id AS id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active", "passive", "timed"}).AddRow(1, 15, 10, true))
+	mock.ExpectQuery("SELECT recipe_id, sub_recipe_id FROM recipe_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "sub_recipe_id"}))
+
+	rec := request("max_time=30")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var recipes []models.Recipe
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recipes))
+	require.Len(t, recipes, 1)
+	assert.Equal(t, "Stir fry", recipes[0].Name)
+	assert.Equal(t, 25, recipes[0].Time.Total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
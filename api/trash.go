@@ -0,0 +1,155 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// TrashRetention is how long deleted items are kept, used to tell clients
+// when they will be purged. The server sets it from its -trash-retention-days
+// flag; 0 means they are never purged.
+var TrashRetention time.Duration
+
+// GET /api/trash
+func GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID := r.Context().Value("household").(int)
+
+	trash, err := models.GetTrash(db, householdID, TrashRetention)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(trash)
+}
+
+// confirmDeletion checks what still uses a recipe or meal before it is
+// deleted. Unless the request has ?confirm=true, it answers 409 with the
+// affected meals and plans and returns false.
+func confirmDeletion(w http.ResponseWriter, r *http.Request, user *clerk.User, impact func(householdID int) (*models.DeletionImpact, error)) bool {
+	if r.URL.Query().Get("confirm") == "true" {
+		return true
+	}
+	db := r.Context().Value("db").(*sqlx.DB)
+	householdID, err := GetHouseholdIDForUser(db, user.ID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	affected, err := impact(householdID)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if affected.Empty() {
+		return true
+	}
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": "still in use; repeat with ?confirm=true to delete anyway",
+		"meals": affected.Meals,
+		"plans": affected.Plans,
+	})
+	return false
+}
+
+// inTrash answers 404 for a deleted recipe, meal or plan and returns true.
+// Deleted items are only reachable through the trash and restore endpoints.
+func inTrash(w http.ResponseWriter, deletedAt *time.Time) bool {
+	if deletedAt == nil {
+		return false
+	}
+	ErrorResponse(w, "Not found", http.StatusNotFound)
+	return true
+}
+
+// trashError writes the response for a failed delete or restore
+func trashError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		ErrorResponse(w, "Not found", http.StatusNotFound)
+	} else if !overlapResponse(w, err) {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// POST /api/recipes/{id}/restore
+func RestoreRecipe(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	user, err := RequiresAuthentication(r)
+	if err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	if !canEditRecipe(w, db, id, user) {
+		return
+	}
+	if err := models.RestoreRecipe(db, id); err != nil {
+		trashError(w, err)
+		return
+	}
+	recipe, err := models.GetRecipe(db, id)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(recipe)
+}
+
+// POST /api/meals/{id}/restore
+//
+// Meals are shared, so like deleting one this only needs a signed-in user.
+func RestoreMealHandler(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+
+	if _, err := RequiresAuthentication(r); err != nil {
+		ErrorResponse(w, "Unauthorized request", http.StatusUnauthorized)
+		return
+	}
+	if err := models.RestoreMeal(db, id); err != nil {
+		trashError(w, err)
+		return
+	}
+	meal, err := models.GetMeal(db, id)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(meal)
+}
+
+// POST /api/plans/{id}/restore
+//
+// A plan can't be restored over days a newer plan covers; that answers 409
+// with the overlapping plans.
+func RestorePlan(w http.ResponseWriter, r *http.Request) {
+	db := r.Context().Value("db").(*sqlx.DB)
+	id := r.Context().Value("id").(int)
+	householdID := r.Context().Value("household").(int)
+
+	plan, err := models.GetPlan(db, id)
+	if err != nil || plan.HouseholdID != householdID {
+		ErrorResponse(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+	if err := models.RestorePlan(db, id); err != nil {
+		trashError(w, err)
+		return
+	}
+	plan, err = models.GetPlan(db, id)
+	if err != nil {
+		ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(plan)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteRecipeInUse(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	mock.ExpectQuery("SELECT household_id FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(nil))
+	mock.ExpectQuery("SELECT household_id FROM household_members").WithArgs("test-user").
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(42))
+	mock.ExpectQuery("SELECT m.id, m.name, m.slug FROM meals m").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug"}).AddRow(5, "Taco night", "taco-night"))
+	mock.ExpectQuery("SELECT DISTINCT p.id").WithArgs(sqlmock.AnyArg(), 42, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date"}))
+
+	req := httptest.NewRequest("DELETE", "/api/recipes/1", nil)
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "id", 1)
+	rec := httptest.NewRecorder()
+	DeleteRecipe(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+	var body struct {
+		Meals []struct {
+			Name string `json:"name"`
+		} `json:"meals"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	require.Len(t, body.Meals, 1)
+	assert.Equal(t, "Taco night", body.Meals[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreRecipeNotInTrash(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	mock.ExpectQuery("SELECT household_id FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(nil))
+	mock.ExpectExec("UPDATE recipes SET deleted_at=NULL").WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req := httptest.NewRequest("POST", "/api/recipes/1/restore", nil)
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "id", 1)
+	rec := httptest.NewRecorder()
+	RestoreRecipe(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreOtherHouseholdsRecipe(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	orig := RequiresAuthentication
+	RequiresAuthentication = mockAuth
+	defer func() { RequiresAuthentication = orig }()
+
+	mock.ExpectQuery("SELECT household_id FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(7))
+	mock.ExpectQuery("SELECT household_id FROM household_members").WithArgs("test-user").
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow(42))
+
+	req := httptest.NewRequest("POST", "/api/recipes/1/restore", nil)
+	ctx := context.WithValue(req.Context(), "db", db)
+	ctx = context.WithValue(ctx, "id", 1)
+	rec := httptest.NewRecorder()
+	RestoreRecipe(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrashedItemsNotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	deletedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	request := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		ctx := context.WithValue(req.Context(), "db", db)
+		ctx = context.WithValue(ctx, "id", 1)
+		ctx = context.WithValue(ctx, "household", 42)
+		rec := httptest.NewRecorder()
+		handler(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	t.Run("recipe", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "deleted_at"}).AddRow(1, "Toast", "Hot", "toast", deletedAt))
+		mock.ExpectQuery("SELECT \\* FROM recipe_ingredients").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT \\* FROM recipe_steps").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		assert.Equal(t, http.StatusNotFound, request(GetRecipe).Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("meal", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "deleted_at"}).AddRow(1, "Brunch", "Late", "brunch", deletedAt))
+		mock.ExpectQuery("SELECT \\* FROM meal_ingredients").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT \\* FROM meal_steps").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT \\* FROM meal_recipes").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+		mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		assert.Equal(t, http.StatusNotFound, request(GetMealHandler).Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("plan", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id", "deleted_at"}).
+				AddRow(1, deletedAt, deletedAt.AddDate(0, 0, 6), 42, deletedAt))
+		mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+
+		assert.Equal(t, http.StatusNotFound, request(GetPlan).Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
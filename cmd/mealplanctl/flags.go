@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// idList is a repeatable integer flag
+type idList []int
+
+func (l *idList) String() string {
+	parts := make([]string, len(*l))
+	for i, id := range *l {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *idList) Set(value string) error {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", value)
+	}
+	*l = append(*l, id)
+	return nil
+}
+
+// intArgs parses exactly n positional integer arguments
+func intArgs(args []string, n int, usage string) ([]int, error) {
+	if len(args) != n {
+		return nil, errors.New(usage)
+	}
+	ids := make([]int, n)
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
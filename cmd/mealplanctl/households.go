@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lawn-chair/mealplan/api"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+func runHouseholds(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected list, merge, move-member, export or import")
+	}
+
+	switch args[0] {
+	case "list":
+		return listHouseholds()
+	case "merge":
+		return mergeHouseholds(args[1:])
+	case "move-member":
+		return moveMember(args[1:])
+	case "export":
+		return exportHousehold(args[1:])
+	case "import":
+		return importHousehold(args[1:])
+	}
+	return fmt.Errorf("unknown households subcommand %q", args[0])
+}
+
+func listHouseholds() error {
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	households, err := models.ListHouseholds(db)
+	if err != nil {
+		return err
+	}
+	for _, h := range households {
+		emails := make([]string, len(h.Members))
+		for i, m := range h.Members {
+			emails[i] = m.Email
+			if emails[i] == "" {
+				emails[i] = m.UserID
+			}
+		}
+		fmt.Printf("%d\t%s\t%s\n", h.ID, h.Name, strings.Join(emails, ", "))
+	}
+	return nil
+}
+
+func mergeHouseholds(args []string) error {
+	ids, err := intArgs(args, 2, "usage: mealplanctl households merge FROM_ID INTO_ID")
+	if err != nil {
+		return err
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := models.MergeHouseholds(db, ids[0], ids[1]); err != nil {
+		return err
+	}
+	fmt.Printf("merged household %d into %d\n", ids[0], ids[1])
+	return nil
+}
+
+func moveMember(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: mealplanctl households move-member USER_ID HOUSEHOLD_ID")
+	}
+	householdID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid household id %q", args[1])
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := models.MoveHouseholdMember(db, args[0], householdID); err != nil {
+		return err
+	}
+	fmt.Printf("moved %s to household %d\n", args[0], householdID)
+	return nil
+}
+
+// exportHousehold writes the same library document as GET /api/export
+func exportHousehold(args []string) error {
+	fs := flag.NewFlagSet("households export", flag.ExitOnError)
+	output := fs.String("o", "", "output file (default stdout)")
+	archive := fs.Bool("zip", false, "write a zip archive including images")
+	fs.Parse(args)
+	ids, err := intArgs(fs.Args(), 1, "usage: mealplanctl households export [--zip] [-o FILE] HOUSEHOLD_ID")
+	if err != nil {
+		return err
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	lib, err := models.ExportLibrary(db, ids[0])
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *archive {
+		return models.WriteLibraryArchive(context.Background(), w, lib, api.FetchExportImage)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(lib)
+}
+
+// importHousehold loads a library document or archive into a household, as
+// POST /api/import does.
+func importHousehold(args []string) error {
+	fs := flag.NewFlagSet("households import", flag.ExitOnError)
+	strategy := fs.String("strategy", models.ConflictSkip, "slug conflict strategy: skip, rename or overwrite")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("usage: mealplanctl households import [--strategy S] HOUSEHOLD_ID FILE")
+	}
+	if !models.ValidConflictStrategy(*strategy) {
+		return models.ErrUnknownConflictStrategy
+	}
+	householdID, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid household id %q", fs.Arg(0))
+	}
+
+	data, err := os.ReadFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	lib, images, err := models.ReadLibrary(data)
+	if err != nil {
+		return err
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	upload, undo := api.ArchiveImageUploader(context.Background(), lib, images)
+	result, err := models.ImportLibrary(db, householdID, lib, *strategy, upload)
+	if err != nil {
+		undo()
+		return err
+	}
+	return printJSON(result)
+}
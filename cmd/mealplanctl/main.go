@@ -0,0 +1,70 @@
+// mealplanctl is a command-line companion to the mealplan server. It works
+// directly against the database named by DATABASE_URL.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"github.com/lawn-chair/mealplan/utils"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"migrate":    {"migrate [--dir DIR] up|down|status", runMigrate},
+	"households": {"households list|merge|move-member|export|import ...", runHouseholds},
+	"nutrition":  {"nutrition import FILE.csv", runNutrition},
+	"recipes":    {"recipes import|export --format paprika|mealie|cooklang ...", runRecipes},
+	"reslug":     {"reslug [--dry-run]", runReslug},
+	"rotations":  {"rotations roll", runRotations},
+	"prune":      {"prune [--dry-run] [--days N] [--grace D] tags|images|trash", runPrune},
+	"seed":       {"seed [--household ID]", runSeed},
+}
+
+func main() {
+	godotenv.Load(".env.local")
+	godotenv.Load()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: mealplanctl <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  mealplanctl", commands[name].usage)
+	}
+}
+
+func connect() (*sqlx.DB, error) {
+	return sqlx.Connect("postgres", utils.GetEnv("DATABASE_URL", ""))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/lawn-chair/mealplan/api"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+func runReslug(args []string) error {
+	fs := flag.NewFlagSet("reslug", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "show the changes without saving them")
+	fs.Parse(args)
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	changes, err := models.ReslugRecipes(db, *dryRun)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Printf("%d\t%s -> %s\n", c.ID, c.OldSlug, c.NewSlug)
+	}
+	fmt.Printf("%d recipes re-slugged\n", len(changes))
+	return nil
+}
+
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "list what would be removed without removing it (images only)")
+	days := fs.Int("days", 30, "purge what has been in the trash this many days (trash only)")
+	grace := fs.Duration("grace", 24*time.Hour, "keep images uploaded within this long, which may not be saved yet (images only)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: mealplanctl prune [--dry-run] [--days N] [--grace D] tags|images|trash")
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch fs.Arg(0) {
+	case "tags":
+		tags, err := models.PruneOrphanedTags(db)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			fmt.Println("removed tag", tag)
+		}
+		return nil
+	case "images":
+		urls, err := models.ImageURLs(db)
+		if err != nil {
+			return err
+		}
+		return pruneImages(urls, *dryRun, time.Now().Add(-*grace))
+	case "trash":
+		result, err := models.PurgeTrash(db, time.Now().AddDate(0, 0, -*days))
+		if result != nil {
+			fmt.Printf("purged %d recipes, %d meals, %d plans\n", result.Recipes, result.Meals, result.Plans)
+		}
+		return err
+	}
+	return fmt.Errorf("unknown prune target %q", fs.Arg(0))
+}
+
+// pruneImages removes objects from the image bucket that no recipe or meal
+// references. Objects are matched on the last path segment of each URL,
+// which is the object key StoreImage generates. Objects modified after
+// cutoff are kept, since their recipe or meal may not be saved yet.
+func pruneImages(urls []string, dryRun bool, cutoff time.Time) error {
+	referenced := map[string]bool{}
+	for _, raw := range urls {
+		if u, err := url.Parse(raw); err == nil {
+			referenced[path.Base(u.Path)] = true
+		}
+	}
+
+	client, bucket, err := api.ImageStorage()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	removed := 0
+	for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if referenced[path.Base(object.Key)] || object.LastModified.After(cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := client.RemoveObject(ctx, bucket, object.Key, minio.RemoveObjectOptions{}); err != nil {
+				return err
+			}
+		}
+		fmt.Println("removed image", object.Key)
+		removed++
+	}
+	fmt.Printf("%d orphaned images\n", removed)
+	return nil
+}
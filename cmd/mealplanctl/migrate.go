@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	iofs "io/fs"
+	"os"
+
+	"github.com/lawn-chair/mealplan/migrations"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dir := fs.String("dir", "", "directory containing goose SQL migrations (default: the ones built in)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: mealplanctl migrate [--dir DIR] up|down|status")
+	}
+
+	var source iofs.FS = migrations.FS
+	if *dir != "" {
+		source = os.DirFS(*dir)
+	}
+	all, err := models.LoadMigrations(source, ".")
+	if err != nil {
+		return err
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := models.MigrateUpLocked(context.Background(), db, all)
+		for _, m := range applied {
+			fmt.Println("applied", m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		m, err := models.MigrateDown(db, all)
+		if err != nil {
+			return err
+		}
+		fmt.Println("rolled back", m.Name)
+		return nil
+	case "status":
+		applied, err := models.AppliedMigrations(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range all {
+			state := "pending"
+			if applied[m.Version] {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %s\n", state, m.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate subcommand %q", fs.Arg(0))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// runNutrition loads a nutrient dataset CSV, such as data/nutrients.csv,
+// replacing existing entries with the same names.
+func runNutrition(args []string) error {
+	if len(args) != 2 || args[0] != "import" {
+		return errors.New("usage: mealplanctl nutrition import FILE.csv")
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	items, err := models.LoadNutritionCSV(f)
+	if err != nil {
+		return err
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := models.SaveIngredientNutrition(db, items); err != nil {
+		return err
+	}
+	fmt.Printf("loaded %d ingredients\n", len(items))
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lawn-chair/mealplan/api"
+	"github.com/lawn-chair/mealplan/models"
+)
+
+func runRecipes(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected import or export")
+	}
+
+	switch args[0] {
+	case "import":
+		return importRecipes(args[1:])
+	case "export":
+		return exportRecipes(args[1:])
+	}
+	return fmt.Errorf("unknown recipes subcommand %q", args[0])
+}
+
+// importRecipes creates a recipe for every entry in a Paprika, Mealie or
+// Cooklang file. Embedded photos are uploaded to image storage.
+func importRecipes(args []string) error {
+	fs := flag.NewFlagSet("recipes import", flag.ExitOnError)
+	format := fs.String("format", "", "file format: paprika, mealie or cooklang")
+	dryRun := fs.Bool("dry-run", false, "parse the file and list recipes without saving")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: mealplanctl recipes import --format FORMAT FILE")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	imported, err := models.DecodeRecipes(*format, data)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		for _, item := range imported {
+			fmt.Printf("%s (%d ingredients, %d steps)\n", item.Recipe.Name, len(item.Recipe.Ingredients), len(item.Recipe.Steps))
+		}
+		return nil
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, item := range imported {
+		recipe := item.Recipe
+		if len(item.Photo) > 0 {
+			url, err := api.StoreImage(context.Background(), item.PhotoName, bytes.NewReader(item.Photo), int64(len(item.Photo)))
+			if err != nil {
+				return fmt.Errorf("uploading photo for %s: %w", recipe.Name, err)
+			}
+			recipe.Image = models.NullStringWrapper(url)
+		}
+		created, err := models.CreateRecipe(db, &recipe)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", recipe.Name, err)
+		}
+		fmt.Printf("created %d %s\n", created.ID, created.Slug)
+	}
+	return nil
+}
+
+// exportRecipes writes recipes to a file, or stdout when no file is given
+func exportRecipes(args []string) error {
+	fs := flag.NewFlagSet("recipes export", flag.ExitOnError)
+	format := fs.String("format", "", "file format: paprika, mealie or cooklang")
+	output := fs.String("o", "", "output file (default stdout)")
+	var ids idList
+	fs.Var(&ids, "id", "recipe ID to export; repeat for several (default all)")
+	fs.Parse(args)
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	recipes, err := models.GetRecipesWithDetails(db, ids)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return models.EncodeRecipes(*format, w, recipes)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lawn-chair/mealplan/models"
+)
+
+// runRotations creates the plans of rotations that are due, for deployments
+// that run the server with -rotations=false and schedule this instead.
+func runRotations(args []string) error {
+	if len(args) != 1 || args[0] != "roll" {
+		return errors.New("usage: mealplanctl rotations roll")
+	}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	created, err := models.RollDueRotations(db, time.Now())
+	for _, plan := range created {
+		fmt.Printf("created plan %d (%s to %s) for household %d\n", plan.ID,
+			plan.StartDate.Format("2006-01-02"), plan.EndDate.Format("2006-01-02"), plan.HouseholdID)
+	}
+	return err
+}
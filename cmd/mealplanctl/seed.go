@@ -0,0 +1,58 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/lawn-chair/mealplan/models"
+)
+
+//go:embed demo.json
+var demoLibrary []byte
+
+// runSeed loads a small demo library into a household, creating one when no
+// household is given, and plans its meals for the coming week.
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	householdID := fs.Int("household", 0, "household to seed (default: create a new demo household)")
+	fs.Parse(args)
+
+	var lib models.Library
+	if err := json.Unmarshal(demoLibrary, &lib); err != nil {
+		return err
+	}
+	lib.ExportedAt = time.Now()
+
+	start := time.Now().Truncate(24 * time.Hour)
+	plan := models.Plan{
+		StartDate: models.Date{Time: start},
+		EndDate:   models.Date{Time: start.AddDate(0, 0, 6)},
+	}
+	for _, meal := range lib.Meals {
+		plan.Meals = append(plan.Meals, meal.ID)
+	}
+	lib.Plans = []models.Plan{plan}
+
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *householdID == 0 {
+		*householdID, err = models.CreateHousehold(db, "Demo Household")
+		if err != nil {
+			return err
+		}
+		fmt.Println("created household", *householdID)
+	}
+
+	result, err := models.ImportLibrary(db, *householdID, &lib, models.ConflictSkip, nil)
+	if err != nil {
+		return err
+	}
+	return printJSON(result)
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 
@@ -20,6 +22,8 @@ import (
 	_ "github.com/lib/pq"
 
 	"github.com/lawn-chair/mealplan/api"
+	"github.com/lawn-chair/mealplan/migrations"
+	"github.com/lawn-chair/mealplan/models"
 	"github.com/lawn-chair/mealplan/utils"
 
 	// Import the root CAs of the system - needed to allow Clerk to work in Docker
@@ -30,6 +34,12 @@ func main() {
 	godotenv.Load(".env.local")
 	godotenv.Load()
 
+	migrate := flag.Bool("migrate", utils.GetEnv("MIGRATE_ON_START", "false") == "true", "apply pending migrations before serving (env MIGRATE_ON_START)")
+	rotations := flag.Bool("rotations", utils.GetEnv("ROLL_ROTATIONS", "false") == "true", "create plans from due plan rotations in the background (env ROLL_ROTATIONS)")
+	defaultRetention, _ := strconv.Atoi(utils.GetEnv("TRASH_RETENTION_DAYS", "0"))
+	retentionDays := flag.Int("trash-retention-days", defaultRetention, "purge deleted recipes, meals and plans after this many days; 0, the default, keeps them (env TRASH_RETENTION_DAYS)")
+	flag.Parse()
+
 	fmt.Println("Starting mealplan server...")
 	db, err := sqlx.Connect("postgres", utils.GetEnv("DATABASE_URL", ""))
 	if err != nil {
@@ -38,6 +48,20 @@ func main() {
 	defer db.Close()
 	fmt.Println("Connected to database")
 
+	if *migrate {
+		if err := runMigrations(db); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *rotations {
+		go rollRotations(db, time.Hour)
+	}
+	if *retentionDays > 0 {
+		api.TrashRetention = time.Duration(*retentionDays) * 24 * time.Hour
+		go purgeTrash(db, api.TrashRetention, time.Hour)
+	}
+
 	clerk.SetKey(utils.GetEnv("CLERK_SECRET_KEY", "clerk_secret"))
 
 	r := chi.NewRouter()
@@ -48,7 +72,7 @@ func main() {
 	// Basic CORS
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Cache-Control", "Pragma", "Expires"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -75,17 +99,35 @@ func main() {
 				meal.Get("/", api.GetMealHandler)
 				meal.Put("/", api.UpdateMealHandler)
 				meal.Delete("/", api.DeleteMealHandler)
+				meal.Get("/revisions", api.GetMealRevisions)
+				meal.Post("/revisions/{rev}/restore", api.RestoreMealRevision)
+				meal.Post("/restore", api.RestoreMealHandler)
+				meal.Put("/ingredients/{ingredientID}/position", api.MoveMealIngredient)
 			})
 		})
 
 		apir.Route("/recipes", func(recipes chi.Router) {
 			recipes.Get("/", api.GetRecipes)
 			recipes.Post("/", api.CreateRecipe)
+			recipes.Post("/import", api.ImportRecipe)
+			recipes.Get("/export", api.ExportRecipes)
 			recipes.Route("/{id}", func(recipe chi.Router) {
 				recipe.Use(IdCtx)
 				recipe.Get("/", api.GetRecipe)
 				recipe.Put("/", api.UpdateRecipe)
 				recipe.Delete("/", api.DeleteRecipe)
+				recipe.Get("/revisions", api.GetRecipeRevisions)
+				recipe.Post("/revisions/{rev}/restore", api.RestoreRecipeRevision)
+				recipe.Post("/restore", api.RestoreRecipe)
+				recipe.Post("/fork", api.ForkRecipe)
+				recipe.Get("/upstream", api.GetRecipeUpstream)
+				recipe.Put("/ingredients/{ingredientID}/position", api.MoveRecipeIngredient)
+				recipe.Route("/cook-sessions", func(sessions chi.Router) {
+					sessions.Post("/", api.StartCookSession)
+					sessions.Get("/{sessionID}", api.GetCookSession)
+					sessions.Patch("/{sessionID}", api.UpdateCookSession)
+					sessions.Get("/{sessionID}/stream", api.StreamCookSession)
+				})
 			})
 		})
 
@@ -93,12 +135,25 @@ func main() {
 			plans.Use(AuthCtx)
 			plans.Get("/", api.GetPlans)
 			plans.Post("/", api.CreatePlan)
+			plans.Post("/generate", api.GeneratePlan)
+			plans.Get("/timeline", api.GetPlanTimeline)
+			plans.Route("/rotations", func(rotations chi.Router) {
+				rotations.Get("/", api.GetPlanRotations)
+				rotations.Post("/", api.CreatePlanRotation)
+				rotations.Delete("/{rotationID}", api.DeletePlanRotation)
+				rotations.Post("/{rotationID}/roll", api.RollPlanRotation)
+			})
 			plans.Route("/{id}", func(plan chi.Router) {
 				plan.Use(IdCtx)
 				plan.Get("/", api.GetPlan)
 				plan.Put("/", api.UpdatePlan)
 				plan.Delete("/", api.DeletePlan)
 				plan.Get("/ingredients", api.GetPlanIngredients)
+				plan.Get("/nutrition", api.GetPlanNutrition)
+				plan.Post("/copy", api.CopyPlan)
+				plan.Post("/archive", api.ArchivePlan)
+				plan.Delete("/archive", api.ArchivePlan)
+				plan.Post("/restore", api.RestorePlan)
 			})
 		})
 
@@ -106,12 +161,57 @@ func main() {
 			shoppingList.Use(AuthCtx)
 			shoppingList.Get("/", api.GetShoppingList)
 			shoppingList.Put("/", api.UpdateShoppingList)
+			shoppingList.Get("/export", api.ExportShoppingList)
+			shoppingList.Post("/share", api.ShareShoppingList)
 		})
 
+		apir.Get("/shared/shopping-list/{token}", api.GetSharedShoppingList)
+
+		apir.With(AuthCtx).Get("/trash", api.GetTrashHandler)
+
 		apir.Get("/tags", api.ListTagsHandler)
 
+		apir.Route("/collections", func(collections chi.Router) {
+			collections.Use(AuthCtx)
+			collections.Get("/", api.GetCollectionsHandler)
+			collections.Post("/", api.CreateCollectionHandler)
+			collections.Get("/{collection}", api.GetCollectionHandler)
+			collections.Delete("/{collection}", api.DeleteCollectionHandler)
+			collections.Post("/{collection}/items", api.AddCollectionItemHandler)
+			collections.Delete("/{collection}/items", api.RemoveCollectionItemHandler)
+		})
+
+		apir.Route("/meal-log", func(mealLog chi.Router) {
+			mealLog.Use(AuthCtx)
+			mealLog.Get("/", api.GetMealLogHandler)
+			mealLog.Post("/", api.CreateMealLogHandler)
+			mealLog.Get("/stats", api.GetMealStatsHandler)
+			mealLog.Delete("/{entryID}", api.DeleteMealLogHandler)
+		})
+
+		apir.Route("/prices", func(prices chi.Router) {
+			prices.Use(AuthCtx)
+			prices.Get("/", api.GetPricesHandler)
+			prices.Post("/", api.CreatePriceHandler)
+			prices.Delete("/{priceID}", api.DeletePriceHandler)
+		})
+
+		apir.Route("/receipts", func(receipts chi.Router) {
+			receipts.Use(AuthCtx)
+			receipts.Get("/", api.GetReceiptsHandler)
+			receipts.Post("/", api.CreateReceiptHandler)
+		})
+
+		apir.Get("/ingredient-catalog", api.GetIngredientCatalogHandler)
+		apir.With(AuthCtx, AdminCtx).Put("/ingredient-catalog", api.UpdateIngredientCatalogHandler)
+
+		apir.With(AuthCtx).Get("/export", api.ExportLibraryHandler)
+		apir.With(AuthCtx).Post("/import", api.ImportLibraryHandler)
+
 		apir.Post("/images", api.PostImageHandler)
 
+		apir.With(AuthCtx, AdminCtx).Get("/admin/schema", api.GetSchemaHandler)
+
 		apir.Route("/household", func(household chi.Router) {
 			household.Use(AuthCtx)
 			household.Get("/", api.GetUserHouseholdHandler)
@@ -119,6 +219,12 @@ func main() {
 			household.Post("/join", api.JoinHouseholdHandler)
 			household.Post("/leave", api.LeaveHouseholdHandler)
 			household.Post("/remove-member", api.RemoveHouseholdMemberHandler)
+			household.Get("/dietary-profiles", api.GetDietaryProfilesHandler)
+			household.Put("/dietary-profiles/{userID}", api.UpdateDietaryProfileHandler)
+			household.Get("/equipment", api.GetHouseholdEquipmentHandler)
+			household.Put("/equipment", api.UpdateHouseholdEquipmentHandler)
+			household.Get("/budget", api.GetHouseholdBudgetHandler)
+			household.Put("/budget", api.UpdateHouseholdBudgetHandler)
 		})
 	})
 
@@ -199,3 +305,56 @@ func AuthCtx(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// AdminCtx only lets admins through. It must come after AuthCtx.
+func AdminCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value("user").(*clerk.User)
+		if !ok || !api.IsAdmin(user.ID) {
+			api.ErrorResponse(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func runMigrations(db *sqlx.DB) error {
+	all, err := models.LoadMigrations(migrations.FS, ".")
+	if err != nil {
+		return err
+	}
+	applied, err := models.MigrateUpLocked(context.Background(), db, all)
+	for _, m := range applied {
+		fmt.Println("Applied migration", m.Name)
+	}
+	return err
+}
+
+// rollRotations creates plans from due rotations now and then every interval
+func rollRotations(db *sqlx.DB, interval time.Duration) {
+	for {
+		created, err := models.RollDueRotations(db, time.Now())
+		for _, plan := range created {
+			fmt.Printf("Created plan %d from rotation for household %d\n", plan.ID, plan.HouseholdID)
+		}
+		if err != nil {
+			fmt.Println("Error rolling plan rotations:", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// purgeTrash permanently deletes items that have been in the trash longer
+// than retention, now and then every interval
+func purgeTrash(db *sqlx.DB, retention time.Duration, interval time.Duration) {
+	for {
+		result, err := models.PurgeTrash(db, time.Now().Add(-retention))
+		if result != nil && result.Recipes+result.Meals+result.Plans > 0 {
+			fmt.Printf("Purged %d recipes, %d meals and %d plans from the trash\n", result.Recipes, result.Meals, result.Plans)
+		}
+		if err != nil {
+			fmt.Println("Error purging trash:", err)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -128,3 +129,25 @@ func TestRouteSetup(t *testing.T) {
 	r.ServeHTTP(rec, req)
 	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
+
+func TestAdminCtx(t *testing.T) {
+	t.Setenv("ADMIN_USER_IDS", "user_admin, user_other")
+
+	handler := AdminCtx(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(user *clerk.User) int {
+		req := httptest.NewRequest("GET", "/api/admin/schema", nil)
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(&clerk.User{ID: "user_admin"}))
+	assert.Equal(t, http.StatusOK, request(&clerk.User{ID: "user_other"}))
+	assert.Equal(t, http.StatusForbidden, request(&clerk.User{ID: "user_member"}))
+	assert.Equal(t, http.StatusForbidden, request(nil))
+}
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Macros for a reference amount of each ingredient, e.g. 100 g of flour
+CREATE TABLE ingredient_nutrition (
+    name TEXT PRIMARY KEY,
+    amount DOUBLE PRECISION NOT NULL DEFAULT 100,
+    unit TEXT NOT NULL DEFAULT 'g',
+    calories DOUBLE PRECISION NOT NULL DEFAULT 0,
+    protein DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fat DOUBLE PRECISION NOT NULL DEFAULT 0,
+    carbs DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fiber DOUBLE PRECISION NOT NULL DEFAULT 0,
+    sodium DOUBLE PRECISION NOT NULL DEFAULT 0,
+    source TEXT NOT NULL DEFAULT ''
+);
+
+ALTER TABLE recipes ADD COLUMN servings INTEGER;
+ALTER TABLE meals ADD COLUMN servings INTEGER;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE meals DROP COLUMN IF EXISTS servings;
+ALTER TABLE recipes DROP COLUMN IF EXISTS servings;
+DROP TABLE IF EXISTS ingredient_nutrition;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE dietary_profiles (
+    user_id TEXT PRIMARY KEY,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    allergens TEXT[] NOT NULL DEFAULT '{}',
+    excluded_ingredients TEXT[] NOT NULL DEFAULT '{}',
+    diets TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- Allergen and food-group categories for ingredient names, matched as whole
+-- words against recipe and meal ingredients
+CREATE TABLE ingredient_categories (
+    name TEXT NOT NULL,
+    category TEXT NOT NULL,
+    PRIMARY KEY (name, category)
+);
+
+INSERT INTO ingredient_categories (name, category) VALUES
+    ('peanut', 'peanut'), ('peanut butter', 'peanut'), ('peanut oil', 'peanut'),
+    ('almond', 'tree_nut'), ('walnut', 'tree_nut'), ('cashew', 'tree_nut'), ('pecan', 'tree_nut'),
+    ('pistachio', 'tree_nut'), ('hazelnut', 'tree_nut'), ('pine nut', 'tree_nut'),
+    ('milk', 'dairy'), ('butter', 'dairy'), ('cream', 'dairy'), ('heavy cream', 'dairy'), ('sour cream', 'dairy'),
+    ('cheese', 'dairy'), ('parmesan', 'dairy'), ('cheddar', 'dairy'), ('mozzarella', 'dairy'),
+    ('yogurt', 'dairy'), ('buttermilk', 'dairy'),
+    ('egg', 'egg'), ('mayonnaise', 'egg'),
+    ('flour', 'gluten'), ('bread', 'gluten'), ('breadcrumbs', 'gluten'), ('spaghetti', 'gluten'),
+    ('pasta', 'gluten'), ('noodles', 'gluten'), ('tortilla', 'gluten'), ('barley', 'gluten'),
+    ('soy sauce', 'soy'), ('soy sauce', 'gluten'), ('tofu', 'soy'), ('edamame', 'soy'), ('miso', 'soy'),
+    ('sesame', 'sesame'), ('sesame oil', 'sesame'), ('tahini', 'sesame'),
+    ('fish', 'fish'), ('salmon', 'fish'), ('tuna', 'fish'), ('cod', 'fish'), ('anchovy', 'fish'), ('fish sauce', 'fish'),
+    ('shrimp', 'shellfish'), ('prawn', 'shellfish'), ('crab', 'shellfish'), ('lobster', 'shellfish'),
+    ('scallop', 'shellfish'), ('mussel', 'shellfish'), ('clam', 'shellfish'),
+    ('chicken', 'meat'), ('beef', 'meat'), ('ground beef', 'meat'), ('pork', 'meat'), ('bacon', 'meat'),
+    ('ham', 'meat'), ('sausage', 'meat'), ('turkey', 'meat'), ('lamb', 'meat'), ('chorizo', 'meat'),
+    ('pancetta', 'meat'), ('prosciutto', 'meat'), ('chicken stock', 'meat'), ('beef stock', 'meat'),
+    ('gelatin', 'meat'),
+    ('honey', 'honey');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS ingredient_categories;
+DROP TABLE IF EXISTS dietary_profiles;
+-- +goose StatementEnd
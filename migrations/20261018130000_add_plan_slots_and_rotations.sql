@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Meals can be planned for a particular day and slot ("dinner"). A meal can
+-- then appear on several days of the same plan, so uniqueness only applies
+-- to meals without a day.
+ALTER TABLE plan_meals ADD COLUMN day DATE;
+ALTER TABLE plan_meals ADD COLUMN slot TEXT;
+ALTER TABLE plan_meals DROP CONSTRAINT IF EXISTS plan_meals_plan_id_meal_id_key;
+CREATE UNIQUE INDEX plan_meals_undated_key ON plan_meals (plan_id, meal_id) WHERE day IS NULL;
+CREATE UNIQUE INDEX plan_meals_dated_key ON plan_meals (plan_id, meal_id, day, slot) WHERE day IS NOT NULL;
+
+-- A rotation cycles through saved plans, copying the next one forward each
+-- time a new plan is due
+CREATE TABLE plan_rotations (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    next_start DATE NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE plan_rotation_entries (
+    rotation_id INTEGER NOT NULL REFERENCES plan_rotations(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    plan_id INTEGER NOT NULL REFERENCES plans(id) ON DELETE CASCADE,
+    PRIMARY KEY (rotation_id, position)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS plan_rotation_entries;
+DROP TABLE IF EXISTS plan_rotations;
+DROP INDEX IF EXISTS plan_meals_dated_key;
+DROP INDEX IF EXISTS plan_meals_undated_key;
+DELETE FROM plan_meals a USING plan_meals b
+    WHERE a.plan_id = b.plan_id AND a.meal_id = b.meal_id AND a.id > b.id;
+ALTER TABLE plan_meals ADD CONSTRAINT plan_meals_plan_id_meal_id_key UNIQUE (plan_id, meal_id);
+ALTER TABLE plan_meals DROP COLUMN IF EXISTS slot;
+ALTER TABLE plan_meals DROP COLUMN IF EXISTS day;
+-- +goose StatementEnd
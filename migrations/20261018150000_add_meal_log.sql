@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE meal_log (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    meal_id INTEGER NOT NULL REFERENCES meals(id) ON DELETE CASCADE,
+    plan_id INTEGER REFERENCES plans(id) ON DELETE SET NULL,
+    status TEXT NOT NULL CHECK (status IN ('cooked', 'skipped', 'replaced')),
+    replacement_meal_id INTEGER REFERENCES meals(id) ON DELETE SET NULL,
+    rating INTEGER CHECK (rating BETWEEN 1 AND 5),
+    notes TEXT NOT NULL DEFAULT '',
+    cooked_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX meal_log_household_meal_idx ON meal_log (household_id, meal_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS meal_log;
+-- +goose StatementEnd
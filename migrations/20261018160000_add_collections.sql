@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE collections (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX collections_user_name_idx ON collections (user_id, lower(name));
+
+CREATE TABLE collection_items (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    recipe_id INTEGER REFERENCES recipes(id) ON DELETE CASCADE,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE,
+    CHECK (num_nonnulls(recipe_id, meal_id) = 1)
+);
+
+CREATE UNIQUE INDEX collection_items_recipe_idx ON collection_items (collection_id, recipe_id) WHERE recipe_id IS NOT NULL;
+CREATE UNIQUE INDEX collection_items_meal_idx ON collection_items (collection_id, meal_id) WHERE meal_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS collection_items;
+DROP TABLE IF EXISTS collections;
+-- +goose StatementEnd
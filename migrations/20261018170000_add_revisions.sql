@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE revisions (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    recipe_id INTEGER REFERENCES recipes(id) ON DELETE CASCADE,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (num_nonnulls(recipe_id, meal_id) = 1)
+);
+
+CREATE UNIQUE INDEX revisions_recipe_idx ON revisions (recipe_id, revision) WHERE recipe_id IS NOT NULL;
+CREATE UNIQUE INDEX revisions_meal_idx ON revisions (meal_id, revision) WHERE meal_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS revisions;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipes ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE meals ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE plans ADD COLUMN deleted_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE plans DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE meals DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE recipes DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipes ADD COLUMN forked_from INTEGER REFERENCES recipes(id) ON DELETE SET NULL;
+ALTER TABLE recipes ADD COLUMN forked_revision INTEGER;
+ALTER TABLE recipes ADD COLUMN household_id INTEGER REFERENCES households(id) ON DELETE SET NULL;
+CREATE INDEX recipes_forked_from_idx ON recipes (forked_from);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS recipes_forked_from_idx;
+ALTER TABLE recipes DROP COLUMN IF EXISTS household_id;
+ALTER TABLE recipes DROP COLUMN IF EXISTS forked_revision;
+ALTER TABLE recipes DROP COLUMN IF EXISTS forked_from;
+-- +goose StatementEnd
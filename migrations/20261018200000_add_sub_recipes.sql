@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipe_ingredients ADD COLUMN sub_recipe_id INTEGER REFERENCES recipes(id) ON DELETE SET NULL;
+ALTER TABLE recipe_ingredients ADD COLUMN quantity DOUBLE PRECISION CHECK (quantity > 0);
+CREATE INDEX recipe_ingredients_sub_recipe_id_idx ON recipe_ingredients (sub_recipe_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS recipe_ingredients_sub_recipe_id_idx;
+ALTER TABLE recipe_ingredients DROP COLUMN IF EXISTS quantity;
+ALTER TABLE recipe_ingredients DROP COLUMN IF EXISTS sub_recipe_id;
+-- +goose StatementEnd
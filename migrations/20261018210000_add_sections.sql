@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipe_ingredients ADD COLUMN section TEXT NOT NULL DEFAULT '';
+ALTER TABLE recipe_steps ADD COLUMN section TEXT NOT NULL DEFAULT '';
+ALTER TABLE meal_ingredients ADD COLUMN section TEXT NOT NULL DEFAULT '';
+ALTER TABLE meal_steps ADD COLUMN section TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE meal_steps DROP COLUMN IF EXISTS section;
+ALTER TABLE meal_ingredients DROP COLUMN IF EXISTS section;
+ALTER TABLE recipe_steps DROP COLUMN IF EXISTS section;
+ALTER TABLE recipe_ingredients DROP COLUMN IF EXISTS section;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipe_ingredients ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE meal_ingredients ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+-- Keep the order ingredients were inserted in
+UPDATE recipe_ingredients ri SET position = numbered.position
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY recipe_id ORDER BY id) AS position FROM recipe_ingredients) numbered
+WHERE ri.id = numbered.id;
+UPDATE meal_ingredients mi SET position = numbered.position
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY meal_id ORDER BY id) AS position FROM meal_ingredients) numbered
+WHERE mi.id = numbered.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE meal_ingredients DROP COLUMN IF EXISTS position;
+ALTER TABLE recipe_ingredients DROP COLUMN IF EXISTS position;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipe_steps
+    ADD COLUMN active_minutes INTEGER CHECK (active_minutes >= 0),
+    ADD COLUMN passive_minutes INTEGER CHECK (passive_minutes >= 0),
+    ADD COLUMN temperature TEXT NOT NULL DEFAULT '';
+ALTER TABLE meal_steps
+    ADD COLUMN active_minutes INTEGER CHECK (active_minutes >= 0),
+    ADD COLUMN passive_minutes INTEGER CHECK (passive_minutes >= 0),
+    ADD COLUMN temperature TEXT NOT NULL DEFAULT '';
+
+-- Set these to override the times added up from the steps
+ALTER TABLE recipes
+    ADD COLUMN prep_minutes INTEGER CHECK (prep_minutes >= 0),
+    ADD COLUMN cook_minutes INTEGER CHECK (cook_minutes >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE recipes DROP COLUMN IF EXISTS cook_minutes, DROP COLUMN IF EXISTS prep_minutes;
+ALTER TABLE meal_steps DROP COLUMN IF EXISTS temperature, DROP COLUMN IF EXISTS passive_minutes, DROP COLUMN IF EXISTS active_minutes;
+ALTER TABLE recipe_steps DROP COLUMN IF EXISTS temperature, DROP COLUMN IF EXISTS passive_minutes, DROP COLUMN IF EXISTS active_minutes;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE cook_sessions (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    current_step INTEGER NOT NULL DEFAULT 1,
+    checked_ingredients INTEGER[] NOT NULL DEFAULT '{}',
+    timers JSONB NOT NULL DEFAULT '[]',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+-- A user follows a recipe in one session at a time
+CREATE UNIQUE INDEX cook_sessions_active_idx ON cook_sessions (user_id, recipe_id) WHERE finished_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cook_sessions;
+-- +goose StatementEnd
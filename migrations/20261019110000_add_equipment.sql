@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recipes ADD COLUMN equipment TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE meals ADD COLUMN equipment TEXT[] NOT NULL DEFAULT '{}';
+
+-- What the household has to cook with; empty until they say
+ALTER TABLE households ADD COLUMN equipment TEXT[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE households DROP COLUMN IF EXISTS equipment;
+ALTER TABLE meals DROP COLUMN IF EXISTS equipment;
+ALTER TABLE recipes DROP COLUMN IF EXISTS equipment;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What a household paid for Amount Unit of an ingredient at a store. Rows are
+-- never updated, so older rows are the price history.
+CREATE TABLE ingredient_prices (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    store TEXT NOT NULL DEFAULT '',
+    amount DOUBLE PRECISION NOT NULL DEFAULT 1,
+    unit TEXT NOT NULL DEFAULT '',
+    price DOUBLE PRECISION NOT NULL,
+    observed_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ingredient_prices_household_name_idx ON ingredient_prices (household_id, name, store, observed_on);
+
+ALTER TABLE households ADD COLUMN weekly_budget DOUBLE PRECISION;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE households DROP COLUMN IF EXISTS weekly_budget;
+DROP TABLE IF EXISTS ingredient_prices;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A shopping trip as entered by the household. Lines keep what was on the
+-- receipt, matched or not.
+CREATE TABLE receipts (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    store TEXT NOT NULL DEFAULT '',
+    purchased_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    total DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lines JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX receipts_household_idx ON receipts (household_id, purchased_on);
+
+-- Price points recorded from a receipt go with it
+ALTER TABLE ingredient_prices ADD COLUMN receipt_id INTEGER REFERENCES receipts(id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE ingredient_prices DROP COLUMN IF EXISTS receipt_id;
+DROP TABLE IF EXISTS receipts;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Share expiry is compared with NOW(), so store it with its time zone
+ALTER TABLE shopping_list_shares
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE shopping_list_shares
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN expires_at TYPE TIMESTAMP;
+-- +goose StatementEnd
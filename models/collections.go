@@ -0,0 +1,230 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvalidCollection = errors.New("invalid collection")
+
+// FavoritesCollection names the collection every user has without creating
+// it. It is stored like any other collection once something is added to it.
+const FavoritesCollection = "Favorites"
+
+// Collection is a user's named set of recipes and meals
+type Collection struct {
+	ID      int    `db:"id" json:"id"`
+	UserID  string `db:"user_id" json:"user_id"`
+	Name    string `db:"name" json:"name"`
+	Recipes []int  `db:"-" json:"recipes"`
+	Meals   []int  `db:"-" json:"meals"`
+}
+
+// CollectionItem names one recipe or one meal
+type CollectionItem struct {
+	RecipeID *int `db:"recipe_id" json:"recipe_id,omitempty"`
+	MealID   *int `db:"meal_id" json:"meal_id,omitempty"`
+}
+
+func (item *CollectionItem) validate() error {
+	if (item.RecipeID == nil) == (item.MealID == nil) {
+		return fmt.Errorf("%w: give either a recipe_id or a meal_id", ErrInvalidCollection)
+	}
+	return nil
+}
+
+func isFavorites(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), FavoritesCollection)
+}
+
+func loadCollectionItems(db *sqlx.DB, c *Collection) error {
+	items := []CollectionItem{}
+	err := db.Select(&items, `SELECT recipe_id, meal_id FROM collection_items WHERE collection_id=$1 ORDER BY id`, c.ID)
+	if err != nil {
+		return err
+	}
+	c.Recipes = []int{}
+	c.Meals = []int{}
+	for _, item := range items {
+		if item.RecipeID != nil {
+			c.Recipes = append(c.Recipes, *item.RecipeID)
+		} else if item.MealID != nil {
+			c.Meals = append(c.Meals, *item.MealID)
+		}
+	}
+	return nil
+}
+
+func GetCollections(db *sqlx.DB, userID string) ([]Collection, error) {
+	collections := []Collection{}
+	err := db.Select(&collections, `SELECT * FROM collections WHERE user_id=$1 ORDER BY lower(name)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range collections {
+		if err := loadCollectionItems(db, &collections[i]); err != nil {
+			return nil, err
+		}
+	}
+	return collections, nil
+}
+
+// GetCollection finds one of the user's collections by id or by name. The
+// favorites collection is returned empty, with an ID of 0, until it is used.
+func GetCollection(db *sqlx.DB, userID string, ref string) (*Collection, error) {
+	c := Collection{}
+	var err error
+	if id, convErr := strconv.Atoi(ref); convErr == nil {
+		err = db.Get(&c, `SELECT * FROM collections WHERE id=$1 AND user_id=$2`, id, userID)
+	} else {
+		err = db.Get(&c, `SELECT * FROM collections WHERE user_id=$1 AND lower(name)=lower($2)`, userID, strings.TrimSpace(ref))
+	}
+	if err == sql.ErrNoRows && isFavorites(ref) {
+		return &Collection{UserID: userID, Name: FavoritesCollection, Recipes: []int{}, Meals: []int{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := loadCollectionItems(db, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCollection saves a new collection for the user, with any recipes and
+// meals it already lists.
+func CreateCollection(db *sqlx.DB, userID string, c *Collection) (*Collection, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidCollection)
+	}
+	if _, err := strconv.Atoi(c.Name); err == nil {
+		return nil, fmt.Errorf("%w: name can't be a number", ErrInvalidCollection)
+	}
+
+	var exists bool
+	err := db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM collections WHERE user_id=$1 AND lower(name)=lower($2))`, userID, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: you already have a collection named %q", ErrInvalidCollection, c.Name)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Get(&c.ID, `INSERT INTO collections (user_id, name) VALUES ($1, $2) RETURNING id`, userID, c.Name)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, id := range uniqueInts(c.Recipes) {
+		if _, err := tx.Exec(`INSERT INTO collection_items (collection_id, recipe_id) VALUES ($1, $2)`, c.ID, id); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	for _, id := range uniqueInts(c.Meals) {
+		if _, err := tx.Exec(`INSERT INTO collection_items (collection_id, meal_id) VALUES ($1, $2)`, c.ID, id); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetCollection(db, userID, strconv.Itoa(c.ID))
+}
+
+// DeleteCollection removes one of the user's collections. The recipes and
+// meals in it are untouched.
+func DeleteCollection(db *sqlx.DB, userID string, id int) error {
+	res, err := db.Exec(`DELETE FROM collections WHERE id=$1 AND user_id=$2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AddToCollection adds a recipe or meal to the collection, creating the
+// favorites collection the first time it is used. Adding an item twice is
+// not an error.
+func AddToCollection(db *sqlx.DB, userID string, ref string, item *CollectionItem) (*Collection, error) {
+	if err := item.validate(); err != nil {
+		return nil, err
+	}
+	c, err := GetCollection(db, userID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if item.RecipeID != nil {
+		err = db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM recipes WHERE id=$1)`, *item.RecipeID)
+	} else {
+		err = db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM meals WHERE id=$1)`, *item.MealID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: no such recipe or meal", ErrInvalidCollection)
+	}
+
+	if c.ID == 0 {
+		err = db.Get(&c.ID, `INSERT INTO collections (user_id, name) VALUES ($1, $2) RETURNING id`, userID, c.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = db.Exec(`INSERT INTO collection_items (collection_id, recipe_id, meal_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		c.ID, item.RecipeID, item.MealID)
+	if err != nil {
+		return nil, err
+	}
+	return GetCollection(db, userID, strconv.Itoa(c.ID))
+}
+
+// RemoveFromCollection takes a recipe or meal out of the collection
+func RemoveFromCollection(db *sqlx.DB, userID string, ref string, item *CollectionItem) (*Collection, error) {
+	if err := item.validate(); err != nil {
+		return nil, err
+	}
+	c, err := GetCollection(db, userID, ref)
+	if err != nil || c.ID == 0 {
+		return c, err
+	}
+	_, err = db.Exec(`DELETE FROM collection_items WHERE collection_id=$1 AND (recipe_id=$2 OR meal_id=$3)`,
+		c.ID, item.RecipeID, item.MealID)
+	if err != nil {
+		return nil, err
+	}
+	return GetCollection(db, userID, strconv.Itoa(c.ID))
+}
+
+// Contains reports whether the collection lists the recipe or meal
+func (c *Collection) Contains(item CollectionItem) bool {
+	ids, want := c.Meals, item.MealID
+	if item.RecipeID != nil {
+		ids, want = c.Recipes, item.RecipeID
+	}
+	if want == nil {
+		return false
+	}
+	for _, id := range ids {
+		if id == *want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var collectionColumns = []string{"id", "user_id", "name"}
+
+func TestGetCollection(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	t.Run("by id", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM collections WHERE id=\\$1 AND user_id=\\$2").WithArgs(3, "user1").
+			WillReturnRows(sqlmock.NewRows(collectionColumns).AddRow(3, "user1", "Weeknight"))
+		mock.ExpectQuery("SELECT recipe_id, meal_id FROM collection_items").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "meal_id"}).AddRow(1, nil).AddRow(nil, 7))
+
+		c, err := GetCollection(db, "user1", "3")
+		require.NoError(t, err)
+		assert.Equal(t, "Weeknight", c.Name)
+		assert.Equal(t, []int{1}, c.Recipes)
+		assert.Equal(t, []int{7}, c.Meals)
+		assert.True(t, c.Contains(CollectionItem{MealID: &c.Meals[0]}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("favorites before first use", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM collections WHERE user_id=\\$1 AND lower\\(name\\)").WithArgs("user1", "favorites").
+			WillReturnError(sql.ErrNoRows)
+
+		c, err := GetCollection(db, "user1", "favorites")
+		require.NoError(t, err)
+		assert.Equal(t, 0, c.ID)
+		assert.Empty(t, c.Recipes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		mock.ExpectQuery("SELECT \\* FROM collections").WithArgs("user1", "holiday baking").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := GetCollection(db, "user1", "holiday baking")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCreateCollection(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := CreateCollection(db, "user1", &Collection{Name: "  "})
+		assert.ErrorIs(t, err, ErrInvalidCollection)
+		_, err = CreateCollection(db, "user1", &Collection{Name: "12"})
+		assert.ErrorIs(t, err, ErrInvalidCollection)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").WithArgs("user1", "Weeknight").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		_, err := CreateCollection(db, "user1", &Collection{Name: "Weeknight"})
+		assert.ErrorIs(t, err, ErrInvalidCollection)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("creates with items", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").WithArgs("user1", "Holiday baking").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO collections").WithArgs("user1", "Holiday baking").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectExec("INSERT INTO collection_items \\(collection_id, recipe_id\\)").WithArgs(4, 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT \\* FROM collections WHERE id=\\$1").WithArgs(4, "user1").
+			WillReturnRows(sqlmock.NewRows(collectionColumns).AddRow(4, "user1", "Holiday baking"))
+		mock.ExpectQuery("SELECT recipe_id, meal_id FROM collection_items").WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "meal_id"}).AddRow(2, nil))
+
+		c, err := CreateCollection(db, "user1", &Collection{Name: " Holiday baking ", Recipes: []int{2, 2}})
+		require.NoError(t, err)
+		assert.Equal(t, 4, c.ID)
+		assert.Equal(t, []int{2}, c.Recipes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAddToCollection(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	recipeID := 2
+
+	_, err := AddToCollection(db, "user1", "favorites", &CollectionItem{})
+	assert.ErrorIs(t, err, ErrInvalidCollection)
+
+	// The first favorite creates the collection
+	mock.ExpectQuery("SELECT \\* FROM collections").WithArgs("user1", "favorites").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM recipes").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO collections").WithArgs("user1", FavoritesCollection).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO collection_items").WithArgs(9, &recipeID, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT \\* FROM collections WHERE id=\\$1").WithArgs(9, "user1").
+		WillReturnRows(sqlmock.NewRows(collectionColumns).AddRow(9, "user1", FavoritesCollection))
+	mock.ExpectQuery("SELECT recipe_id, meal_id FROM collection_items").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "meal_id"}).AddRow(2, nil))
+
+	c, err := AddToCollection(db, "user1", "favorites", &CollectionItem{RecipeID: &recipeID})
+	require.NoError(t, err)
+	assert.Equal(t, 9, c.ID)
+	assert.Equal(t, []int{2}, c.Recipes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidCookSession = errors.New("invalid cook session")
+
+// CookSession is one user following a recipe step by step. Every device the
+// user cooks from shares it.
+type CookSession struct {
+	ID                 int           `db:"id" json:"id"`
+	UserID             string        `db:"user_id" json:"user_id"`
+	RecipeID           int           `db:"recipe_id" json:"recipe_id"`
+	CurrentStep        int           `db:"current_step" json:"current_step"`
+	CheckedIngredients pq.Int64Array `db:"checked_ingredients" json:"checked_ingredients"`
+	Timers             CookTimers    `db:"timers" json:"timers"`
+	StartedAt          time.Time     `db:"started_at" json:"started_at"`
+	UpdatedAt          time.Time     `db:"updated_at" json:"updated_at"`
+	FinishedAt         *time.Time    `db:"finished_at" json:"finished_at"`
+}
+
+// CookTimer is a countdown started while cooking, optionally for one step
+type CookTimer struct {
+	Label           string    `json:"label"`
+	Step            *int      `json:"step,omitempty"`
+	DurationSeconds int       `json:"duration_seconds"`
+	StartedAt       time.Time `json:"started_at"`
+	EndsAt          time.Time `json:"ends_at"`
+}
+
+type CookTimers []CookTimer
+
+func (t *CookTimers) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, &t)
+}
+
+func (t CookTimers) Value() (driver.Value, error) {
+	if t == nil {
+		t = CookTimers{}
+	}
+	return json.Marshal(t)
+}
+
+// CookSessionUpdate changes a session. Fields left out are kept; lists
+// replace the session's lists.
+type CookSessionUpdate struct {
+	CurrentStep        *int        `json:"current_step"`
+	CheckedIngredients *[]int64    `json:"checked_ingredients"`
+	Timers             *CookTimers `json:"timers"`
+	Finished           bool        `json:"finished"`
+}
+
+// StartCookSession returns the user's unfinished session for the recipe,
+// or starts one at the first step. created is false when a session was
+// already under way, so a second device picks up where the first is.
+func StartCookSession(db *sqlx.DB, userID string, recipeID int) (session *CookSession, created bool, err error) {
+	var deletedAt *time.Time
+	if err := db.Get(&deletedAt, `SELECT deleted_at FROM recipes WHERE id=$1`, recipeID); err != nil {
+		return nil, false, err
+	}
+	if deletedAt != nil {
+		return nil, false, sql.ErrNoRows
+	}
+
+	session = &CookSession{}
+	err = db.Get(session, `SELECT * FROM cook_sessions WHERE user_id=$1 AND recipe_id=$2 AND finished_at IS NULL`, userID, recipeID)
+	if err == nil {
+		return session, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	err = db.Get(session, `INSERT INTO cook_sessions (user_id, recipe_id) VALUES ($1, $2) RETURNING *`, userID, recipeID)
+	if err != nil {
+		return nil, false, err
+	}
+	return session, true, nil
+}
+
+// GetCookSession loads one of the user's sessions
+func GetCookSession(db *sqlx.DB, userID string, id int) (*CookSession, error) {
+	session := CookSession{}
+	if err := db.Get(&session, `SELECT * FROM cook_sessions WHERE id=$1 AND user_id=$2`, id, userID); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpdateCookSession applies an update to one of the user's unfinished
+// sessions. The step must be one of the recipe's and checked ingredients
+// must be its lines.
+func UpdateCookSession(db *sqlx.DB, userID string, id int, update *CookSessionUpdate) (*CookSession, error) {
+	session, err := GetCookSession(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.FinishedAt != nil {
+		return nil, fmt.Errorf("%w: the session is finished", ErrInvalidCookSession)
+	}
+
+	if update.CurrentStep != nil {
+		var steps int
+		if err := db.Get(&steps, `SELECT COUNT(*) FROM recipe_steps WHERE recipe_id=$1`, session.RecipeID); err != nil {
+			return nil, err
+		}
+		if *update.CurrentStep < 1 || *update.CurrentStep > max(steps, 1) {
+			return nil, fmt.Errorf("%w: the recipe has %d steps", ErrInvalidCookSession, steps)
+		}
+		session.CurrentStep = *update.CurrentStep
+	}
+
+	if update.CheckedIngredients != nil {
+		ingredientIDs := []int64{}
+		if err := db.Select(&ingredientIDs, `SELECT id FROM recipe_ingredients WHERE recipe_id=$1`, session.RecipeID); err != nil {
+			return nil, err
+		}
+		checked := pq.Int64Array{}
+		for _, ingredientID := range *update.CheckedIngredients {
+			if !slices.Contains(ingredientIDs, ingredientID) {
+				return nil, fmt.Errorf("%w: ingredient %d isn't in the recipe", ErrInvalidCookSession, ingredientID)
+			}
+			if !slices.Contains(checked, ingredientID) {
+				checked = append(checked, ingredientID)
+			}
+		}
+		session.CheckedIngredients = checked
+	}
+
+	if update.Timers != nil {
+		timers := CookTimers{}
+		for _, timer := range *update.Timers {
+			timer.Label = strings.TrimSpace(timer.Label)
+			if timer.DurationSeconds <= 0 {
+				return nil, fmt.Errorf("%w: timers need a duration", ErrInvalidCookSession)
+			}
+			if timer.StartedAt.IsZero() {
+				timer.StartedAt = time.Now().UTC()
+			}
+			timer.EndsAt = timer.StartedAt.Add(time.Duration(timer.DurationSeconds) * time.Second)
+			timers = append(timers, timer)
+		}
+		session.Timers = timers
+	}
+
+	updated := CookSession{}
+	err = db.Get(&updated, `UPDATE cook_sessions SET current_step=$1, checked_ingredients=$2, timers=$3, updated_at=NOW(),
+			finished_at=CASE WHEN $4::boolean THEN NOW() END
+		WHERE id=$5 RETURNING *`,
+		session.CurrentStep, session.CheckedIngredients, session.Timers, update.Finished, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
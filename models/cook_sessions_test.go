@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cookSessionColumns = []string{"id", "user_id", "recipe_id", "current_step", "checked_ingredients", "timers", "started_at", "updated_at", "finished_at"}
+
+func TestStartCookSession(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT deleted_at FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(nil))
+	mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE user_id=\\$1 AND recipe_id=\\$2 AND finished_at IS NULL").
+		WithArgs("user-1", 1).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("INSERT INTO cook_sessions \\(user_id, recipe_id\\)").WithArgs("user-1", 1).
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "user-1", 1, 1, "{}", []byte(`[]`), now, now, nil))
+
+	session, created, err := StartCookSession(db, "user-1", 1)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 4, session.ID)
+	assert.Equal(t, 1, session.CurrentStep)
+
+	// A second device gets the same session
+	mock.ExpectQuery("SELECT deleted_at FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(nil))
+	mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE user_id=\\$1 AND recipe_id=\\$2 AND finished_at IS NULL").
+		WithArgs("user-1", 1).
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "user-1", 1, 3, "{10}", []byte(`[]`), now, now, nil))
+
+	session, created, err = StartCookSession(db, "user-1", 1)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 3, session.CurrentStep)
+	assert.Equal(t, []int64{10}, []int64(session.CheckedIngredients))
+
+	mock.ExpectQuery("SELECT deleted_at FROM recipes WHERE id=\\$1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(now))
+	_, _, err = StartCookSession(db, "user-1", 2)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCookSession(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	expectSession := func() {
+		mock.ExpectQuery("SELECT \\* FROM cook_sessions WHERE id=\\$1 AND user_id=\\$2").WithArgs(4, "user-1").
+			WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "user-1", 1, 1, "{}", []byte(`[]`), now, now, nil))
+	}
+	step := func(n int) *int { return &n }
+
+	expectSession()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM recipe_steps WHERE recipe_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	_, err := UpdateCookSession(db, "user-1", 4, &CookSessionUpdate{CurrentStep: step(4)})
+	assert.ErrorIs(t, err, ErrInvalidCookSession)
+
+	expectSession()
+	mock.ExpectQuery("SELECT id FROM recipe_ingredients WHERE recipe_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11))
+	_, err = UpdateCookSession(db, "user-1", 4, &CookSessionUpdate{CheckedIngredients: &[]int64{10, 99}})
+	assert.ErrorIs(t, err, ErrInvalidCookSession)
+
+	expectSession()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM recipe_steps WHERE recipe_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT id FROM recipe_ingredients WHERE recipe_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11))
+	mock.ExpectQuery("UPDATE cook_sessions SET current_step=\\$1").
+		WithArgs(2, sqlmock.AnyArg(), sqlmock.AnyArg(), false, 4).
+		WillReturnRows(sqlmock.NewRows(cookSessionColumns).AddRow(4, "user-1", 1, 2, "{11}", []byte(`[{"label":"Simmer","duration_seconds":600}]`), now, now, nil))
+
+	timers := CookTimers{{Label: " Simmer ", Step: step(2), DurationSeconds: 600}}
+	session, err := UpdateCookSession(db, "user-1", 4, &CookSessionUpdate{
+		CurrentStep:        step(2),
+		CheckedIngredients: &[]int64{11, 11},
+		Timers:             &timers,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, session.CurrentStep)
+	require.Len(t, session.Timers, 1)
+	assert.Equal(t, "Simmer", session.Timers[0].Label)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCookTimersValue(t *testing.T) {
+	start := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	value, err := CookTimers{{Label: "Rest", DurationSeconds: 300, StartedAt: start, EndsAt: start.Add(5 * time.Minute)}}.Value()
+	require.NoError(t, err)
+
+	var timers CookTimers
+	require.NoError(t, timers.Scan(value))
+	assert.Equal(t, "Rest", timers[0].Label)
+	assert.True(t, timers[0].EndsAt.Equal(start.Add(5*time.Minute)))
+
+	value, err = CookTimers(nil).Value()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("[]"), value)
+}
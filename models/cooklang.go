@@ -0,0 +1,295 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var cooklangIngredient = regexp.MustCompile(`@([^@#~{}\s][^@#~{}\n]*?)\{([^}]*)\}|@([^\s@#~{}.,;:!?()]+)`)
+var cooklangCookware = regexp.MustCompile(`#([^@#~{}\s][^@#~{}\n]*?)\{[^}]*\}|#([^\s@#~{}.,;:!?()]+)`)
+var cooklangTimer = regexp.MustCompile(`~[^@#~{}\s]*\{([^}]*)\}`)
+var cooklangBlockComment = regexp.MustCompile(`(?s)\[-.*?-\]`)
+
+// DecodeCooklang reads a single .cook file, or a zip archive of them
+func DecodeCooklang(data []byte) ([]ImportedRecipe, error) {
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		return []ImportedRecipe{{Recipe: parseCooklang(string(data), "")}}, nil
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	recipes := []ImportedRecipe{}
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".cook") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := readRecipeEntry(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		title := strings.TrimSuffix(path.Base(f.Name), ".cook")
+		recipes = append(recipes, ImportedRecipe{Recipe: parseCooklang(string(b), title)})
+	}
+	return recipes, nil
+}
+
+// parseCooklang converts Cooklang source into a recipe. Metadata may be given
+// as YAML front matter or as ">> key: value" lines. fallbackTitle is used when
+// the file doesn't name the recipe itself.
+func parseCooklang(source string, fallbackTitle string) Recipe {
+	recipe := newImportedRecipe()
+	recipe.Name = fallbackTitle
+
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = cooklangBlockComment.ReplaceAllString(source, "")
+
+	metadata := map[string]string{}
+	var tagList []string
+	if strings.HasPrefix(source, "---\n") {
+		if end := strings.Index(source[4:], "\n---"); end >= 0 {
+			tagList = parseFrontMatter(source[4:4+end], metadata)
+			source = source[4+end+4:]
+		}
+	}
+
+	// Steps are paragraphs; a "== Name ==" line starts a section
+	type paragraph struct {
+		section string
+		lines   []string
+	}
+	paragraphs := []paragraph{{}}
+	for _, line := range strings.Split(source, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">>") {
+			if key, value, ok := strings.Cut(strings.TrimPrefix(trimmed, ">>"), ":"); ok {
+				metadata[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			}
+			continue
+		}
+		section := paragraphs[len(paragraphs)-1].section
+		if strings.HasPrefix(trimmed, "=") {
+			paragraphs = append(paragraphs, paragraph{section: cleanSection(strings.Trim(trimmed, "= "))})
+			continue
+		}
+		if trimmed == "" {
+			paragraphs = append(paragraphs, paragraph{section: section})
+			continue
+		}
+		paragraphs[len(paragraphs)-1].lines = append(paragraphs[len(paragraphs)-1].lines, trimmed)
+	}
+
+	seen := map[string]bool{}
+	for _, paragraph := range paragraphs {
+		if len(paragraph.lines) == 0 {
+			continue
+		}
+		text := strings.Join(paragraph.lines, " ")
+
+		for _, m := range cooklangIngredient.FindAllStringSubmatch(text, -1) {
+			name, quantity := strings.TrimSpace(m[1]), m[2]
+			if name == "" {
+				name = m[3]
+			}
+			ingredient := RecipeIngredient{Name: name, Amount: cooklangAmount(quantity), Section: paragraph.section}
+			if key := ingredient.Name + "|" + ingredient.Amount; !seen[key] {
+				seen[key] = true
+				recipe.Ingredients = append(recipe.Ingredients, ingredient)
+			}
+		}
+
+		text = cooklangIngredient.ReplaceAllStringFunc(text, func(s string) string {
+			m := cooklangIngredient.FindStringSubmatch(s)
+			return strings.TrimSpace(m[1] + m[3])
+		})
+		text = cooklangCookware.ReplaceAllStringFunc(text, func(s string) string {
+			m := cooklangCookware.FindStringSubmatch(s)
+			return strings.TrimSpace(m[1] + m[2])
+		})
+		text = cooklangTimer.ReplaceAllStringFunc(text, func(s string) string {
+			return cooklangAmount(cooklangTimer.FindStringSubmatch(s)[1])
+		})
+
+		recipe.Steps = append(recipe.Steps, RecipeStep{Order: len(recipe.Steps) + 1, Text: strings.Join(strings.Fields(text), " "), Section: paragraph.section})
+	}
+
+	if title := metadata["title"]; title != "" {
+		recipe.Name = title
+	}
+	recipe.Description = metadata["description"]
+	recipe.Image = NullStringWrapper(metadata["image"])
+	if tags, ok := metadata["tags"]; ok {
+		tagList = append(tagList, strings.Split(strings.Trim(tags, "[]"), ",")...)
+	}
+	recipe.Tags = normalizeTags(tagList)
+
+	return recipe
+}
+
+// parseFrontMatter understands the small subset of YAML that Cooklang files
+// use in practice: "key: value" pairs and a block list of tags.
+func parseFrontMatter(block string, metadata map[string]string) []string {
+	tags := []string{}
+	currentKey := ""
+	for _, line := range strings.Split(block, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") && currentKey == "tags" {
+			tags = append(tags, yamlScalar(strings.TrimPrefix(trimmed, "- ")))
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		currentKey = strings.ToLower(strings.TrimSpace(key))
+		if value = strings.TrimSpace(value); value != "" {
+			metadata[currentKey] = yamlScalar(value)
+		}
+	}
+	return tags
+}
+
+func yamlScalar(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	return strings.Trim(s, `'`)
+}
+
+// cooklangAmount turns "2%cups" into "2 cups"
+func cooklangAmount(quantity string) string {
+	qty, unit, _ := strings.Cut(quantity, "%")
+	return strings.TrimSpace(strings.TrimSpace(qty) + " " + strings.TrimSpace(unit))
+}
+
+// cooklangQuantity turns "2 cups" into "2%cups"
+func cooklangQuantity(amount string) string {
+	words := strings.Fields(amount)
+	i := 0
+	for i < len(words) && quantityToken.MatchString(words[i]) {
+		i++
+	}
+	if i == 0 || i == len(words) {
+		return amount
+	}
+	return strings.Join(words[:i], " ") + "%" + strings.Join(words[i:], " ")
+}
+
+// EncodeCooklang writes a single recipe as a .cook file, or several as a zip
+// of .cook files.
+func EncodeCooklang(w io.Writer, recipes []Recipe) error {
+	if len(recipes) == 1 {
+		_, err := io.WriteString(w, formatCooklang(recipes[0]))
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for i, recipe := range recipes {
+		name := recipe.Slug
+		if name == "" {
+			name = fmt.Sprintf("recipe-%d", i+1)
+		}
+		f, err := zw.Create(name + ".cook")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, formatCooklang(recipe)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// formatCooklang annotates the first mention of each ingredient in the steps.
+// Ingredients never mentioned in a step are listed in an extra first step so
+// nothing is lost.
+func formatCooklang(recipe Recipe) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", strconv.Quote(recipe.Name))
+	if recipe.Description != "" {
+		fmt.Fprintf(&b, "description: %s\n", strconv.Quote(recipe.Description))
+	}
+	if recipe.Image.String != "" {
+		fmt.Fprintf(&b, "image: %s\n", strconv.Quote(recipe.Image.String))
+	}
+	if len(recipe.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range recipe.Tags {
+			fmt.Fprintf(&b, "  - %s\n", tag)
+		}
+	}
+	b.WriteString("---\n")
+
+	steps := make([]string, len(recipe.Steps))
+	sections := make([]string, len(recipe.Steps))
+	for i, step := range recipe.Steps {
+		steps[i] = step.Text
+		sections[i] = step.Section
+	}
+
+	// Place longer names first so "salt" doesn't claim the mention of "kosher salt"
+	ingredients := slices.Clone(recipe.Ingredients)
+	slices.SortStableFunc(ingredients, func(a, b RecipeIngredient) int {
+		return len(b.Name) - len(a.Name)
+	})
+
+	unplaced := []string{}
+	for _, ingredient := range ingredients {
+		token := "@" + ingredient.Name + "{" + cooklangQuantity(ingredient.Amount) + "}"
+		placed := false
+		if ingredient.Name == "" {
+			continue
+		}
+		mention := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(ingredient.Name) + `\b`)
+		for i, step := range steps {
+			// Blank out ingredients that are already annotated so "salt" doesn't
+			// match inside "@kosher salt{}"
+			masked := cooklangIngredient.ReplaceAllStringFunc(step, func(s string) string {
+				return strings.Repeat("\x00", len(s))
+			})
+			if loc := mention.FindStringIndex(masked); loc != nil {
+				steps[i] = step[:loc[0]] + token + step[loc[1]:]
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			unplaced = append(unplaced, token)
+		}
+	}
+	if len(unplaced) > 0 {
+		steps = append([]string{"Ingredients: " + strings.Join(unplaced, ", ") + "."}, steps...)
+		sections = append([]string{""}, sections...)
+	}
+
+	current := ""
+	for i, step := range steps {
+		if sections[i] != current {
+			current = sections[i]
+			fmt.Fprintf(&b, "\n== %s ==\n", current)
+		}
+		b.WriteString("\n" + step + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCooklang(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.cook")
+	require.NoError(t, err)
+
+	recipes, err := DecodeCooklang(data)
+	require.NoError(t, err)
+	require.Len(t, recipes, 1)
+
+	recipe := recipes[0].Recipe
+	assert.Equal(t, "Easy Pancakes", recipe.Name)
+	assert.Equal(t, "Sunday morning staple.", recipe.Description)
+	assert.Equal(t, []string{"breakfast", "sweet"}, recipe.Tags)
+	assert.Equal(t, []RecipeIngredient{
+		{Name: "eggs", Amount: "3"},
+		{Name: "flour", Amount: "125 g"},
+		{Name: "milk", Amount: "250 ml"},
+		{Name: "sea salt", Amount: ""},
+		{Name: "maple syrup", Amount: ""},
+	}, recipe.Ingredients)
+	require.Len(t, recipe.Steps, 3)
+	assert.Equal(t, "Crack the eggs into a mixing bowl, then add the flour, milk and a pinch of sea salt.", recipe.Steps[0].Text)
+	assert.Equal(t, "Pour 40 ml of batter into a hot frying pan and cook for 2 minutes per side.", recipe.Steps[1].Text)
+	assert.Equal(t, "Serve with maple syrup.", recipe.Steps[2].Text)
+}
+
+func TestCooklangRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.cook")
+	require.NoError(t, err)
+	original, err := DecodeCooklang(data)
+	require.NoError(t, err)
+	recipe := original[0].Recipe
+	recipe.Image = NullStringWrapper("https://example.com/pancakes.jpg")
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeCooklang(&b, []Recipe{recipe}))
+	assert.Contains(t, b.String(), "@flour{125%g}")
+	assert.Contains(t, b.String(), "@sea salt{}")
+
+	decoded, err := DecodeCooklang(b.Bytes())
+	require.NoError(t, err)
+	require.Len(t, decoded, 1)
+	assert.Equal(t, recipe, decoded[0].Recipe)
+}
+
+func TestCooklangUnmentionedIngredients(t *testing.T) {
+	recipe := Recipe{
+		Name:        "Toast",
+		Ingredients: []RecipeIngredient{{Name: "bread", Amount: "2 slices"}, {Name: "salt", Amount: ""}, {Name: "kosher salt", Amount: "1 tsp"}},
+		Steps:       []RecipeStep{{Order: 1, Text: "Toast the bread and sprinkle with kosher salt."}},
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeCooklang(&b, []Recipe{recipe}))
+	assert.Contains(t, b.String(), "Ingredients: @salt{}.")
+	assert.Contains(t, b.String(), "Toast the @bread{2%slices} and sprinkle with @kosher salt{1%tsp}.")
+}
+
+func TestCooklangArchive(t *testing.T) {
+	recipes := []Recipe{
+		{Name: "One", Slug: "one", Steps: []RecipeStep{{Order: 1, Text: "Boil water."}}},
+		{Name: "Two", Slug: "two", Steps: []RecipeStep{{Order: 1, Text: "Eat."}}},
+	}
+	var b bytes.Buffer
+	require.NoError(t, EncodeCooklang(&b, recipes))
+
+	decoded, err := DecodeRecipes(RecipeFormatCooklang, b.Bytes())
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "Two", decoded[1].Recipe.Name)
+
+	_, err = DecodeRecipes("evernote", b.Bytes())
+	assert.Equal(t, ErrUnknownRecipeFormat, err)
+}
+
+func TestCooklangArchiveBoundsEntries(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, err := zw.Create("huge.cook")
+	require.NoError(t, err)
+	_, err = f.Write(bytes.Repeat([]byte("a"), MaxRecipeFileSize+1))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	_, err = DecodeCooklang(archive.Bytes())
+	assert.ErrorIs(t, err, ErrRecipeFileTooLarge)
+}
@@ -0,0 +1,325 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrUnknownDiet = errors.New("unknown diet")
+var ErrNotHouseholdMember = errors.New("user is not a member of this household")
+
+// Diets maps each supported diet to the ingredient categories it excludes
+var Diets = map[string][]string{
+	"vegetarian":  {"meat", "fish", "shellfish"},
+	"pescatarian": {"meat"},
+	"vegan":       {"meat", "fish", "shellfish", "dairy", "egg", "honey"},
+	"gluten-free": {"gluten"},
+	"dairy-free":  {"dairy"},
+}
+
+// DietaryProfile records what a household member can't or won't eat.
+// Allergens are ingredient categories such as "peanut" or "tree_nut";
+// excluded ingredients are matched by name.
+type DietaryProfile struct {
+	UserID              string         `db:"user_id" json:"user_id"`
+	HouseholdID         int            `db:"household_id" json:"household_id"`
+	Allergens           pq.StringArray `db:"allergens" json:"allergens"`
+	ExcludedIngredients pq.StringArray `db:"excluded_ingredients" json:"excluded_ingredients"`
+	Diets               pq.StringArray `db:"diets" json:"diets"`
+}
+
+// IngredientCategories is one ingredient catalog entry
+type IngredientCategories struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`
+
+	// pattern is wholeWord(Name), compiled when the catalog is loaded
+	pattern *regexp.Regexp
+}
+
+// Kinds of DietaryWarning
+const (
+	WarningAllergen = "allergen"
+	WarningDiet     = "diet"
+	WarningExcluded = "excluded"
+)
+
+// DietaryWarning explains why an ingredient conflicts with a member's profile.
+// Detail is the allergen, diet or excluded ingredient involved.
+type DietaryWarning struct {
+	UserID     string `json:"user_id"`
+	MealID     int    `json:"meal_id,omitempty"`
+	Ingredient string `json:"ingredient"`
+	Kind       string `json:"kind"`
+	Detail     string `json:"detail"`
+}
+
+// normalizeCategory lowercases a category and joins words with underscores,
+// so "Tree nut" and "tree_nut" are the same allergen.
+func normalizeCategory(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(s, "_", " "))), "_")
+}
+
+func normalizeList(values []string, normalize func(string) string) pq.StringArray {
+	out := pq.StringArray{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		v = normalize(v)
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// GetDietaryProfiles returns the profiles of a household's members
+func GetDietaryProfiles(db *sqlx.DB, householdID int) ([]DietaryProfile, error) {
+	profiles := []DietaryProfile{}
+	err := db.Select(&profiles, `SELECT p.* FROM dietary_profiles p
+		JOIN household_members m ON m.user_id = p.user_id AND m.household_id = p.household_id
+		WHERE p.household_id=$1 ORDER BY p.user_id`, householdID)
+	return profiles, err
+}
+
+// SaveDietaryProfile creates or replaces a member's profile
+func SaveDietaryProfile(db *sqlx.DB, p *DietaryProfile) (*DietaryProfile, error) {
+	p.Allergens = normalizeList(p.Allergens, normalizeCategory)
+	p.ExcludedIngredients = normalizeList(p.ExcludedIngredients, normalizeName)
+	p.Diets = normalizeList(p.Diets, normalizeName)
+	for _, diet := range p.Diets {
+		if _, ok := Diets[diet]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownDiet, diet)
+		}
+	}
+
+	var member bool
+	err := db.Get(&member, `SELECT EXISTS (SELECT 1 FROM household_members WHERE household_id=$1 AND user_id=$2)`, p.HouseholdID, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrNotHouseholdMember
+	}
+
+	_, err = db.NamedExec(`INSERT INTO dietary_profiles (user_id, household_id, allergens, excluded_ingredients, diets)
+		VALUES (:user_id, :household_id, :allergens, :excluded_ingredients, :diets)
+		ON CONFLICT (user_id) DO UPDATE SET household_id=EXCLUDED.household_id, allergens=EXCLUDED.allergens,
+			excluded_ingredients=EXCLUDED.excluded_ingredients, diets=EXCLUDED.diets`, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetIngredientCatalog returns every catalogued ingredient with its categories
+func GetIngredientCatalog(db *sqlx.DB) ([]IngredientCategories, error) {
+	rows := []struct {
+		Name     string `db:"name"`
+		Category string `db:"category"`
+	}{}
+	err := db.Select(&rows, `SELECT name, category FROM ingredient_categories ORDER BY name, category`)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := []IngredientCategories{}
+	for _, row := range rows {
+		if n := len(catalog); n > 0 && catalog[n-1].Name == row.Name {
+			catalog[n-1].Categories = append(catalog[n-1].Categories, row.Category)
+			continue
+		}
+		catalog = append(catalog, IngredientCategories{Name: row.Name, Categories: []string{row.Category}, pattern: wholeWord(row.Name)})
+	}
+	return catalog, nil
+}
+
+// SaveIngredientCategories replaces the categories of the given ingredients.
+// An entry with no categories removes the ingredient from the catalog.
+func SaveIngredientCategories(db *sqlx.DB, entries []IngredientCategories) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := normalizeName(entry.Name)
+		if name == "" {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM ingredient_categories WHERE name=$1`, name); err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, category := range normalizeList(entry.Categories, normalizeCategory) {
+			if _, err := tx.Exec(`INSERT INTO ingredient_categories (name, category) VALUES ($1, $2)`, name, category); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// categorizeIngredient returns the categories of every catalog entry found in
+// name. Longer entries are matched first and their words masked, so "peanut
+// butter" counts as peanut but not as butter.
+func categorizeIngredient(name string, catalog []IngredientCategories) []string {
+	name = normalizeName(name)
+	entries := make([]IngredientCategories, len(catalog))
+	copy(entries, catalog)
+	sort.SliceStable(entries, func(i, j int) bool { return len(entries[i].Name) > len(entries[j].Name) })
+
+	categories := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		mention := entry.pattern
+		if mention == nil {
+			mention = wholeWord(entry.Name)
+		}
+		if loc := mention.FindStringIndex(name); loc != nil {
+			name = name[:loc[0]] + strings.Repeat(" ", loc[1]-loc[0]) + name[loc[1]:]
+			for _, c := range entry.Categories {
+				if !seen[c] {
+					seen[c] = true
+					categories = append(categories, c)
+				}
+			}
+		}
+	}
+	return categories
+}
+
+// wholeWord matches term as whole words, allowing a plural ending
+func wholeWord(term string) *regexp.Regexp {
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(term) + `(e?s)?\b`)
+}
+
+// CheckIngredients compares ingredient names with each profile and returns a
+// warning for every conflict.
+func CheckIngredients(profiles []DietaryProfile, catalog []IngredientCategories, ingredients []string) []DietaryWarning {
+	warnings := []DietaryWarning{}
+	excludedPatterns := map[string]*regexp.Regexp{}
+	for _, p := range profiles {
+		for _, excluded := range p.ExcludedIngredients {
+			if excludedPatterns[excluded] == nil {
+				excludedPatterns[excluded] = wholeWord(excluded)
+			}
+		}
+	}
+	for _, ingredient := range ingredients {
+		if strings.TrimSpace(ingredient) == "" {
+			continue
+		}
+		categories := categorizeIngredient(ingredient, catalog)
+		has := map[string]bool{}
+		for _, c := range categories {
+			has[c] = true
+		}
+
+		for _, p := range profiles {
+			for _, allergen := range p.Allergens {
+				if has[allergen] {
+					warnings = append(warnings, DietaryWarning{UserID: p.UserID, Ingredient: ingredient, Kind: WarningAllergen, Detail: allergen})
+				}
+			}
+			for _, diet := range p.Diets {
+				for _, c := range Diets[diet] {
+					if has[c] {
+						warnings = append(warnings, DietaryWarning{UserID: p.UserID, Ingredient: ingredient, Kind: WarningDiet, Detail: diet})
+						break
+					}
+				}
+			}
+			for _, excluded := range p.ExcludedIngredients {
+				if excludedPatterns[excluded].MatchString(normalizeName(ingredient)) {
+					warnings = append(warnings, DietaryWarning{UserID: p.UserID, Ingredient: ingredient, Kind: WarningExcluded, Detail: excluded})
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// mealIngredientNames lists a meal's own ingredients and the raw ingredients
+// of its recipes
+func mealIngredientNames(db *sqlx.DB, meal *Meal) ([]string, error) {
+	names := []string{}
+	for _, ingredient := range meal.Ingredients {
+		names = append(names, ingredient.Name)
+	}
+	recipes, err := activeRecipes(db, meal)
+	if err != nil {
+		return nil, err
+	}
+	for _, recipe := range recipes {
+		for _, ingredient := range recipe.RawIngredients() {
+			names = append(names, ingredient.Name)
+		}
+	}
+	return names, nil
+}
+
+// MealWarnings checks a loaded meal against a household's profiles
+func MealWarnings(db *sqlx.DB, householdID int, meal *Meal) ([]DietaryWarning, error) {
+	profiles, err := GetDietaryProfiles(db, householdID)
+	if err != nil || len(profiles) == 0 {
+		return []DietaryWarning{}, err
+	}
+	catalog, err := GetIngredientCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+	names, err := mealIngredientNames(db, meal)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := CheckIngredients(profiles, catalog, names)
+	for i := range warnings {
+		warnings[i].MealID = meal.ID
+	}
+	return warnings, nil
+}
+
+// PlanWarnings checks every meal in a plan against a household's profiles
+func PlanWarnings(db *sqlx.DB, householdID int, mealIDs []int) ([]DietaryWarning, error) {
+	warnings := []DietaryWarning{}
+	profiles, err := GetDietaryProfiles(db, householdID)
+	if err != nil || len(profiles) == 0 {
+		return warnings, err
+	}
+	catalog, err := GetIngredientCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mealID := range mealIDs {
+		meal, err := GetMeal(db, mealID)
+		if err != nil {
+			return nil, err
+		}
+		if meal.DeletedAt != nil {
+			continue
+		}
+		names, err := mealIngredientNames(db, meal)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range CheckIngredients(profiles, catalog, names) {
+			w.MealID = mealID
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings, nil
+}
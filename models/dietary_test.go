@@ -0,0 +1,137 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCatalog = []IngredientCategories{
+	{Name: "butter", Categories: []string{"dairy"}},
+	{Name: "peanut butter", Categories: []string{"peanut"}},
+	{Name: "egg", Categories: []string{"egg"}},
+	{Name: "chicken", Categories: []string{"meat"}},
+	{Name: "flour", Categories: []string{"gluten"}},
+}
+
+func TestCategorizeIngredientByCatalog(t *testing.T) {
+	assert.Equal(t, []string{"peanut"}, categorizeIngredient("Crunchy Peanut Butter", testCatalog))
+	assert.Equal(t, []string{"dairy"}, categorizeIngredient("unsalted butter", testCatalog))
+	assert.Equal(t, []string{"egg"}, categorizeIngredient("2 large eggs", testCatalog))
+	assert.ElementsMatch(t, []string{"meat", "gluten"}, categorizeIngredient("chicken thighs, dredged in flour", testCatalog))
+	assert.Empty(t, categorizeIngredient("eggplant", testCatalog))
+}
+
+func TestCheckIngredients(t *testing.T) {
+	profiles := []DietaryProfile{
+		{UserID: "alice", Allergens: pq.StringArray{"peanut"}},
+		{UserID: "bob", Diets: pq.StringArray{"vegetarian"}, ExcludedIngredients: pq.StringArray{"cilantro"}},
+	}
+
+	warnings := CheckIngredients(profiles, testCatalog, []string{"peanut butter", "chicken breast", "fresh cilantro", "rice", ""})
+	assert.Equal(t, []DietaryWarning{
+		{UserID: "alice", Ingredient: "peanut butter", Kind: WarningAllergen, Detail: "peanut"},
+		{UserID: "bob", Ingredient: "chicken breast", Kind: WarningDiet, Detail: "vegetarian"},
+		{UserID: "bob", Ingredient: "fresh cilantro", Kind: WarningExcluded, Detail: "cilantro"},
+	}, warnings)
+
+	assert.Empty(t, CheckIngredients(profiles, testCatalog, []string{"butter", "rice"}))
+}
+
+func TestSaveDietaryProfile(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	t.Run("normalizes and saves", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(42, "user1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO dietary_profiles").
+			WithArgs("user1", 42, pq.StringArray{"tree_nut"}, pq.StringArray{"cilantro"}, pq.StringArray{"vegan"}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		profile, err := SaveDietaryProfile(db, &DietaryProfile{
+			UserID:              "user1",
+			HouseholdID:         42,
+			Allergens:           pq.StringArray{"Tree nut", "tree_nut"},
+			ExcludedIngredients: pq.StringArray{" Cilantro "},
+			Diets:               pq.StringArray{"Vegan"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, pq.StringArray{"tree_nut"}, profile.Allergens)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown diet", func(t *testing.T) {
+		_, err := SaveDietaryProfile(db, &DietaryProfile{UserID: "user1", HouseholdID: 42, Diets: pq.StringArray{"carnivore"}})
+		assert.ErrorIs(t, err, ErrUnknownDiet)
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(42, "stranger").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := SaveDietaryProfile(db, &DietaryProfile{UserID: "stranger", HouseholdID: 42})
+		assert.Equal(t, ErrNotHouseholdMember, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetIngredientCatalog(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT name, category FROM ingredient_categories").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "category"}).
+			AddRow("milk", "dairy").
+			AddRow("pesto", "dairy").
+			AddRow("pesto", "tree_nut"))
+
+	catalog, err := GetIngredientCatalog(db)
+	require.NoError(t, err)
+	require.Len(t, catalog, 2)
+	assert.Equal(t, "milk", catalog[0].Name)
+	assert.Equal(t, []string{"dairy"}, catalog[0].Categories)
+	assert.Equal(t, "pesto", catalog[1].Name)
+	assert.Equal(t, []string{"dairy", "tree_nut"}, catalog[1].Categories)
+	// The patterns are compiled once, when the catalog is loaded
+	require.NotNil(t, catalog[1].pattern)
+	assert.True(t, catalog[1].pattern.MatchString("basil pesto"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMealIngredientNamesExpandsSubRecipes(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	columns := []string{"id", "recipe_id", "name", "amount", "calories", "sub_recipe_id", "quantity"}
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug"}).AddRow(1, "Satay", "Skewers", "satay"))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(10, 1, "chicken", "1 lb", nil, nil, nil).
+			AddRow(11, 1, "satay sauce", "", nil, 2, nil))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug"}).AddRow(2, "Satay Sauce", "Sauce", "satay-sauce"))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(20, 2, "peanut butter", "1/2 cup", nil, nil, nil))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	// The peanut butter only appears in the sub-recipe
+	names, err := mealIngredientNames(db, &Meal{
+		Ingredients: []MealIngredient{{Name: "rice", Amount: "1 cup"}},
+		MealRecipes: []MealRecipes{{RecipeID: 1}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rice", "chicken", "peanut butter"}, names)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// equipmentAliases maps other names for a piece of equipment to the one
+// stored, so "Crock-Pot" on a recipe matches "slow cooker" in a kitchen
+var equipmentAliases = map[string]string{
+	"crock pot":                "slow cooker",
+	"crockpot":                 "slow cooker",
+	"instapot":                 "instant pot",
+	"electric pressure cooker": "instant pot",
+	"kitchenaid":               "stand mixer",
+	"kitchenaid mixer":         "stand mixer",
+	"hand blender":             "immersion blender",
+	"stick blender":            "immersion blender",
+	"hand mixer":               "electric mixer",
+	"sheet pan":                "baking sheet",
+	"cookie sheet":             "baking sheet",
+	"airfryer":                 "air fryer",
+	"bbq":                      "grill",
+	"barbecue":                 "grill",
+}
+
+// normalizeEquipmentName lowercases a name, treats hyphens as spaces and
+// applies equipmentAliases
+func normalizeEquipmentName(s string) string {
+	name := normalizeName(strings.ReplaceAll(s, "-", " "))
+	if alias, ok := equipmentAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// NormalizeEquipment cleans up an equipment list, dropping blanks and
+// duplicates
+func NormalizeEquipment(equipment []string) pq.StringArray {
+	return normalizeList(equipment, normalizeEquipmentName)
+}
+
+// MissingEquipment lists the required equipment that isn't owned
+func MissingEquipment(required []string, owned []string) []string {
+	have := map[string]bool{}
+	for _, item := range owned {
+		have[item] = true
+	}
+	missing := []string{}
+	for _, item := range required {
+		if !have[item] {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
+
+// GetHouseholdEquipment returns the equipment the household has said it
+// owns. An empty list means it hasn't said, not that it owns nothing.
+func GetHouseholdEquipment(db *sqlx.DB, householdID int) (pq.StringArray, error) {
+	equipment := pq.StringArray{}
+	err := db.Get(&equipment, `SELECT equipment FROM households WHERE id=$1`, householdID)
+	return equipment, err
+}
+
+// SetHouseholdEquipment replaces the household's equipment
+func SetHouseholdEquipment(db *sqlx.DB, householdID int, equipment []string) (pq.StringArray, error) {
+	normalized := NormalizeEquipment(equipment)
+	_, err := db.Exec(`UPDATE households SET equipment=$1 WHERE id=$2`, normalized, householdID)
+	if err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// GetMealEquipment returns the equipment each meal needs: its own and that
+// of its recipes
+func GetMealEquipment(db *sqlx.DB) (map[int][]string, error) {
+	rows := []struct {
+		MealID    int            `db:"meal_id"`
+		Equipment pq.StringArray `db:"equipment"`
+	}{}
+	err := db.Select(&rows, `SELECT id AS meal_id, equipment FROM meals
+		UNION ALL
+		SELECT mr.meal_id, r.equipment FROM meal_recipes mr JOIN recipes r ON r.id = mr.recipe_id`)
+	if err != nil {
+		return nil, err
+	}
+	equipment := map[int][]string{}
+	for _, row := range rows {
+		for _, item := range row.Equipment {
+			if !slices.Contains(equipment[row.MealID], item) {
+				equipment[row.MealID] = append(equipment[row.MealID], item)
+			}
+		}
+	}
+	return equipment, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEquipment(t *testing.T) {
+	equipment := NormalizeEquipment([]string{" Crock-Pot ", "slow cooker", "Stand  Mixer", "", "KitchenAid"})
+	assert.Equal(t, []string{"slow cooker", "stand mixer"}, []string(equipment))
+	assert.NotNil(t, NormalizeEquipment(nil))
+}
+
+func TestMissingEquipment(t *testing.T) {
+	assert.Equal(t, []string{"instant pot"}, MissingEquipment([]string{"oven", "instant pot"}, []string{"oven", "grill"}))
+	assert.Empty(t, MissingEquipment(nil, []string{"oven"}))
+}
+
+func TestGetMealEquipment(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id AS meal_id, equipment FROM meals").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "equipment"}).
+			AddRow(1, "{oven}").
+			AddRow(2, "{}").
+			AddRow(1, "{oven,\"stand mixer\"}"))
+
+	equipment, err := GetMealEquipment(db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"oven", "stand mixer"}, equipment[1])
+	assert.Empty(t, equipment[2])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGeneratePlanEquipment(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Now().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	mock.ExpectQuery("SELECT id, name FROM meals WHERE deleted_at IS NULL ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Pot roast").AddRow(2, "Stir fry"))
+	mock.ExpectQuery("SELECT mt.meal_id, t.name FROM meal_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}))
+	mock.ExpectQuery("SELECT meal_id, name FROM meal_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}))
+	mock.ExpectQuery("SELECT p.\\* FROM dietary_profiles").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "allergens", "excluded_ingredients", "diets"}))
+	mock.ExpectQuery("SELECT equipment FROM households WHERE id=\\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"equipment"}).AddRow("{wok,oven}"))
+	mock.ExpectQuery("SELECT id AS meal_id, equipment FROM meals").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "equipment"}).
+			AddRow(1, "{\"slow cooker\"}").
+			AddRow(2, "{wok}"))
+
+	generated, err := GeneratePlan(db, 42, &PlanGenerationRequest{
+		StartDate: Date{Time: start},
+		EndDate:   Date{Time: start.AddDate(0, 0, 1)},
+		Seed:      3,
+	})
+	require.NoError(t, err)
+	// The household has no slow cooker, so only the stir fry can be picked
+	assert.Equal(t, []int{2, 2}, generated.Plan.Meals)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
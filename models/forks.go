@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNotForked = errors.New("recipe is not a fork")
+
+// Upstream describes how a fork's parent has changed since it was forked.
+// Changes compares the parent as it was forked with the parent now, and
+// Revisions lists each update made to the parent since.
+type Upstream struct {
+	Parent         *Recipe    `json:"parent"`
+	ForkedRevision int        `json:"forked_revision"`
+	Changes        []Change   `json:"changes"`
+	Revisions      []Revision `json:"revisions"`
+}
+
+// ForkRecipe copies a recipe, with its ingredients, steps and tags, into a new
+// recipe owned by the household.
+func ForkRecipe(db *sqlx.DB, id int, householdID int) (*Recipe, error) {
+	parent, err := GetRecipe(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if parent.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	var revision int
+	err = db.Get(&revision, `SELECT COALESCE(MAX(revision), 0) FROM revisions WHERE recipe_id=$1`, id)
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	fork, err := CreateRecipe(db, parent)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`UPDATE recipes SET forked_from=$1, forked_revision=$2, household_id=$3 WHERE id=$4`,
+		id, revision, householdID, fork.ID)
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	return GetRecipe(db, fork.ID)
+}
+
+// GetRecipeOwner returns the household that owns a fork, or nil for a shared
+// recipe anyone can edit.
+func GetRecipeOwner(db *sqlx.DB, id int) (*int, error) {
+	var owner *int
+	err := db.Get(&owner, `SELECT household_id FROM recipes WHERE id=$1`, id)
+	return owner, err
+}
+
+// GetRecipeUpstream lists the changes made to a fork's parent since the fork
+func GetRecipeUpstream(db *sqlx.DB, id int) (*Upstream, error) {
+	fork, err := GetRecipe(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if fork.ForkedFrom == nil {
+		return nil, ErrNotForked
+	}
+
+	upstream := &Upstream{Changes: []Change{}, Revisions: []Revision{}}
+	if fork.ForkedRevision != nil {
+		upstream.ForkedRevision = *fork.ForkedRevision
+	}
+	upstream.Parent, err = GetRecipe(db, *fork.ForkedFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := GetRecipeRevisions(db, *fork.ForkedFrom)
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		if revision.Revision > upstream.ForkedRevision {
+			upstream.Revisions = append(upstream.Revisions, revision)
+		}
+	}
+	if len(upstream.Revisions) == 0 {
+		return upstream, nil
+	}
+
+	// The oldest revision since the fork is the parent as it was forked
+	forked := Recipe{}
+	if err := json.Unmarshal(upstream.Revisions[len(upstream.Revisions)-1].Snapshot, &forked); err != nil {
+		return nil, err
+	}
+	upstream.Changes = diffVersions(recipeVersion(&forked), recipeVersion(upstream.Parent))
+	return upstream, nil
+}
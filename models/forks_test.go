@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expectRecipe(mock sqlmock.Sqlmock, rows *sqlmock.Rows, id int) {
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(id).WillReturnRows(rows)
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients").WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "name", "amount", "calories"}))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps").WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+}
+
+func TestGetRecipeUpstream(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	columns := []string{"id", "name", "description", "slug", "forked_from", "forked_revision", "household_id"}
+	parent := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).AddRow(1, "Beef stew", "Hearty", "stew", nil, nil, nil)
+	}
+	expectRecipe(mock, sqlmock.NewRows(columns).AddRow(5, "Stew", "Hearty", "stew-1", 1, 2, 42), 5)
+	expectRecipe(mock, parent(), 1)
+	expectRecipe(mock, parent(), 1)
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM revisions WHERE recipe_id=\\$1 ORDER BY revision DESC").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "revision", "snapshot", "created_at"}).
+			AddRow(9, 1, 3, []byte(`{"name":"Stew","description":"Hearty"}`), created).
+			AddRow(7, 1, 2, []byte(`{"name":"Stew","description":"Thin"}`), created))
+
+	upstream, err := GetRecipeUpstream(db, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, upstream.Parent.ID)
+	assert.Equal(t, 2, upstream.ForkedRevision)
+	require.Len(t, upstream.Revisions, 1)
+	assert.Equal(t, 3, upstream.Revisions[0].Revision)
+	assert.Equal(t, []Change{{Field: "name", Before: "Stew", After: "Beef stew"}}, upstream.Changes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRecipeUpstreamNotForked(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	expectRecipe(mock, sqlmock.NewRows([]string{"id", "name", "description", "slug"}).AddRow(1, "Stew", "Hearty", "stew"), 1)
+
+	_, err := GetRecipeUpstream(db, 1)
+	assert.ErrorIs(t, err, ErrNotForked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -161,3 +161,120 @@ func randomString(n int) string {
 	}
 	return string(s)
 }
+
+// CreateHousehold creates an empty household with the given name
+func CreateHousehold(db *sqlx.DB, name string) (int, error) {
+	var householdID int
+	err := db.QueryRow(`INSERT INTO households (name) VALUES ($1) RETURNING id`, name).Scan(&householdID)
+	return householdID, err
+}
+
+// ListHouseholds returns every household with its members
+func ListHouseholds(db *sqlx.DB) ([]Household, error) {
+	households := []Household{}
+	err := db.Select(&households, `SELECT id, name FROM households ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []HouseholdMember{}
+	err = db.Select(&members, `SELECT household_id, user_id, email FROM household_members ORDER BY household_id, email`)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[int]*Household{}
+	for i := range households {
+		households[i].Members = []HouseholdMember{}
+		byID[households[i].ID] = &households[i]
+	}
+	for _, m := range members {
+		if h, ok := byID[m.HouseholdID]; ok {
+			h.Members = append(h.Members, m)
+		}
+	}
+	return households, nil
+}
+
+// MoveHouseholdMember moves a user into another household, leaving whatever
+// household they were in before.
+func MoveHouseholdMember(db *sqlx.DB, userID string, householdID int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var email string
+	err = tx.Get(&email, `DELETE FROM household_members WHERE user_id=$1 RETURNING email`, userID)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user %s is not a member of any household", userID)
+		}
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO household_members (household_id, user_id, email) VALUES ($1, $2, $3)`, householdID, userID, email)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeHouseholds moves everything belonging to household from, such as its
+// members, plans, recipes, prices and pantry items, into household into,
+// then deletes from. into gains from's equipment, and from's weekly budget
+// if it has none of its own.
+func MergeHouseholds(db *sqlx.DB, from int, into int) error {
+	if from == into {
+		return errors.New("cannot merge a household into itself")
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.Get(&exists, `SELECT EXISTS (SELECT 1 FROM households WHERE id=$1)`, into)
+	if err == nil && !exists {
+		err = fmt.Errorf("household %d does not exist", into)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	statements := []string{
+		`UPDATE household_members SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE plans SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE shopping_list_shares SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE dietary_profiles SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE plan_rotations SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE meal_log SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE recipes SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE receipts SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE ingredient_prices SET household_id=$2 WHERE household_id=$1`,
+		`UPDATE households h SET
+			equipment = ARRAY(SELECT DISTINCT e FROM unnest(h.equipment || f.equipment) AS e ORDER BY e),
+			weekly_budget = COALESCE(h.weekly_budget, f.weekly_budget)
+			FROM households f WHERE h.id=$2 AND f.id=$1`,
+		// The destination keeps its pantry; items from the source are added to it
+		`INSERT INTO pantry (household_id) VALUES ($2) ON CONFLICT (household_id) DO NOTHING`,
+		`INSERT INTO pantry_items (pantry_id, item_name)
+			SELECT (SELECT id FROM pantry WHERE household_id=$2), item_name
+			FROM pantry_items WHERE pantry_id IN (SELECT id FROM pantry WHERE household_id=$1)
+			ON CONFLICT DO NOTHING`,
+		`DELETE FROM pantry_items WHERE pantry_id IN (SELECT id FROM pantry WHERE household_id=$1)`,
+		`DELETE FROM pantry WHERE household_id=$1`,
+		`DELETE FROM households WHERE id=$1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, from, into); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
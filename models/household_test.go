@@ -79,3 +79,74 @@ func TestListHouseholdMembers(t *testing.T) {
 		t.Errorf("unexpected member values: %v", members)
 	}
 }
+
+func TestListHouseholds(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	mock.ExpectQuery("SELECT id, name FROM households").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Smith Household").AddRow(2, "Empty Household"))
+	mock.ExpectQuery("SELECT household_id, user_id, email FROM household_members").
+		WillReturnRows(sqlmock.NewRows([]string{"household_id", "user_id", "email"}).
+			AddRow(1, "user1", "a@example.com").AddRow(1, "user2", "b@example.com"))
+
+	households, err := ListHouseholds(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(households) != 2 || len(households[0].Members) != 2 || len(households[1].Members) != 0 {
+		t.Errorf("unexpected households: %+v", households)
+	}
+}
+
+func TestMoveHouseholdMember(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM household_members WHERE user_id=\\$1 RETURNING email").WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("a@example.com"))
+	mock.ExpectExec("INSERT INTO household_members").WithArgs(3, "user1", "a@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := MoveHouseholdMember(db, "user1", 3); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestMergeHouseholds(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	if err := MergeHouseholds(db, 2, 2); err == nil {
+		t.Error("expected error merging a household into itself")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("UPDATE household_members SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE plans SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE shopping_list_shares SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE dietary_profiles SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE plan_rotations SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE meal_log SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec("UPDATE recipes SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE receipts SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE ingredient_prices SET household_id").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 6))
+	mock.ExpectExec("UPDATE households h SET\\s+equipment = .+weekly_budget = COALESCE\\(h.weekly_budget, f.weekly_budget\\)").
+		WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO pantry \\(household_id\\)").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO pantry_items").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("DELETE FROM pantry_items").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("DELETE FROM pantry WHERE").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM households").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := MergeHouseholds(db, 2, 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
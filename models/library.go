@@ -0,0 +1,508 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LibraryVersion is bumped whenever the archive layout changes in a way older
+// importers can't read.
+const LibraryVersion = 1
+
+// libraryFile is the name of the JSON document inside a zip archive
+const libraryFile = "library.json"
+
+// MaxLibraryImageSize is the largest image read from a zip archive
+const MaxLibraryImageSize = 20 << 20
+
+var ErrUnsupportedLibraryVersion = errors.New("unsupported library archive version")
+var ErrLibraryImageTooLarge = errors.New("archive image is too large")
+var ErrUnknownConflictStrategy = errors.New("unknown conflict strategy, expected one of skip, rename or overwrite")
+
+// Conflict strategies used when an imported recipe or meal has the same slug as an existing one
+const (
+	ConflictSkip      = "skip"
+	ConflictRename    = "rename"
+	ConflictOverwrite = "overwrite"
+)
+
+// Library is a portable snapshot of everything a household works with.
+// Recipes and meals are shared between households so all of them are
+// included; plans and the pantry belong to the exporting household.
+type Library struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Recipes    []Recipe  `json:"recipes"`
+	Meals      []Meal    `json:"meals"`
+	Tags       []string  `json:"tags"`
+	Plans      []Plan    `json:"plans"`
+	Pantry     []string  `json:"pantry"`
+	// Images maps an image URL used by a recipe or meal to its path in a zip archive
+	Images map[string]string `json:"images,omitempty"`
+}
+
+// ImportResult counts what happened to each kind of record during an import
+type ImportResult struct {
+	Created     map[string]int `json:"created"`
+	Skipped     map[string]int `json:"skipped"`
+	Overwritten map[string]int `json:"overwritten"`
+	Conflicts   []PlanConflict `json:"conflicts"`
+}
+
+// PlanConflict is an imported plan left out because it shares days with
+// plans the household already has
+type PlanConflict struct {
+	StartDate Date   `json:"start_date"`
+	EndDate   Date   `json:"end_date"`
+	Plans     []Plan `json:"plans"`
+}
+
+func newImportResult() *ImportResult {
+	return &ImportResult{Created: map[string]int{}, Skipped: map[string]int{}, Overwritten: map[string]int{}, Conflicts: []PlanConflict{}}
+}
+
+// ExportLibrary collects the household's library into a Library document
+func ExportLibrary(db *sqlx.DB, householdID int) (*Library, error) {
+	lib := Library{
+		Version:    LibraryVersion,
+		ExportedAt: time.Now().UTC(),
+		Recipes:    []Recipe{},
+		Meals:      []Meal{},
+		Plans:      []Plan{},
+	}
+
+	recipes, err := GetRecipesWithDetails(db, nil)
+	if err != nil {
+		return nil, err
+	}
+	lib.Recipes = recipes
+
+	mealIDs := []int{}
+	if err := db.Select(&mealIDs, "SELECT id FROM meals WHERE deleted_at IS NULL ORDER BY id ASC"); err != nil {
+		fmt.Println("Error exporting meals:", err)
+		return nil, err
+	}
+	for _, id := range mealIDs {
+		meal, err := GetMeal(db, id)
+		if err != nil {
+			return nil, err
+		}
+		lib.Meals = append(lib.Meals, *meal)
+	}
+
+	lib.Tags, err = GetAllTags(db)
+	if err != nil {
+		return nil, err
+	}
+
+	planIDs := []int{}
+	if err := db.Select(&planIDs, "SELECT id FROM plans WHERE household_id=$1 AND deleted_at IS NULL ORDER BY start_date ASC", householdID); err != nil {
+		fmt.Println("Error exporting plans:", err)
+		return nil, err
+	}
+	for _, id := range planIDs {
+		plan, err := GetPlan(db, id)
+		if err != nil {
+			return nil, err
+		}
+		lib.Plans = append(lib.Plans, *plan)
+	}
+
+	pantry, err := GetPantry(db, householdID)
+	if err != nil {
+		return nil, err
+	}
+	lib.Pantry = pantry.Items
+
+	return &lib, nil
+}
+
+// WriteLibraryArchive writes lib as a zip archive, downloading each recipe and
+// meal image with fetch. Images that can't be fetched are left as URLs.
+func WriteLibraryArchive(ctx context.Context, w io.Writer, lib *Library, fetch Fetcher) error {
+	zw := zip.NewWriter(w)
+
+	lib.Images = map[string]string{}
+	addImage := func(imageURL string) {
+		if imageURL == "" {
+			return
+		}
+		if _, ok := lib.Images[imageURL]; ok {
+			return
+		}
+		data, err := fetch(ctx, imageURL)
+		if err != nil {
+			fmt.Println("Skipping image in export:", imageURL, err)
+			return
+		}
+		name := fmt.Sprintf("images/%d-%s", len(lib.Images)+1, imageBaseName(imageURL))
+		f, err := zw.Create(name)
+		if err != nil {
+			return
+		}
+		if _, err := f.Write(data); err != nil {
+			return
+		}
+		lib.Images[imageURL] = name
+	}
+	for _, recipe := range lib.Recipes {
+		addImage(recipe.Image.String)
+	}
+	for _, meal := range lib.Meals {
+		addImage(meal.Image.String)
+	}
+
+	f, err := zw.Create(libraryFile)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(lib); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func imageBaseName(imageURL string) string {
+	if u, err := url.Parse(imageURL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		return path.Base(u.Path)
+	}
+	return "image"
+}
+
+// ReadLibrary parses either a JSON library document or a zip archive created
+// by WriteLibraryArchive. For archives the image files are returned keyed by
+// their original URL.
+func ReadLibrary(data []byte) (*Library, map[string][]byte, error) {
+	lib := Library{}
+	images := map[string][]byte{}
+
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		if err := json.Unmarshal(data, &lib); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		files := map[string]*zip.File{}
+		for _, f := range zr.File {
+			files[f.Name] = f
+		}
+
+		f, ok := files[libraryFile]
+		if !ok {
+			return nil, nil, fmt.Errorf("archive is missing %s", libraryFile)
+		}
+		if err := readZipJSON(f, &lib); err != nil {
+			return nil, nil, err
+		}
+
+		for imageURL, name := range lib.Images {
+			f, ok := files[name]
+			if !ok {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, nil, err
+			}
+			// The sizes in the zip header can't be trusted, so read at most
+			// one byte past the limit to spot larger images
+			b, err := io.ReadAll(io.LimitReader(rc, MaxLibraryImageSize+1))
+			rc.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(b) > MaxLibraryImageSize {
+				return nil, nil, fmt.Errorf("%w: %s", ErrLibraryImageTooLarge, name)
+			}
+			images[imageURL] = b
+		}
+	}
+
+	if lib.Version < 1 || lib.Version > LibraryVersion {
+		return nil, nil, ErrUnsupportedLibraryVersion
+	}
+	return &lib, images, nil
+}
+
+func readZipJSON(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+// ValidConflictStrategy reports whether strategy is one ImportLibrary understands
+func ValidConflictStrategy(strategy string) bool {
+	return strategy == ConflictSkip || strategy == ConflictRename || strategy == ConflictOverwrite
+}
+
+// ImportLibrary restores lib into the database for householdID in a single
+// transaction. Recipes and meals whose slug already exists are handled
+// according to strategy; "rename" relies on insertRecipe/insertMeal picking
+// the next free slug. IDs inside the archive are remapped so meals and plans
+// keep pointing at the right records. storeImage, if given, is called with the
+// image URL of each recipe and meal that is created or overwritten and
+// returns the URL to save instead.
+func ImportLibrary(db *sqlx.DB, householdID int, lib *Library, strategy string, storeImage func(imageURL string) (string, error)) (*ImportResult, error) {
+	if !ValidConflictStrategy(strategy) {
+		return nil, ErrUnknownConflictStrategy
+	}
+	result := newImportResult()
+
+	setImage := func(image *sql.NullString) error {
+		if storeImage == nil || image.String == "" {
+			return nil
+		}
+		newURL, err := storeImage(image.String)
+		if err != nil {
+			return err
+		}
+		*image = NullStringWrapper(newURL)
+		return nil
+	}
+
+	// GetPantry creates the household's pantry with its default items the
+	// first time it is read, so do that before the import starts
+	var pantry *Pantry
+	if len(lib.Pantry) > 0 {
+		var err error
+		if pantry, err = GetPantry(db, householdID); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	if err := importLibrary(tx, db, householdID, lib, strategy, pantry, setImage, result); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// importLibrary does the work of ImportLibrary inside tx. Revisions of
+// overwritten records are read through db, as they existed before the import.
+func importLibrary(tx *sqlx.Tx, db *sqlx.DB, householdID int, lib *Library, strategy string, pantry *Pantry, setImage func(*sql.NullString) error, result *ImportResult) error {
+	recipeIDs := map[int]int{}
+	for _, recipe := range componentsFirst(lib.Recipes) {
+		oldID := recipe.ID
+
+		// Components are imported first; lines using a recipe that isn't in
+		// the archive keep their text only
+		ingredients := make([]RecipeIngredient, len(recipe.Ingredients))
+		for j, line := range recipe.Ingredients {
+			if line.SubRecipeID != nil {
+				if newID, ok := recipeIDs[*line.SubRecipeID]; ok {
+					line.SubRecipeID = &newID
+				} else {
+					line.SubRecipeID, line.Quantity = nil, nil
+				}
+			}
+			line.SubRecipe = nil
+			ingredients[j] = line
+		}
+		recipe.Ingredients = ingredients
+
+		var existingID int
+		err := tx.Get(&existingID, "SELECT id FROM recipes WHERE slug=$1 AND deleted_at IS NULL", recipe.Slug)
+		if err == nil && strategy != ConflictRename {
+			recipeIDs[oldID] = existingID
+			if strategy == ConflictSkip {
+				result.Skipped["recipes"]++
+				continue
+			}
+			if err := validateRecipe(tx, existingID, &recipe); err != nil {
+				return fmt.Errorf("overwriting recipe %q: %w", recipe.Slug, err)
+			}
+			previous, err := GetRecipe(db, existingID)
+			if err != nil {
+				return fmt.Errorf("overwriting recipe %q: %w", recipe.Slug, err)
+			}
+			if err := setImage(&recipe.Image); err != nil {
+				return fmt.Errorf("storing the image of recipe %q: %w", recipe.Slug, err)
+			}
+			if err := writeRecipe(tx, existingID, &recipe, previous); err != nil {
+				return fmt.Errorf("overwriting recipe %q: %w", recipe.Slug, err)
+			}
+			result.Overwritten["recipes"]++
+			continue
+		}
+
+		if err := validateRecipe(tx, 0, &recipe); err != nil {
+			return fmt.Errorf("importing recipe %q: %w", recipe.Slug, err)
+		}
+		if err := setImage(&recipe.Image); err != nil {
+			return fmt.Errorf("storing the image of recipe %q: %w", recipe.Slug, err)
+		}
+		id, err := insertRecipe(tx, &recipe)
+		if err == nil {
+			err = writeRecipe(tx, id, &recipe, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("importing recipe %q: %w", recipe.Slug, err)
+		}
+		recipeIDs[oldID] = id
+		result.Created["recipes"]++
+	}
+
+	mealIDs := map[int]int{}
+	for i := range lib.Meals {
+		meal := lib.Meals[i]
+		oldID := meal.ID
+
+		mealRecipes := []MealRecipes{}
+		for _, mr := range meal.MealRecipes {
+			if newID, ok := recipeIDs[mr.RecipeID]; ok {
+				mealRecipes = append(mealRecipes, MealRecipes{RecipeID: newID})
+			}
+		}
+		meal.MealRecipes = mealRecipes
+		if err := validateMealTimes(&meal); err != nil {
+			return fmt.Errorf("importing meal %q: %w", meal.Slug, err)
+		}
+
+		var existingID int
+		err := tx.Get(&existingID, "SELECT id FROM meals WHERE slug=$1 AND deleted_at IS NULL", meal.Slug)
+		if err == nil && strategy != ConflictRename {
+			mealIDs[oldID] = existingID
+			if strategy == ConflictSkip {
+				result.Skipped["meals"]++
+				continue
+			}
+			previous, err := GetMeal(db, existingID)
+			if err != nil {
+				return fmt.Errorf("overwriting meal %q: %w", meal.Slug, err)
+			}
+			if err := setImage(&meal.Image); err != nil {
+				return fmt.Errorf("storing the image of meal %q: %w", meal.Slug, err)
+			}
+			if err := writeMeal(tx, existingID, &meal, previous); err != nil {
+				return fmt.Errorf("overwriting meal %q: %w", meal.Slug, err)
+			}
+			result.Overwritten["meals"]++
+			continue
+		}
+
+		if err := setImage(&meal.Image); err != nil {
+			return fmt.Errorf("storing the image of meal %q: %w", meal.Slug, err)
+		}
+		id, err := insertMeal(tx, &meal)
+		if err == nil {
+			err = writeMeal(tx, id, &meal, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("importing meal %q: %w", meal.Slug, err)
+		}
+		mealIDs[oldID] = id
+		result.Created["meals"]++
+	}
+
+	for _, tag := range lib.Tags {
+		res, err := tx.Exec("INSERT INTO tags (name) VALUES ($1) ON CONFLICT DO NOTHING", tag)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			result.Created["tags"]++
+		}
+	}
+
+	for i := range lib.Plans {
+		plan := lib.Plans[i]
+		meals := []int{}
+		for _, id := range plan.Meals {
+			if newID, ok := mealIDs[id]; ok {
+				meals = append(meals, newID)
+			}
+		}
+		plan.Meals = meals
+		slots := []PlanSlot{}
+		for _, slot := range plan.Slots {
+			if newID, ok := mealIDs[slot.MealID]; ok {
+				slot.MealID = newID
+				slots = append(slots, slot)
+			}
+		}
+		plan.Slots = slots
+		plan.HouseholdID = householdID
+
+		var existingID int
+		err := tx.Get(&existingID, "SELECT id FROM plans WHERE household_id=$1 AND start_date=$2 AND deleted_at IS NULL", householdID, plan.StartDate)
+		if err == nil {
+			// Plans are identified by their dates, so renaming isn't possible
+			if strategy != ConflictOverwrite {
+				result.Skipped["plans"]++
+				continue
+			}
+			if err := writePlanMeals(tx, existingID, &plan); err != nil {
+				return err
+			}
+			result.Overwritten["plans"]++
+			continue
+		}
+
+		// Plans can't share days, which CreatePlan would check
+		overlaps := []Plan{}
+		err = tx.Select(&overlaps, overlappingPlansQuery, householdID, plan.StartDate, plan.EndDate, 0)
+		if err != nil {
+			fmt.Println("Error importing plan:", err)
+			return err
+		}
+		if len(overlaps) > 0 {
+			result.Conflicts = append(result.Conflicts, PlanConflict{StartDate: plan.StartDate, EndDate: plan.EndDate, Plans: overlaps})
+			continue
+		}
+
+		// Insert directly rather than via CreatePlan, which rejects past dates
+		err = tx.Get(&plan.ID, "INSERT INTO plans (start_date, end_date, household_id) VALUES ($1, $2, $3) RETURNING id", plan.StartDate, plan.EndDate, householdID)
+		if err != nil {
+			fmt.Println("Error importing plan:", err)
+			return err
+		}
+		if err := writePlanMeals(tx, plan.ID, &plan); err != nil {
+			return err
+		}
+		result.Created["plans"]++
+	}
+
+	if pantry != nil {
+		added, err := writePantryItems(tx, pantry.ID, lib.Pantry, strategy == ConflictOverwrite)
+		if err != nil {
+			return err
+		}
+		if strategy == ConflictOverwrite {
+			result.Overwritten["pantry_items"] = len(lib.Pantry)
+		} else if added > 0 {
+			result.Created["pantry_items"] = added
+		}
+	}
+
+	return nil
+}
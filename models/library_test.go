@@ -0,0 +1,189 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLibrary() *Library {
+	return &Library{
+		Version: LibraryVersion,
+		Recipes: []Recipe{
+			{ID: 7, Name: "Pancakes", Description: "Fluffy", Slug: "pancakes", Image: NullStringWrapper("https://img.example.com/pancakes.jpg")},
+		},
+		Meals: []Meal{
+			{ID: 3, Name: "Brunch", Description: "Weekend", Slug: "brunch", MealRecipes: []MealRecipes{{MealID: 3, RecipeID: 7}}},
+		},
+		Tags:   []string{"breakfast"},
+		Plans:  []Plan{{ID: 1, StartDate: Date{Time: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)}, EndDate: Date{Time: time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)}, Meals: []int{3}}},
+		Pantry: []string{"salt"},
+	}
+}
+
+func TestLibraryArchiveRoundTrip(t *testing.T) {
+	fetch := func(ctx context.Context, imageURL string) ([]byte, error) {
+		if imageURL == "https://img.example.com/pancakes.jpg" {
+			return []byte("jpeg bytes"), nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, WriteLibraryArchive(context.Background(), &b, testLibrary(), fetch))
+
+	lib, images, err := ReadLibrary(b.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "Pancakes", lib.Recipes[0].Name)
+	assert.Equal(t, "images/1-pancakes.jpg", lib.Images["https://img.example.com/pancakes.jpg"])
+	assert.Equal(t, []byte("jpeg bytes"), images["https://img.example.com/pancakes.jpg"])
+}
+
+func TestReadLibrary(t *testing.T) {
+	lib, images, err := ReadLibrary([]byte(`{"version": 1, "recipes": [{"name": "Toast"}]}`))
+	require.NoError(t, err)
+	assert.Empty(t, images)
+	assert.Equal(t, "Toast", lib.Recipes[0].Name)
+
+	_, _, err = ReadLibrary([]byte(`{"version": 99}`))
+	assert.Equal(t, ErrUnsupportedLibraryVersion, err)
+
+	_, _, err = ReadLibrary([]byte(`not json`))
+	assert.Error(t, err)
+
+	// Images over the limit are rejected whatever the zip header says
+	var b bytes.Buffer
+	huge := func(ctx context.Context, imageURL string) ([]byte, error) {
+		return make([]byte, MaxLibraryImageSize+1), nil
+	}
+	require.NoError(t, WriteLibraryArchive(context.Background(), &b, testLibrary(), huge))
+	_, _, err = ReadLibrary(b.Bytes())
+	assert.ErrorIs(t, err, ErrLibraryImageTooLarge)
+}
+
+func TestImportLibrary(t *testing.T) {
+	t.Run("skip existing", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		// The pantry is read before the import starts
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM pantry WHERE household_id = $1")).
+			WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "household_id"}).AddRow(1, 42))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT item_name FROM pantry_items WHERE pantry_id = $1")).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"item_name"}).AddRow("salt"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM recipes WHERE slug=$1")).
+			WithArgs("pancakes").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(70))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM meals WHERE slug=$1")).
+			WithArgs("brunch").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tags (name) VALUES ($1) ON CONFLICT DO NOTHING")).
+			WithArgs("breakfast").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM plans WHERE household_id=$1 AND start_date=$2")).
+			WithArgs(42, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO pantry_items (pantry_id, item_name) VALUES ($1, $2) ON CONFLICT DO NOTHING")).
+			WithArgs(1, "salt").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		// Skipped records keep their images where they are
+		storeImage := func(imageURL string) (string, error) {
+			t.Errorf("unexpected image upload of %s", imageURL)
+			return imageURL, nil
+		}
+		result, err := ImportLibrary(db, 42, testLibrary(), ConflictSkip, storeImage)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Skipped["recipes"])
+		assert.Equal(t, 1, result.Skipped["meals"])
+		assert.Equal(t, 1, result.Skipped["plans"])
+		assert.Equal(t, 0, result.Created["tags"])
+		assert.Equal(t, 0, result.Created["pantry_items"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("overlapping plans are reported as conflicts", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		lib := &Library{Version: LibraryVersion, Plans: []Plan{{StartDate: Date{Time: start}, EndDate: Date{Time: start.AddDate(0, 0, 6)}}}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM plans WHERE household_id=$1 AND start_date=$2")).
+			WithArgs(42, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		// The household already has a plan from the Thursday
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM plans WHERE household_id=$1 AND start_date <= $3 AND end_date >= $2")).
+			WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+				AddRow(9, start.AddDate(0, 0, 3), start.AddDate(0, 0, 9), 42))
+		mock.ExpectCommit()
+
+		result, err := ImportLibrary(db, 42, lib, ConflictSkip, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Created["plans"])
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, start, result.Conflicts[0].StartDate.Time)
+		require.Len(t, result.Conflicts[0].Plans, 1)
+		assert.Equal(t, 9, result.Conflicts[0].Plans[0].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on failure", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		lib := testLibrary()
+		lib.Pantry = nil
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM recipes WHERE slug=$1")).
+			WithArgs("pancakes").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM recipes WHERE slug=$1")).
+			WithArgs("pancakes").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO recipes (name, description, slug)")).
+			WithArgs("Pancakes", "Fluffy", "pancakes").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM recipes WHERE slug=$1")).
+			WithArgs("pancakes").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(71))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE recipes SET")).
+			WithArgs("Pancakes", "Fluffy", NullStringWrapper("http://localhost:9000/mp-images/new.jpg"),
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 71).
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		uploads := []string{}
+		storeImage := func(imageURL string) (string, error) {
+			uploads = append(uploads, imageURL)
+			return "http://localhost:9000/mp-images/new.jpg", nil
+		}
+		_, err := ImportLibrary(db, 42, lib, ConflictSkip, storeImage)
+		assert.Error(t, err)
+		assert.Equal(t, []string{"https://img.example.com/pancakes.jpg"}, uploads)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		defer db.Close()
+
+		_, err := ImportLibrary(db, 42, testLibrary(), "merge", nil)
+		assert.Equal(t, ErrUnknownConflictStrategy, err)
+	})
+}
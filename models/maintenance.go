@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gosimple/slug"
+	"github.com/jmoiron/sqlx"
+)
+
+// SlugChange records a recipe whose slug was regenerated
+type SlugChange struct {
+	ID      int    `json:"id"`
+	OldSlug string `json:"old_slug"`
+	NewSlug string `json:"new_slug"`
+}
+
+// ReslugRecipes regenerates recipe slugs that no longer match their names,
+// for example after a rename, using the same numbered suffixes as
+// CreateRecipe when names collide. Slugs that already match, including
+// numbered ones, are left alone. With dryRun nothing is written.
+func ReslugRecipes(db *sqlx.DB, dryRun bool) ([]SlugChange, error) {
+	recipes := []struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+		Slug string `db:"slug"`
+	}{}
+	err := db.Select(&recipes, `SELECT id, name, slug FROM recipes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	// Slugs that already match are claimed first so they never move
+	taken := map[string]bool{}
+	for _, r := range recipes {
+		if slugMatchesName(r.Slug, r.Name) {
+			taken[r.Slug] = true
+		}
+	}
+
+	changes := []SlugChange{}
+	for _, r := range recipes {
+		if slugMatchesName(r.Slug, r.Name) {
+			continue
+		}
+		want := slug.Make(r.Name)
+		for i := 1; taken[want]; i++ {
+			want = slug.Make(r.Name + "-" + fmt.Sprint(i))
+		}
+		taken[want] = true
+		changes = append(changes, SlugChange{ID: r.ID, OldSlug: r.Slug, NewSlug: want})
+	}
+
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	// Clear the slugs being changed first so swaps don't hit the unique constraint
+	for _, c := range changes {
+		if _, err := tx.Exec(`UPDATE recipes SET slug=$1 WHERE id=$2`, fmt.Sprintf("reslug-%d", c.ID), c.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	for _, c := range changes {
+		if _, err := tx.Exec(`UPDATE recipes SET slug=$1 WHERE id=$2`, c.NewSlug, c.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return changes, tx.Commit()
+}
+
+// slugMatchesName reports whether s is the slug CreateRecipe would give name,
+// with or without a numbered suffix.
+func slugMatchesName(s string, name string) bool {
+	base := slug.Make(name)
+	if s == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(s, base+"-")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(suffix)
+	return err == nil
+}
+
+// PruneOrphanedTags deletes tags no recipe or meal uses and returns their names
+func PruneOrphanedTags(db *sqlx.DB) ([]string, error) {
+	tags := []string{}
+	err := db.Select(&tags, `DELETE FROM tags t
+		WHERE NOT EXISTS (SELECT 1 FROM recipe_tags rt WHERE rt.tag_id = t.id)
+		AND NOT EXISTS (SELECT 1 FROM meal_tags mt WHERE mt.tag_id = t.id)
+		RETURNING name`)
+	return tags, err
+}
+
+// ImageURLs returns every image URL referenced by a recipe or meal, including
+// their earlier revisions so restoring one doesn't lose its image
+func ImageURLs(db *sqlx.DB) ([]string, error) {
+	urls := []string{}
+	err := db.Select(&urls, `SELECT image FROM recipes WHERE image IS NOT NULL AND image <> ''
+		UNION SELECT image FROM meals WHERE image IS NOT NULL AND image <> ''
+		UNION SELECT snapshot->'image'->>'String' FROM revisions WHERE snapshot->'image'->>'String' <> ''`)
+	return urls, err
+}
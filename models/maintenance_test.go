@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReslugRecipes(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "slug"}).
+			AddRow(1, "Pancakes", "pancakes-1").
+			AddRow(2, "Pancakes", "pancakes").
+			AddRow(3, "Tomato Soup", "old-soup").
+			AddRow(4, "Tomato Soup", "tomato-soup-1").
+			AddRow(5, "Tomato Soup", "tomato-bisque")
+	}
+
+	mock.ExpectQuery("SELECT id, name, slug FROM recipes").WillReturnRows(rows())
+	changes, err := ReslugRecipes(db, true)
+	require.NoError(t, err)
+	// Numbered slugs still match their names and keep their place
+	assert.Equal(t, []SlugChange{
+		{ID: 3, OldSlug: "old-soup", NewSlug: "tomato-soup"},
+		{ID: 5, OldSlug: "tomato-bisque", NewSlug: "tomato-soup-2"},
+	}, changes)
+
+	mock.ExpectQuery("SELECT id, name, slug FROM recipes").WillReturnRows(rows())
+	mock.ExpectBegin()
+	for range changes {
+		mock.ExpectExec("UPDATE recipes SET slug").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	for _, c := range changes {
+		mock.ExpectExec("UPDATE recipes SET slug").WithArgs(c.NewSlug, c.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	_, err = ReslugRecipes(db, false)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPruneOrphanedTags(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("DELETE FROM tags t").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("unused"))
+
+	tags, err := PruneOrphanedTags(db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unused"}, tags)
+}
+
+func TestImageURLs(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT image FROM recipes .*UNION SELECT image FROM meals .*UNION SELECT snapshot->'image'->>'String' FROM revisions`).
+		WillReturnRows(sqlmock.NewRows([]string{"image"}).
+			AddRow("https://img.example.com/current.jpg").
+			AddRow("https://img.example.com/old-revision.jpg"))
+
+	urls, err := ImageURLs(db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://img.example.com/current.jpg", "https://img.example.com/old-revision.jpg"}, urls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvalidMealLog = errors.New("invalid meal log entry")
+
+// Meal log statuses
+const (
+	MealCooked   = "cooked"
+	MealSkipped  = "skipped"
+	MealReplaced = "replaced"
+)
+
+// MealLogEntry records what became of a meal: cooked, skipped, or replaced
+// by another meal. Rating (1-5) and notes are optional.
+type MealLogEntry struct {
+	ID                int       `db:"id" json:"id"`
+	HouseholdID       int       `db:"household_id" json:"household_id"`
+	UserID            string    `db:"user_id" json:"user_id"`
+	MealID            int       `db:"meal_id" json:"meal_id"`
+	PlanID            *int      `db:"plan_id" json:"plan_id,omitempty"`
+	Status            string    `db:"status" json:"status"`
+	ReplacementMealID *int      `db:"replacement_meal_id" json:"replacement_meal_id,omitempty"`
+	Rating            *int      `db:"rating" json:"rating,omitempty"`
+	Notes             string    `db:"notes" json:"notes"`
+	CookedOn          Date      `db:"cooked_on" json:"cooked_on"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+}
+
+// MealStats summarizes a household's log for one meal
+type MealStats struct {
+	MealID        int      `db:"meal_id" json:"meal_id"`
+	LastCooked    *Date    `db:"last_cooked" json:"last_cooked"`
+	AverageRating *float64 `db:"average_rating" json:"average_rating"`
+	TimesCooked   int      `db:"times_cooked" json:"times_cooked"`
+}
+
+func (e *MealLogEntry) validate() error {
+	switch e.Status {
+	case MealCooked, MealSkipped:
+		e.ReplacementMealID = nil
+	case MealReplaced:
+		if e.ReplacementMealID == nil {
+			return fmt.Errorf("%w: replaced meals need a replacement_meal_id", ErrInvalidMealLog)
+		}
+	default:
+		return fmt.Errorf("%w: status must be cooked, skipped or replaced", ErrInvalidMealLog)
+	}
+	if e.Rating != nil && (*e.Rating < 1 || *e.Rating > 5) {
+		return fmt.Errorf("%w: rating must be between 1 and 5", ErrInvalidMealLog)
+	}
+	if e.MealID == 0 {
+		return fmt.Errorf("%w: meal_id is required", ErrInvalidMealLog)
+	}
+	return nil
+}
+
+// CreateMealLogEntry records an entry for a household member. An entry tied
+// to a plan must name one of the household's plans containing the meal.
+func CreateMealLogEntry(db *sqlx.DB, householdID int, userID string, e *MealLogEntry) (*MealLogEntry, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	e.HouseholdID = householdID
+	e.UserID = userID
+	if e.CookedOn.IsZero() {
+		e.CookedOn = Date{Time: startOfToday()}
+	}
+
+	if e.PlanID != nil {
+		var planned bool
+		err := db.Get(&planned, `SELECT EXISTS (SELECT 1 FROM plans p JOIN plan_meals pm ON pm.plan_id = p.id
+			WHERE p.id=$1 AND p.household_id=$2 AND pm.meal_id=$3)`, *e.PlanID, householdID, e.MealID)
+		if err != nil {
+			return nil, err
+		}
+		if !planned {
+			return nil, fmt.Errorf("%w: meal %d is not on plan %d", ErrInvalidMealLog, e.MealID, *e.PlanID)
+		}
+	}
+
+	err := db.Get(e, `INSERT INTO meal_log (household_id, user_id, meal_id, plan_id, status, replacement_meal_id, rating, notes, cooked_on)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *`,
+		e.HouseholdID, e.UserID, e.MealID, e.PlanID, e.Status, e.ReplacementMealID, e.Rating, e.Notes, e.CookedOn)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// GetMealLog lists a household's entries, newest first. A mealID or planID of
+// 0 doesn't filter.
+func GetMealLog(db *sqlx.DB, householdID int, mealID int, planID int) ([]MealLogEntry, error) {
+	entries := []MealLogEntry{}
+	err := db.Select(&entries, `SELECT * FROM meal_log WHERE household_id=$1
+		AND ($2 = 0 OR meal_id=$2) AND ($3 = 0 OR plan_id=$3)
+		ORDER BY cooked_on DESC, id DESC`, householdID, mealID, planID)
+	return entries, err
+}
+
+// DeleteMealLogEntry removes one of the user's own entries
+func DeleteMealLogEntry(db *sqlx.DB, householdID int, userID string, id int) error {
+	res, err := db.Exec(`DELETE FROM meal_log WHERE id=$1 AND household_id=$2 AND user_id=$3`, id, householdID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetMealStats returns the household's stats for every meal it has logged
+func GetMealStats(db *sqlx.DB, householdID int) (map[int]MealStats, error) {
+	rows := []MealStats{}
+	err := db.Select(&rows, `SELECT meal_id,
+			MAX(cooked_on) FILTER (WHERE status = 'cooked') AS last_cooked,
+			AVG(rating)::float8 AS average_rating,
+			COUNT(*) FILTER (WHERE status = 'cooked') AS times_cooked
+		FROM meal_log WHERE household_id=$1 GROUP BY meal_id`, householdID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[int]MealStats{}
+	for _, row := range rows {
+		stats[row.MealID] = row
+	}
+	return stats, nil
+}
+
+// ApplyMealStats copies each meal's last cooked date and average rating from
+// stats
+func ApplyMealStats(meals []Meal, stats map[int]MealStats) {
+	for i := range meals {
+		if s, ok := stats[meals[i].ID]; ok {
+			meals[i].LastCooked = s.LastCooked
+			meals[i].AverageRating = s.AverageRating
+		}
+	}
+}
+
+// MealSortKeys are the keys SortMeals understands. Prefix one with "-" to
+// sort in descending order.
+var MealSortKeys = []string{"name", "last_cooked", "rating"}
+
+// SortMeals orders meals by key. Meals never cooked or rated sort as the
+// oldest and lowest, so "last_cooked" lists forgotten meals first and
+// "-rating" lists favorites first. Ties keep name order.
+func SortMeals(meals []Meal, key string) error {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var less func(a, b *Meal) bool
+	switch key {
+	case "name":
+		less = func(a, b *Meal) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "last_cooked":
+		less = func(a, b *Meal) bool {
+			if a.LastCooked == nil || b.LastCooked == nil {
+				return a.LastCooked == nil && b.LastCooked != nil
+			}
+			return a.LastCooked.Before(b.LastCooked.Time)
+		}
+	case "rating":
+		less = func(a, b *Meal) bool {
+			if a.AverageRating == nil || b.AverageRating == nil {
+				return a.AverageRating == nil && b.AverageRating != nil
+			}
+			return *a.AverageRating < *b.AverageRating
+		}
+	default:
+		return fmt.Errorf("unknown sort key %q; use one of %s", key, strings.Join(MealSortKeys, ", "))
+	}
+
+	sort.SliceStable(meals, func(i, j int) bool {
+		return strings.ToLower(meals[i].Name) < strings.ToLower(meals[j].Name)
+	})
+	sort.SliceStable(meals, func(i, j int) bool {
+		if desc {
+			return less(&meals[j], &meals[i])
+		}
+		return less(&meals[i], &meals[j])
+	})
+	return nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMealLogEntry(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	rating := func(n int) *int { return &n }
+	planID := 3
+
+	t.Run("validation", func(t *testing.T) {
+		for _, e := range []MealLogEntry{
+			{MealID: 1, Status: "eaten"},
+			{MealID: 1, Status: MealReplaced},
+			{MealID: 1, Status: MealCooked, Rating: rating(6)},
+			{Status: MealCooked},
+		} {
+			_, err := CreateMealLogEntry(db, 42, "user1", &e)
+			assert.ErrorIs(t, err, ErrInvalidMealLog)
+		}
+	})
+
+	t.Run("meal not on plan", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(3, 42, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := CreateMealLogEntry(db, 42, "user1", &MealLogEntry{MealID: 7, PlanID: &planID, Status: MealCooked})
+		assert.ErrorIs(t, err, ErrInvalidMealLog)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("cooked", func(t *testing.T) {
+		cookedOn := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(3, 42, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("INSERT INTO meal_log").
+			WithArgs(42, "user1", 7, &planID, MealCooked, nil, rating(4), "extra garlic", Date{Time: cookedOn}).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "user_id", "meal_id", "plan_id", "status", "replacement_meal_id", "rating", "notes", "cooked_on", "created_at"}).
+				AddRow(1, 42, "user1", 7, 3, "cooked", nil, 4, "extra garlic", cookedOn, time.Now()))
+
+		entry, err := CreateMealLogEntry(db, 42, "user1", &MealLogEntry{
+			MealID:            7,
+			PlanID:            &planID,
+			Status:            MealCooked,
+			ReplacementMealID: rating(9), // dropped, only replaced meals have one
+			Rating:            rating(4),
+			Notes:             "extra garlic",
+			CookedOn:          Date{Time: cookedOn},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, entry.ID)
+		assert.Nil(t, entry.ReplacementMealID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetMealStats(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	cooked := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT meal_id,").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "last_cooked", "average_rating", "times_cooked"}).
+			AddRow(1, cooked, 4.5, 2).
+			AddRow(2, nil, nil, 0))
+
+	stats, err := GetMealStats(db, 42)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, cooked, stats[1].LastCooked.Time)
+	assert.Equal(t, 4.5, *stats[1].AverageRating)
+	assert.Nil(t, stats[2].LastCooked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSortMeals(t *testing.T) {
+	day := func(d int) *Date { return &Date{Time: time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)} }
+	score := func(f float64) *float64 { return &f }
+	names := func(meals []Meal) []string {
+		out := []string{}
+		for _, m := range meals {
+			out = append(out, m.Name)
+		}
+		return out
+	}
+
+	meals := []Meal{
+		{Name: "Tacos", LastCooked: day(10), AverageRating: score(4.5)},
+		{Name: "curry", LastCooked: day(2), AverageRating: score(3)},
+		{Name: "Lasagna"},
+		{Name: "Apple pie", LastCooked: day(10), AverageRating: score(5)},
+	}
+
+	require.NoError(t, SortMeals(meals, "last_cooked"))
+	assert.Equal(t, []string{"Lasagna", "curry", "Apple pie", "Tacos"}, names(meals))
+
+	require.NoError(t, SortMeals(meals, "-rating"))
+	assert.Equal(t, []string{"Apple pie", "Tacos", "curry", "Lasagna"}, names(meals))
+
+	require.NoError(t, SortMeals(meals, "name"))
+	assert.Equal(t, []string{"Apple pie", "curry", "Lasagna", "Tacos"}, names(meals))
+
+	assert.Error(t, SortMeals(meals, "calories"))
+}
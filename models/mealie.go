@@ -0,0 +1,202 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type mealieNamed struct {
+	Name string `json:"name"`
+}
+
+type mealieIngredient struct {
+	Title         string       `json:"title,omitempty"`
+	Note          string       `json:"note"`
+	Display       string       `json:"display,omitempty"`
+	OriginalText  string       `json:"originalText,omitempty"`
+	Quantity      *float64     `json:"quantity,omitempty"`
+	Unit          *mealieNamed `json:"unit,omitempty"`
+	Food          *mealieNamed `json:"food,omitempty"`
+	DisableAmount bool         `json:"disableAmount"`
+}
+
+type mealieInstruction struct {
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text"`
+}
+
+type mealieRecipe struct {
+	Name               string              `json:"name"`
+	Slug               string              `json:"slug,omitempty"`
+	Description        string              `json:"description"`
+	Image              string              `json:"image,omitempty"`
+	RecipeIngredient   []json.RawMessage   `json:"recipeIngredient"`
+	RecipeInstructions []mealieInstruction `json:"recipeInstructions"`
+	Tags               []mealieNamed       `json:"tags"`
+	RecipeCategory     []mealieNamed       `json:"recipeCategory"`
+}
+
+// DecodeMealie reads a Mealie recipe export, either a single recipe object or
+// an array of them.
+func DecodeMealie(data []byte) ([]ImportedRecipe, error) {
+	var mealieRecipes []mealieRecipe
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &mealieRecipes); err != nil {
+			return nil, err
+		}
+	} else {
+		var m mealieRecipe
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, err
+		}
+		mealieRecipes = []mealieRecipe{m}
+	}
+
+	recipes := []ImportedRecipe{}
+	for _, m := range mealieRecipes {
+		recipe := newImportedRecipe()
+		recipe.Name = strings.TrimSpace(m.Name)
+		recipe.Slug = m.Slug
+		recipe.Description = strings.TrimSpace(m.Description)
+		// Mealie stores images by recipe ID; only absolute URLs are usable here
+		if strings.HasPrefix(m.Image, "http://") || strings.HasPrefix(m.Image, "https://") {
+			recipe.Image = NullStringWrapper(m.Image)
+		}
+
+		// A title starts a section that runs until the next title
+		section := ""
+		for _, raw := range m.RecipeIngredient {
+			ingredient, title, ok := decodeMealieIngredient(raw)
+			if title != "" {
+				section = title
+			}
+			if ok {
+				ingredient.Section = section
+				recipe.Ingredients = append(recipe.Ingredients, ingredient)
+			}
+		}
+
+		section = ""
+		for _, instruction := range m.RecipeInstructions {
+			if title := cleanSection(instruction.Title); title != "" {
+				section = title
+			}
+			text := strings.Join(strings.Fields(instruction.Text), " ")
+			if text == "" {
+				continue
+			}
+			recipe.Steps = append(recipe.Steps, RecipeStep{Order: len(recipe.Steps) + 1, Text: text, Section: section})
+		}
+
+		raw := []string{}
+		for _, tag := range m.Tags {
+			raw = append(raw, tag.Name)
+		}
+		for _, category := range m.RecipeCategory {
+			raw = append(raw, category.Name)
+		}
+		recipe.Tags = normalizeTags(raw)
+
+		recipes = append(recipes, ImportedRecipe{Recipe: recipe})
+	}
+	return recipes, nil
+}
+
+// decodeMealieIngredient handles both parsed ingredients (food, unit and
+// quantity) and unparsed ones where the whole line lives in the note. It also
+// returns the section title the line starts, if any.
+func decodeMealieIngredient(raw json.RawMessage) (RecipeIngredient, string, bool) {
+	var line string
+	if err := json.Unmarshal(raw, &line); err == nil {
+		amount, name := SplitIngredientLine(strings.TrimSpace(line))
+		return RecipeIngredient{Name: name, Amount: amount}, "", name != "" || amount != ""
+	}
+
+	var m mealieIngredient
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return RecipeIngredient{}, "", false
+	}
+	ingredient, ok := decodeMealieLine(m)
+	return ingredient, cleanSection(m.Title), ok
+}
+
+func decodeMealieLine(m mealieIngredient) (RecipeIngredient, bool) {
+	if m.Food != nil && m.Food.Name != "" && !m.DisableAmount {
+		amount := []string{}
+		if m.Quantity != nil && *m.Quantity != 0 {
+			amount = append(amount, strconv.FormatFloat(*m.Quantity, 'f', -1, 64))
+		}
+		if m.Unit != nil && m.Unit.Name != "" {
+			amount = append(amount, m.Unit.Name)
+		}
+		name := m.Food.Name
+		if m.Note != "" {
+			name += ", " + m.Note
+		}
+		return RecipeIngredient{Name: name, Amount: strings.Join(amount, " ")}, true
+	}
+
+	for _, text := range []string{m.Note, m.OriginalText, m.Display} {
+		if text = strings.TrimSpace(text); text != "" {
+			amount, name := SplitIngredientLine(text)
+			return RecipeIngredient{Name: name, Amount: amount}, true
+		}
+	}
+	return RecipeIngredient{}, false
+}
+
+// EncodeMealie writes recipes as Mealie JSON. A single recipe is written as
+// an object, several as an array. Ingredients are exported unparsed so
+// Mealie keeps the original text.
+func EncodeMealie(w io.Writer, recipes []Recipe) error {
+	out := make([]mealieRecipe, len(recipes))
+	for i, recipe := range recipes {
+		m := mealieRecipe{
+			Name:               recipe.Name,
+			Slug:               recipe.Slug,
+			Description:        recipe.Description,
+			Image:              recipe.Image.String,
+			RecipeIngredient:   []json.RawMessage{},
+			RecipeInstructions: []mealieInstruction{},
+			Tags:               []mealieNamed{},
+			RecipeCategory:     []mealieNamed{},
+		}
+		// Mealie titles the first line of each section
+		section := ""
+		for _, ingredient := range recipe.Ingredients {
+			line := ingredientLine(ingredient)
+			title := ""
+			if ingredient.Section != section {
+				title, section = ingredient.Section, ingredient.Section
+			}
+			raw, err := json.Marshal(mealieIngredient{Title: title, Note: line, Display: line, OriginalText: line, DisableAmount: true})
+			if err != nil {
+				return err
+			}
+			m.RecipeIngredient = append(m.RecipeIngredient, raw)
+		}
+		section = ""
+		for _, step := range recipe.Steps {
+			title := ""
+			if step.Section != section {
+				title, section = step.Section, step.Section
+			}
+			m.RecipeInstructions = append(m.RecipeInstructions, mealieInstruction{Title: title, Text: step.Text})
+		}
+		for _, tag := range recipe.Tags {
+			m.Tags = append(m.Tags, mealieNamed{Name: tag})
+		}
+		out[i] = m
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if len(out) == 1 {
+		return enc.Encode(out[0])
+	}
+	return enc.Encode(out)
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeMealie(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample_mealie.json")
+	require.NoError(t, err)
+
+	recipes, err := DecodeMealie(data)
+	require.NoError(t, err)
+	require.Len(t, recipes, 1)
+
+	recipe := recipes[0].Recipe
+	assert.Equal(t, "Garlic Butter Shrimp", recipe.Name)
+	assert.Equal(t, "garlic-butter-shrimp", recipe.Slug)
+	// Mealie image IDs aren't URLs we can use
+	assert.False(t, recipe.Image.Valid)
+	assert.Equal(t, []string{"quick", "dinner"}, recipe.Tags)
+	assert.Equal(t, []RecipeIngredient{
+		{Amount: "1.5 pound", Name: "shrimp, peeled"},
+		{Amount: "4 tbsp", Name: "butter"},
+		{Amount: "3 cloves", Name: "garlic"},
+		{Amount: "", Name: "salt to taste"},
+	}, recipe.Ingredients)
+	require.Len(t, recipe.Steps, 2)
+	assert.Equal(t, "Add garlic and shrimp; cook until pink.", recipe.Steps[1].Text)
+}
+
+func TestMealieRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample_mealie.json")
+	require.NoError(t, err)
+	original, err := DecodeMealie(data)
+	require.NoError(t, err)
+
+	recipes := []Recipe{original[0].Recipe, original[0].Recipe}
+	recipes[1].Name = "Second"
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeMealie(&b, recipes))
+	assert.True(t, bytes.HasPrefix(b.Bytes(), []byte("[")))
+
+	decoded, err := DecodeMealie(b.Bytes())
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "Second", decoded[1].Recipe.Name)
+	for i := range recipes {
+		assert.Equal(t, recipes[i].Ingredients, decoded[i].Recipe.Ingredients)
+		assert.Equal(t, recipes[i].Steps, decoded[i].Recipe.Steps)
+		assert.Equal(t, recipes[i].Tags, decoded[i].Recipe.Tags)
+	}
+}
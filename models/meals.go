@@ -4,26 +4,31 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gosimple/slug"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 //var ErrValidation = errors.New("name, description, and slug are required")
 
 type MealIngredient struct {
-	ID     int    `db:"id" json:"id"`
-	MealID int    `db:"meal_id" json:"meal_id"`
-	Name   string `db:"name" json:"name"`
-	Amount string `db:"amount" json:"amount"`
+	ID       int    `db:"id" json:"id"`
+	MealID   int    `db:"meal_id" json:"meal_id"`
+	Name     string `db:"name" json:"name"`
+	Amount   string `db:"amount" json:"amount"`
+	Section  string `db:"section" json:"section,omitempty"`
+	Position int    `db:"position" json:"position"`
 }
 
 type MealStep struct {
-	ID     int    `db:"id" json:"id"`
-	MealID int    `db:"meal_id" json:"meal_id"`
-	Order  int    `db:"order" json:"order"`
-	Text   string `db:"text" json:"text"`
+	ID      int    `db:"id" json:"id"`
+	MealID  int    `db:"meal_id" json:"meal_id"`
+	Order   int    `db:"order" json:"order"`
+	Text    string `db:"text" json:"text"`
+	Section string `db:"section" json:"section,omitempty"`
+	StepTiming
 }
 
 type MealRecipes struct {
@@ -32,20 +37,29 @@ type MealRecipes struct {
 }
 
 type Meal struct {
-	ID          int              `db:"id" json:"id"`
-	Name        string           `db:"name" json:"name"`
-	Description string           `db:"description" json:"description"`
-	Slug        string           `db:"slug" json:"slug"`
-	Image       sql.NullString   `db:"image" json:"image"`
-	Ingredients []MealIngredient `json:"ingredients"`
-	Steps       []MealStep       `json:"steps"`
-	MealRecipes []MealRecipes    `json:"recipes"`
-	Tags        []string         `json:"tags"`
+	ID            int               `db:"id" json:"id"`
+	Name          string            `db:"name" json:"name"`
+	Description   string            `db:"description" json:"description"`
+	Slug          string            `db:"slug" json:"slug"`
+	Image         sql.NullString    `db:"image" json:"image"`
+	Servings      *int              `db:"servings" json:"servings"`
+	Ingredients   []MealIngredient  `json:"ingredients"`
+	Steps         []MealStep        `json:"steps"`
+	MealRecipes   []MealRecipes     `json:"recipes"`
+	Tags          []string          `json:"tags"`
+	Equipment     pq.StringArray    `db:"equipment" json:"equipment"`
+	DeletedAt     *time.Time        `db:"deleted_at" json:"deleted_at,omitempty"`
+	Nutrition     *NutritionSummary `db:"-" json:"nutrition,omitempty"`
+	Cost          *CostSummary      `db:"-" json:"cost,omitempty"`
+	Warnings      []DietaryWarning  `db:"-" json:"warnings,omitempty"`
+	LastCooked    *Date             `db:"-" json:"last_cooked,omitempty"`
+	AverageRating *float64          `db:"-" json:"average_rating,omitempty"`
+	Time          *CookingTime      `db:"-" json:"time,omitempty"`
 }
 
 func GetMeals(db *sqlx.DB) (*[]Meal, error) {
 	meals := []Meal{}
-	err := db.Select(&meals, "SELECT * FROM meals")
+	err := db.Select(&meals, "SELECT * FROM meals WHERE deleted_at IS NULL")
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -64,9 +78,36 @@ func GetMeals(db *sqlx.DB) (*[]Meal, error) {
 }
 
 func CreateMeal(db *sqlx.DB, meal *Meal) (*Meal, error) {
+	if err := validateMealTimes(meal); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	id, err := insertMeal(tx, meal)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := writeMeal(tx, id, meal, nil); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetMeal(db, id)
+}
+
+// insertMeal adds a bare meal row inside tx, giving it the first free slug
+// made from its name, and returns its ID
+func insertMeal(tx *sqlx.Tx, meal *Meal) (int, error) {
 	meal.Slug = slug.Make(meal.Name)
 	var id int
-	err := db.Get(&id, "SELECT id FROM meals WHERE slug=$1", meal.Slug)
+	err := tx.Get(&id, "SELECT id FROM meals WHERE slug=$1", meal.Slug)
 	if err == nil {
 		i := 0
 		for err == nil {
@@ -74,25 +115,27 @@ func CreateMeal(db *sqlx.DB, meal *Meal) (*Meal, error) {
 			fmt.Printf("slug %s already exists\n", meal.Slug)
 			meal.Slug = slug.Make(meal.Name + "-" + fmt.Sprint(i))
 			fmt.Printf("trying %s\n", meal.Slug)
-			err = db.Get(&id, "SELECT id FROM meals WHERE slug=$1", meal.Slug)
+			err = tx.Get(&id, "SELECT id FROM meals WHERE slug=$1", meal.Slug)
 		}
 	}
 
-	tx := db.MustBegin()
-	tx.NamedExec("INSERT INTO meals (name, description, slug, image) VALUES (:name, :description, :slug, :image)", meal)
-	tx.Commit()
-
-	id, err = GetMealIdFromSlug(db, meal.Slug)
+	_, err = tx.NamedExec("INSERT INTO meals (name, description, slug, image) VALUES (:name, :description, :slug, :image)", meal)
 	if err != nil {
-		return nil, err
+		fmt.Println(err)
+		return 0, err
 	}
 
-	return UpdateMeal(db, id, meal)
+	err = tx.Get(&id, "SELECT id FROM meals WHERE slug=$1", meal.Slug)
+	if err != nil {
+		fmt.Println(err)
+		return 0, err
+	}
+	return id, nil
 }
 
 func GetMealIdFromSlug(db *sqlx.DB, slug string) (int, error) {
 	var id int
-	err := db.Get(&id, "SELECT id FROM meals WHERE slug=$1", slug)
+	err := db.Get(&id, "SELECT id FROM meals WHERE slug=$1 AND deleted_at IS NULL", slug)
 	if err != nil {
 		fmt.Println(err)
 		return 0, err
@@ -110,7 +153,7 @@ func GetMeal(db *sqlx.DB, i int) (*Meal, error) {
 	}
 
 	ingredients := []MealIngredient{}
-	err = db.Select(&ingredients, "SELECT * FROM meal_ingredients WHERE meal_id=$1", meal.ID)
+	err = db.Select(&ingredients, "SELECT * FROM meal_ingredients WHERE meal_id=$1 ORDER BY position, id", meal.ID)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -142,68 +185,106 @@ func GetMeal(db *sqlx.DB, i int) (*Meal, error) {
 	return &meal, nil
 }
 
+// UpdateMeal replaces the meal, keeping its previous version as a revision
 func UpdateMeal(db *sqlx.DB, i int, meal *Meal) (*Meal, error) {
+	if err := validateMealTimes(meal); err != nil {
+		return nil, err
+	}
+	previous, err := GetMeal(db, i)
+	if err != nil {
+		return nil, err
+	}
+	return updateMeal(db, i, meal, previous)
+}
+
+// updateMeal saves previous as a revision, if given, and replaces the meal
+func updateMeal(db *sqlx.DB, i int, meal *Meal, previous *Meal) (*Meal, error) {
 	// Start a transaction
 	tx, err := db.Beginx()
 	if err != nil {
 		return nil, err
 	}
+	if err := writeMeal(tx, i, meal, previous); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetMeal(db, i)
+}
+
+// writeMeal replaces meal i's fields, ingredients, steps, recipes and tags
+// with meal's inside tx, saving previous as a revision first if given
+func writeMeal(tx *sqlx.Tx, i int, meal *Meal, previous *Meal) error {
+	var err error
+	if previous != nil {
+		if err := saveRevision(tx, "meal_id", i, previous); err != nil {
+			return err
+		}
+	}
 
 	// Update the Meal table
-	_, err = tx.Exec("UPDATE meals SET name=$1, description=$2, image=$3 WHERE id=$4", meal.Name, meal.Description, meal.Image, i)
+	meal.Equipment = NormalizeEquipment(meal.Equipment)
+	_, err = tx.Exec("UPDATE meals SET name=$1, description=$2, image=$3, servings=$4, equipment=$5 WHERE id=$6",
+		meal.Name, meal.Description, meal.Image, meal.Servings, meal.Equipment, i)
 	if err != nil {
-		tx.Rollback() // Rollback in case of error
 		fmt.Println(err)
-		return nil, err
+		return err
 	}
 	// Update MealIngredients: Delete old and insert new
 	_, err = tx.Exec("DELETE FROM meal_ingredients WHERE meal_id=$1", i)
 	if err != nil {
-		tx.Rollback()
-		return nil, err
+		return err
+	}
+	for j := range meal.Ingredients {
+		meal.Ingredients[j].Section = cleanSection(meal.Ingredients[j].Section)
 	}
-	for _, ingredient := range meal.Ingredients {
-		_, err = tx.Exec("INSERT INTO meal_ingredients (meal_id, name, amount) VALUES ($1, $2, $3)", i, ingredient.Name, ingredient.Amount)
+	for j, ingredient := range groupSections(meal.Ingredients, func(i MealIngredient) string { return i.Section }) {
+		_, err = tx.Exec("INSERT INTO meal_ingredients (meal_id, name, amount, section, position) VALUES ($1, $2, $3, $4, $5)", i, ingredient.Name, ingredient.Amount, ingredient.Section, j+1)
 		if err != nil {
-			tx.Rollback()
 			fmt.Println(err)
-			return nil, err
+			return err
 		}
 	}
 	// Update MealSteps: Delete old and insert new
 	_, err = tx.Exec("DELETE FROM meal_steps WHERE meal_id=$1", i)
 	if err != nil {
-		tx.Rollback()
-		return nil, err
+		return err
 	}
-	for _, step := range meal.Steps {
-		_, err = tx.Exec("INSERT INTO meal_steps (meal_id, text, \"order\") VALUES ($1, $2, $3)", i, step.Text, step.Order)
+	for j := range meal.Steps {
+		meal.Steps[j].Section = cleanSection(meal.Steps[j].Section)
+	}
+	steps := groupSteps(meal.Steps, func(s *MealStep) *int { return &s.Order }, func(s MealStep) string { return s.Section })
+	for _, step := range steps {
+		_, err = tx.Exec("INSERT INTO meal_steps (meal_id, text, \"order\", section, active_minutes, passive_minutes, temperature) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			i, step.Text, step.Order, step.Section, step.ActiveMinutes, step.PassiveMinutes, strings.TrimSpace(step.Temperature))
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return err
 		}
 	}
 	// Update MealRecipes: Delete old and insert new
 	_, err = tx.Exec("DELETE FROM meal_recipes WHERE meal_id=$1", i)
 	if err != nil {
-		tx.Rollback()
-		return nil, err
+		return err
 	}
 	for _, recipe := range meal.MealRecipes {
 		_, err = tx.Exec("INSERT INTO meal_recipes (meal_id, recipe_id) VALUES ($1, $2)", i, recipe.RecipeID)
 		if err != nil {
-			tx.Rollback()
 			fmt.Println(err)
 			fmt.Println(recipe)
-			return nil, err
+			return err
 		}
 	}
 	// Handle tags if present
 	if meal.Tags != nil {
 		_, err = tx.Exec("DELETE FROM meal_tags WHERE meal_id=$1", i)
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return err
 		}
 		for _, tag := range meal.Tags {
 			tag = strings.ToLower(tag)
@@ -213,28 +294,21 @@ func UpdateMeal(db *sqlx.DB, i int, meal *Meal) (*Meal, error) {
 				// Tag does not exist, insert it
 				err = tx.Get(&tagID, "INSERT INTO tags (name) VALUES ($1) RETURNING id", tag)
 				if err != nil {
-					tx.Rollback()
-					return nil, err
+					return err
 				}
 			}
 			_, err = tx.Exec("INSERT INTO meal_tags (meal_id, tag_id) VALUES ($1, $2)", i, tagID)
 			if err != nil {
-				tx.Rollback()
-				return nil, err
+				return err
 			}
 		}
 	}
 
-	// Commit the transaction
-	err = tx.Commit()
-	if err != nil {
-		return nil, err
-	}
-
-	return GetMeal(db, i)
+	return nil
 }
 
-func DeleteMeal(db *sqlx.DB, i int) error {
+// purgeMeal permanently deletes a meal, taking it out of any plans
+func purgeMeal(db *sqlx.DB, i int) error {
 	tx, err := db.Beginx()
 	if err != nil {
 		return err
@@ -150,29 +150,25 @@ func TestCreateMeal(t *testing.T) {
 		},
 	}
 
+	mock.ExpectBegin()
+
 	// First check if slug exists - should return error to continue with create
 	mock.ExpectQuery("SELECT id FROM meals WHERE slug=\\$1").
 		WithArgs(slug).
 		WillReturnError(sql.ErrNoRows)
 
-	// For MustBegin() in CreateMeal
-	mock.ExpectBegin()
-
 	// For the INSERT in CreateMeal
 	mock.ExpectExec("INSERT INTO meals").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	mock.ExpectCommit()
-
-	// For GetMealIdFromSlug
+	// The new meal's ID
 	mock.ExpectQuery("SELECT id FROM meals WHERE slug=\\$1").
 		WithArgs(slug).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	// For UpdateMeal
-	mock.ExpectBegin()
+	// Fill in the rest of the meal in the same transaction
 	mock.ExpectExec("UPDATE meals SET").
-		WithArgs(mealName, description, image, 1).
+		WithArgs(mealName, description, image, nil, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Delete old ingredients and insert new
@@ -180,9 +176,9 @@ func TestCreateMeal(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	for _, ing := range newMeal.Ingredients {
+	for j, ing := range newMeal.Ingredients {
 		mock.ExpectExec("INSERT INTO meal_ingredients").
-			WithArgs(1, ing.Name, ing.Amount).
+			WithArgs(1, ing.Name, ing.Amount, ing.Section, j+1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -193,7 +189,7 @@ func TestCreateMeal(t *testing.T) {
 
 	for _, step := range newMeal.Steps {
 		mock.ExpectExec("INSERT INTO meal_steps").
-			WithArgs(1, step.Text, step.Order).
+			WithArgs(1, step.Text, step.Order, step.Section, step.ActiveMinutes, step.PassiveMinutes, step.Temperature).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -300,10 +296,25 @@ func TestUpdateMeal(t *testing.T) {
 		},
 	}
 
+	// The current version is kept as a revision
+	mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(mealID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).AddRow(mealID, "Old Meal", "Old description", "old-meal", nil))
+	mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(mealID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "meal_id", "name", "amount"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1 ORDER BY").WithArgs(mealID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "meal_id", "order", "text"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(mealID).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(mealID).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
 	// Mock the transaction for UpdateMeal
 	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO revisions \\(meal_id, revision, snapshot\\)").
+		WithArgs(mealID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("UPDATE meals SET").
-		WithArgs(updatedName, description, image, mealID).
+		WithArgs(updatedName, description, image, nil, sqlmock.AnyArg(), mealID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Delete old ingredients and insert new
@@ -311,9 +322,9 @@ func TestUpdateMeal(t *testing.T) {
 		WithArgs(mealID).
 		WillReturnResult(sqlmock.NewResult(0, 2))
 
-	for _, ing := range updateMeal.Ingredients {
+	for j, ing := range updateMeal.Ingredients {
 		mock.ExpectExec("INSERT INTO meal_ingredients").
-			WithArgs(mealID, ing.Name, ing.Amount).
+			WithArgs(mealID, ing.Name, ing.Amount, ing.Section, j+1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -324,7 +335,7 @@ func TestUpdateMeal(t *testing.T) {
 
 	for _, step := range updateMeal.Steps {
 		mock.ExpectExec("INSERT INTO meal_steps").
-			WithArgs(mealID, step.Text, step.Order).
+			WithArgs(mealID, step.Text, step.Order, step.Section, step.ActiveMinutes, step.PassiveMinutes, step.Temperature).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -397,7 +408,7 @@ func TestUpdateMeal(t *testing.T) {
 	}
 }
 
-func TestDeleteMeal(t *testing.T) {
+func TestPurgeMeal(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -408,7 +419,7 @@ func TestDeleteMeal(t *testing.T) {
 
 	mealID := 1
 
-	// Mock the transaction for DeleteMeal
+	// Mock the transaction for purgeMeal
 	mock.ExpectBegin()
 	mock.ExpectExec("DELETE FROM meal_ingredients WHERE meal_id=\\$1").
 		WithArgs(mealID).
@@ -428,7 +439,7 @@ func TestDeleteMeal(t *testing.T) {
 	mock.ExpectCommit()
 
 	// Call the function
-	err = DeleteMeal(sqlxDB, mealID)
+	err = purgeMeal(sqlxDB, mealID)
 
 	// Assertions
 	assert.NoError(t, err)
@@ -0,0 +1,253 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrations are written for goose and are recorded in its version table, so
+// the goose CLI and this runner can be used interchangeably.
+const migrationTable = "goose_db_version"
+
+// migrationLockID is the Postgres advisory lock key held while migrating so
+// that instances starting together don't apply the same migration twice.
+const migrationLockID int64 = 0x6d65616c706c616e // "mealplan"
+
+var ErrNoMigrationToRollBack = errors.New("no applied migration to roll back")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// LoadMigrations reads the goose SQL files in dir, sorted by version
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected VERSION_name.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		source, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		up, down := splitMigration(string(source))
+		migrations = append(migrations, Migration{Version: version, Name: entry.Name(), Up: up, Down: down})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitMigration separates the Up and Down sections of a goose file. Each
+// section is run as a single multi-statement Exec, so the StatementBegin and
+// StatementEnd markers are not needed and are dropped.
+func splitMigration(source string) (up string, down string) {
+	var upLines, downLines []string
+	var current *[]string
+
+	scanner := bufio.NewScanner(strings.NewReader(source))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			current = &upLines
+			continue
+		case "-- +goose Down":
+			current = &downLines
+			continue
+		case "-- +goose StatementBegin", "-- +goose StatementEnd":
+			continue
+		}
+		if current != nil {
+			*current = append(*current, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(upLines, "\n")), strings.TrimSpace(strings.Join(downLines, "\n"))
+}
+
+func ensureMigrationTable(db *sqlx.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+		id SERIAL PRIMARY KEY,
+		version_id BIGINT NOT NULL,
+		is_applied BOOLEAN NOT NULL,
+		tstamp TIMESTAMP DEFAULT NOW()
+	)`)
+	return err
+}
+
+// AppliedMigrations returns the versions currently applied. As in goose, the
+// latest row for each version decides whether it is applied.
+func AppliedMigrations(db *sqlx.DB) (map[int64]bool, error) {
+	if err := ensureMigrationTable(db); err != nil {
+		return nil, err
+	}
+	return loadAppliedMigrations(db)
+}
+
+func loadAppliedMigrations(db *sqlx.DB) (map[int64]bool, error) {
+	rows := []struct {
+		VersionID int64 `db:"version_id"`
+		IsApplied bool  `db:"is_applied"`
+	}{}
+	err := db.Select(&rows, `SELECT DISTINCT ON (version_id) version_id, is_applied
+		FROM `+migrationTable+` ORDER BY version_id, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]bool{}
+	for _, row := range rows {
+		if row.IsApplied && row.VersionID > 0 {
+			applied[row.VersionID] = true
+		}
+	}
+	return applied, nil
+}
+
+// MigrateUp applies every pending migration in order, each in its own
+// transaction, and returns the ones it applied.
+func MigrateUp(db *sqlx.DB, migrations []Migration) ([]Migration, error) {
+	applied, err := AppliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := runMigration(db, m.Version, m.Up, true); err != nil {
+			return done, fmt.Errorf("applying %s: %w", m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateUpLocked runs MigrateUp while holding an advisory lock. Other
+// callers wait for the lock and then find nothing left to apply.
+func MigrateUpLocked(ctx context.Context, db *sqlx.DB, migrations []Migration) ([]Migration, error) {
+	// Advisory locks belong to a session, so lock and unlock on one connection
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	return MigrateUp(db, migrations)
+}
+
+// SchemaStatus describes how far the database is from the known migrations
+type SchemaStatus struct {
+	Version       int64    `json:"version"`
+	LatestVersion int64    `json:"latest_version"`
+	Pending       []string `json:"pending"`
+	UpToDate      bool     `json:"up_to_date"`
+}
+
+// GetSchemaStatus compares the applied migrations with the given ones. It
+// only reads, so a database that was never migrated reports every migration
+// as pending.
+func GetSchemaStatus(db *sqlx.DB, migrations []Migration) (*SchemaStatus, error) {
+	var exists bool
+	if err := db.Get(&exists, `SELECT to_regclass($1) IS NOT NULL`, migrationTable); err != nil {
+		return nil, err
+	}
+	applied := map[int64]bool{}
+	if exists {
+		var err error
+		if applied, err = loadAppliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+
+	status := &SchemaStatus{Pending: []string{}}
+	for v := range applied {
+		if v > status.Version {
+			status.Version = v
+		}
+	}
+	for _, m := range migrations {
+		if m.Version > status.LatestVersion {
+			status.LatestVersion = m.Version
+		}
+		if !applied[m.Version] {
+			status.Pending = append(status.Pending, m.Name)
+		}
+	}
+	status.UpToDate = len(status.Pending) == 0
+	return status, nil
+}
+
+// MigrateDown rolls back the most recently applied migration
+func MigrateDown(db *sqlx.DB, migrations []Migration) (*Migration, error) {
+	applied, err := AppliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if !applied[m.Version] {
+			continue
+		}
+		if err := runMigration(db, m.Version, m.Down, false); err != nil {
+			return nil, fmt.Errorf("rolling back %s: %w", m.Name, err)
+		}
+		return &m, nil
+	}
+	return nil, ErrNoMigrationToRollBack
+}
+
+func runMigration(db *sqlx.DB, version int64, statements string, isApplied bool) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if statements != "" {
+		if _, err := tx.Exec(statements); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	_, err = tx.Exec(`INSERT INTO `+migrationTable+` (version_id, is_applied) VALUES ($1, $2)`, version, isApplied)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
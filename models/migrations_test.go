@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(os.DirFS("../migrations"), ".")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	assert.Equal(t, int64(20240621232446), migrations[0].Version)
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Up, m.Name)
+		assert.NotContains(t, m.Up, "+goose", m.Name)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestSplitMigration(t *testing.T) {
+	up, down := splitMigration(`-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE a (id INT);
+CREATE TABLE b (id INT);
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE b;
+DROP TABLE a;
+`)
+	assert.Equal(t, "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);", up)
+	assert.Equal(t, "DROP TABLE b;\nDROP TABLE a;", down)
+}
+
+var testMigrations = fstest.MapFS{
+	"1_first.sql":  {Data: []byte("-- +goose Up\nCREATE TABLE a (id INT);\n-- +goose Down\nDROP TABLE a;\n")},
+	"2_second.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b (id INT);\n-- +goose Down\nDROP TABLE b;\n")},
+	"README.md":    {Data: []byte("not a migration")},
+}
+
+func TestMigrateUp(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	migrations, err := LoadMigrations(testMigrations, ".")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS goose_db_version").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT DISTINCT ON \\(version_id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied"}).AddRow(0, true).AddRow(1, true))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO goose_db_version").WithArgs(int64(2), true).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	applied, err := MigrateUp(db, migrations)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, "2_second.sql", applied[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateDown(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	migrations, err := LoadMigrations(testMigrations, ".")
+	require.NoError(t, err)
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS goose_db_version").WillReturnResult(sqlmock.NewResult(0, 0))
+	// Version 2 was applied and later rolled back, so 1 is the latest
+	mock.ExpectQuery("SELECT DISTINCT ON \\(version_id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied"}).AddRow(1, true).AddRow(2, false))
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP TABLE a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO goose_db_version").WithArgs(int64(1), false).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	m, err := MigrateDown(db, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), m.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateUpLocked(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	migrations, err := LoadMigrations(testMigrations, ".")
+	require.NoError(t, err)
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS goose_db_version").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT DISTINCT ON \\(version_id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied"}).AddRow(1, true).AddRow(2, true))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := MigrateUpLocked(context.Background(), db, migrations)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSchemaStatus(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	migrations, err := LoadMigrations(testMigrations, ".")
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT to_regclass").WithArgs("goose_db_version").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT DISTINCT ON \\(version_id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied"}).AddRow(0, true).AddRow(1, true))
+
+	status, err := GetSchemaStatus(db, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, &SchemaStatus{Version: 1, LatestVersion: 2, Pending: []string{"2_second.sql"}}, status)
+
+	// A database that was never migrated is left untouched
+	mock.ExpectQuery("SELECT to_regclass").WithArgs("goose_db_version").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	status, err = GetSchemaStatus(db, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1_first.sql", "2_second.sql"}, status.Pending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,513 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidNutritionCSV = errors.New("nutrition CSV must have name and calories columns")
+
+// Nutrition holds macros. Calories are kcal, sodium is milligrams and
+// everything else is grams.
+type Nutrition struct {
+	Calories float64 `db:"calories" json:"calories"`
+	Protein  float64 `db:"protein" json:"protein"`
+	Fat      float64 `db:"fat" json:"fat"`
+	Carbs    float64 `db:"carbs" json:"carbs"`
+	Fiber    float64 `db:"fiber" json:"fiber"`
+	Sodium   float64 `db:"sodium" json:"sodium"`
+}
+
+func (n Nutrition) Add(o Nutrition) Nutrition {
+	return Nutrition{
+		Calories: n.Calories + o.Calories,
+		Protein:  n.Protein + o.Protein,
+		Fat:      n.Fat + o.Fat,
+		Carbs:    n.Carbs + o.Carbs,
+		Fiber:    n.Fiber + o.Fiber,
+		Sodium:   n.Sodium + o.Sodium,
+	}
+}
+
+func (n Nutrition) Scale(f float64) Nutrition {
+	return Nutrition{
+		Calories: n.Calories * f,
+		Protein:  n.Protein * f,
+		Fat:      n.Fat * f,
+		Carbs:    n.Carbs * f,
+		Fiber:    n.Fiber * f,
+		Sodium:   n.Sodium * f,
+	}
+}
+
+// Round rounds every value to one decimal place for display
+func (n Nutrition) Round() Nutrition {
+	r := func(v float64) float64 { return math.Round(v*10) / 10 }
+	return Nutrition{r(n.Calories), r(n.Protein), r(n.Fat), r(n.Carbs), r(n.Fiber), r(n.Sodium)}
+}
+
+// IngredientNutrition is one row of the nutrient dataset: the macros for
+// Amount Unit of the named ingredient.
+type IngredientNutrition struct {
+	Name   string  `db:"name" json:"name"`
+	Amount float64 `db:"amount" json:"amount"`
+	Unit   string  `db:"unit" json:"unit"`
+	Nutrition
+	Source string `db:"source" json:"source"`
+}
+
+// NutritionSummary is attached to recipes and meals. Unmatched lists the
+// ingredients that are missing from the dataset or whose amount couldn't be
+// converted, so the totals are known to be incomplete.
+type NutritionSummary struct {
+	Servings   int       `json:"servings"`
+	Total      Nutrition `json:"total"`
+	PerServing Nutrition `json:"per_serving"`
+	Unmatched  []string  `json:"unmatched"`
+}
+
+// nutritionInput is an ingredient line to look up. Calories, when set,
+// overrides the dataset's calories for that line.
+type nutritionInput struct {
+	Name     string
+	Amount   string
+	Calories *int
+}
+
+// GetNutritionCandidates loads the dataset rows whose names appear in any of
+// the given ingredient names, keyed by name.
+func GetNutritionCandidates(db *sqlx.DB, names []string) (map[string]IngredientNutrition, error) {
+	dataset := map[string]IngredientNutrition{}
+	if len(names) == 0 {
+		return dataset, nil
+	}
+
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+
+	rows := []IngredientNutrition{}
+	err := db.Select(&rows, `SELECT * FROM ingredient_nutrition n
+		WHERE EXISTS (SELECT 1 FROM unnest($1::text[]) AS i(name) WHERE position(n.name IN i.name) > 0)`,
+		pq.Array(lowered))
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		dataset[row.Name] = row
+	}
+	return dataset, nil
+}
+
+// ingredientMatcher finds the entry for an ingredient name in a dataset
+// keyed by lowercase name. An exact or singular match wins; otherwise the
+// longest entry appearing as whole words in the name is used, so "melted
+// butter" matches "butter". The patterns are compiled once per dataset.
+type ingredientMatcher[T any] struct {
+	dataset  map[string]T
+	keys     []string
+	patterns []*regexp.Regexp
+}
+
+func newIngredientMatcher[T any](dataset map[string]T) *ingredientMatcher[T] {
+	keys := make([]string, 0, len(dataset))
+	for key := range dataset {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	patterns := make([]*regexp.Regexp, len(keys))
+	for i, key := range keys {
+		patterns[i] = wholeWord(key)
+	}
+	return &ingredientMatcher[T]{dataset: dataset, keys: keys, patterns: patterns}
+}
+
+func (m *ingredientMatcher[T]) match(name string) (T, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if n, ok := m.dataset[name]; ok {
+		return n, true
+	}
+	for _, suffix := range []string{"es", "s"} {
+		if n, ok := m.dataset[strings.TrimSuffix(name, suffix)]; ok && strings.HasSuffix(name, suffix) {
+			return n, true
+		}
+	}
+
+	for i, pattern := range m.patterns {
+		if pattern.MatchString(name) {
+			return m.dataset[m.keys[i]], true
+		}
+	}
+	var none T
+	return none, false
+}
+
+// computeNutrition totals the macros for a list of ingredients
+func computeNutrition(inputs []nutritionInput, dataset map[string]IngredientNutrition) (Nutrition, []string) {
+	total := Nutrition{}
+	unmatched := []string{}
+	matcher := newIngredientMatcher(dataset)
+	for _, in := range inputs {
+		if in.Name == "" {
+			continue
+		}
+
+		line := Nutrition{}
+		matched := false
+		if entry, ok := matcher.match(in.Name); ok {
+			if q, ok := ParseQuantity(in.Amount); ok {
+				if v, ok := ConvertQuantity(q, entry.Unit); ok && entry.Amount > 0 {
+					line = entry.Nutrition.Scale(v / entry.Amount)
+					matched = true
+				}
+			}
+		}
+		if in.Calories != nil {
+			line.Calories = float64(*in.Calories)
+			matched = true
+		}
+
+		if !matched {
+			unmatched = append(unmatched, in.Name)
+			continue
+		}
+		total = total.Add(line)
+	}
+	return total, unmatched
+}
+
+func newNutritionSummary(total Nutrition, servings int, unmatched []string) *NutritionSummary {
+	if servings < 1 {
+		servings = 1
+	}
+	return &NutritionSummary{
+		Servings:   servings,
+		Total:      total.Round(),
+		PerServing: total.Scale(1 / float64(servings)).Round(),
+		Unmatched:  unmatched,
+	}
+}
+
+// RecipeNutrition computes the totals for a loaded recipe, with the recipes
+// its lines use expanded to their raw ingredients. Recipes without servings
+// are treated as a single serving.
+func RecipeNutrition(db *sqlx.DB, recipe *Recipe) (*NutritionSummary, error) {
+	inputs := []nutritionInput{}
+	names := []string{}
+	recipe.expandIngredients(1, func(line RecipeIngredient, scale float64) {
+		input := nutritionInput{Name: line.Name, Amount: ScaleAmount(line.Amount, scale), Calories: line.Calories}
+		if line.Calories != nil && scale != 1 {
+			calories := int(math.Round(float64(*line.Calories) * scale))
+			input.Calories = &calories
+		}
+		inputs = append(inputs, input)
+		names = append(names, line.Name)
+	})
+
+	dataset, err := GetNutritionCandidates(db, names)
+	if err != nil {
+		return nil, err
+	}
+
+	total, unmatched := computeNutrition(inputs, dataset)
+	servings := 1
+	if recipe.Servings != nil {
+		servings = *recipe.Servings
+	}
+	return newNutritionSummary(total, servings, unmatched), nil
+}
+
+// MealNutrition computes the totals for a loaded meal: its own ingredients
+// plus each of its recipes in full. Without explicit servings a meal serves
+// as many as its largest recipe.
+func MealNutrition(db *sqlx.DB, meal *Meal) (*NutritionSummary, error) {
+	inputs := make([]nutritionInput, len(meal.Ingredients))
+	names := make([]string, len(meal.Ingredients))
+	for i, ingredient := range meal.Ingredients {
+		inputs[i] = nutritionInput{Name: ingredient.Name, Amount: ingredient.Amount}
+		names[i] = ingredient.Name
+	}
+
+	dataset, err := GetNutritionCandidates(db, names)
+	if err != nil {
+		return nil, err
+	}
+	total, unmatched := computeNutrition(inputs, dataset)
+
+	recipes, err := activeRecipes(db, meal)
+	if err != nil {
+		return nil, err
+	}
+	servings := 1
+	for _, recipe := range recipes {
+		summary, err := RecipeNutrition(db, recipe)
+		if err != nil {
+			return nil, err
+		}
+		total = total.Add(summary.Total)
+		unmatched = append(unmatched, summary.Unmatched...)
+		if recipe.Servings != nil && *recipe.Servings > servings {
+			servings = *recipe.Servings
+		}
+	}
+
+	if meal.Servings != nil {
+		servings = *meal.Servings
+	}
+	return newNutritionSummary(total, servings, unmatched), nil
+}
+
+// MealNutritionLine is one meal's contribution to a plan report
+type MealNutritionLine struct {
+	MealID     int       `json:"meal_id"`
+	Name       string    `json:"name"`
+	Day        *Date     `json:"day,omitempty"`
+	Servings   int       `json:"servings"`
+	Total      Nutrition `json:"total"`
+	PerServing Nutrition `json:"per_serving"`
+}
+
+// DayNutrition totals the meals planned for one day of a plan, plus the
+// day's share of the meals that aren't planned for a particular day
+type DayNutrition struct {
+	Date      Date      `json:"date"`
+	Total     Nutrition `json:"total"`
+	PerPerson Nutrition `json:"per_person"`
+}
+
+// PlanNutritionReport aggregates a plan's meals, per day and for the whole
+// plan. Meals planned for a day count towards that day; the rest are spread
+// evenly over the plan's length. PerDay and PerPersonPerDay are averages,
+// and per person figures assume one serving of every meal per person.
+type PlanNutritionReport struct {
+	PlanID          int                 `json:"plan_id"`
+	Days            int                 `json:"days"`
+	Total           Nutrition           `json:"total"`
+	PerDay          Nutrition           `json:"per_day"`
+	PerPersonPerDay Nutrition           `json:"per_person_per_day"`
+	ByDay           []DayNutrition      `json:"by_day"`
+	Meals           []MealNutritionLine `json:"meals"`
+	Unmatched       []string            `json:"unmatched"`
+}
+
+// PlanNutrition builds the nutrition report for a loaded plan
+func PlanNutrition(db *sqlx.DB, plan *Plan) (*PlanNutritionReport, error) {
+	days := int(plan.EndDate.Sub(plan.StartDate.Time).Hours()/24) + 1
+	if days < 1 {
+		days = 1
+	}
+
+	report := &PlanNutritionReport{PlanID: plan.ID, Days: days, ByDay: []DayNutrition{}, Meals: []MealNutritionLine{}, Unmatched: []string{}}
+	for i := 0; i < days; i++ {
+		report.ByDay = append(report.ByDay, DayNutrition{Date: Date{Time: plan.StartDate.AddDate(0, 0, i)}})
+	}
+
+	// A meal may be planned more than once, so each is only worked out once
+	meals := map[int]*Meal{}
+	summaries := map[int]*NutritionSummary{}
+	seen := map[string]bool{}
+	mealLine := func(mealID int, day *Date) (*NutritionSummary, error) {
+		meal, summary := meals[mealID], summaries[mealID]
+		if meal == nil {
+			var err error
+			if meal, err = GetMeal(db, mealID); err != nil {
+				return nil, err
+			}
+			if meal.DeletedAt == nil {
+				if summary, err = MealNutrition(db, meal); err != nil {
+					return nil, err
+				}
+			}
+			meals[mealID], summaries[mealID] = meal, summary
+		}
+		if summary == nil {
+			// The meal is in the trash, so it no longer counts
+			return nil, nil
+		}
+		report.Meals = append(report.Meals, MealNutritionLine{
+			MealID:     meal.ID,
+			Name:       meal.Name,
+			Day:        day,
+			Servings:   summary.Servings,
+			Total:      summary.Total,
+			PerServing: summary.PerServing,
+		})
+		for _, name := range summary.Unmatched {
+			if !seen[name] {
+				seen[name] = true
+				report.Unmatched = append(report.Unmatched, name)
+			}
+		}
+		return summary, nil
+	}
+
+	total, undated, undatedPerPerson := Nutrition{}, Nutrition{}, Nutrition{}
+	for _, slot := range plan.Slots {
+		if slot.MealID == 0 {
+			continue
+		}
+		day := slot.Date
+		summary, err := mealLine(slot.MealID, &day)
+		if err != nil {
+			return nil, err
+		}
+		if summary == nil {
+			continue
+		}
+		total = total.Add(summary.Total)
+		i := int(day.Sub(plan.StartDate.Time).Hours() / 24)
+		if day.Before(plan.StartDate.Time) || i >= days {
+			// Outside the plan's dates, so it can only be averaged in
+			undated = undated.Add(summary.Total)
+			undatedPerPerson = undatedPerPerson.Add(summary.PerServing)
+			continue
+		}
+		report.ByDay[i].Total = report.ByDay[i].Total.Add(summary.Total)
+		report.ByDay[i].PerPerson = report.ByDay[i].PerPerson.Add(summary.PerServing)
+	}
+	for _, mealID := range undatedMeals(plan) {
+		summary, err := mealLine(mealID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if summary == nil {
+			continue
+		}
+		total = total.Add(summary.Total)
+		undated = undated.Add(summary.Total)
+		undatedPerPerson = undatedPerPerson.Add(summary.PerServing)
+	}
+
+	perPerson := Nutrition{}
+	share, sharePerPerson := undated.Scale(1/float64(days)), undatedPerPerson.Scale(1/float64(days))
+	for i := range report.ByDay {
+		day := &report.ByDay[i]
+		perPerson = perPerson.Add(day.PerPerson).Add(sharePerPerson)
+		day.Total = day.Total.Add(share).Round()
+		day.PerPerson = day.PerPerson.Add(sharePerPerson).Round()
+	}
+
+	report.Total = total.Round()
+	report.PerDay = total.Scale(1 / float64(days)).Round()
+	report.PerPersonPerDay = perPerson.Scale(1 / float64(days)).Round()
+	return report, nil
+}
+
+// LoadNutritionCSV reads a nutrient dataset. The header names the columns;
+// name and calories are required, and amount and unit default to 100 g.
+// Recognised columns: name, amount, unit, calories, protein, fat, carbs,
+// fiber, sodium and source.
+func LoadNutritionCSV(r io.Reader) ([]IngredientNutrition, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, ErrInvalidNutritionCSV
+	}
+	if _, ok := columns["calories"]; !ok {
+		return nil, ErrInvalidNutritionCSV
+	}
+
+	items := []IngredientNutrition{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number := func(name string, fallback float64) (float64, error) {
+			s := field(name)
+			if s == "" {
+				return fallback, nil
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: %s: %w", line, name, err)
+			}
+			return v, nil
+		}
+
+		item := IngredientNutrition{Name: strings.ToLower(field("name")), Unit: NormalizeUnit(field("unit")), Source: field("source")}
+		if item.Name == "" {
+			continue
+		}
+		if field("unit") == "" {
+			item.Unit = "g"
+		}
+		values := []struct {
+			column   string
+			dest     *float64
+			fallback float64
+		}{
+			{"amount", &item.Amount, 100},
+			{"calories", &item.Calories, 0},
+			{"protein", &item.Protein, 0},
+			{"fat", &item.Fat, 0},
+			{"carbs", &item.Carbs, 0},
+			{"fiber", &item.Fiber, 0},
+			{"sodium", &item.Sodium, 0},
+		}
+		for _, v := range values {
+			if *v.dest, err = number(v.column, v.fallback); err != nil {
+				return nil, err
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// SaveIngredientNutrition inserts or replaces dataset rows by name
+func SaveIngredientNutrition(db *sqlx.DB, items []IngredientNutrition) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		_, err = tx.NamedExec(`INSERT INTO ingredient_nutrition
+			(name, amount, unit, calories, protein, fat, carbs, fiber, sodium, source)
+			VALUES (:name, :amount, :unit, :calories, :protein, :fat, :carbs, :fiber, :sodium, :source)
+			ON CONFLICT (name) DO UPDATE SET amount=EXCLUDED.amount, unit=EXCLUDED.unit,
+				calories=EXCLUDED.calories, protein=EXCLUDED.protein, fat=EXCLUDED.fat,
+				carbs=EXCLUDED.carbs, fiber=EXCLUDED.fiber, sodium=EXCLUDED.sodium, source=EXCLUDED.source`, item)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("saving %s: %w", item.Name, err)
+		}
+	}
+	return tx.Commit()
+}
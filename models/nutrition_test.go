@@ -0,0 +1,191 @@
+package models
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNutritionDataset() map[string]IngredientNutrition {
+	return map[string]IngredientNutrition{
+		"flour":  {Name: "flour", Amount: 100, Unit: "g", Nutrition: Nutrition{Calories: 364, Protein: 10, Carbs: 76}},
+		"butter": {Name: "butter", Amount: 1, Unit: "tbsp", Nutrition: Nutrition{Calories: 102, Fat: 11.5, Sodium: 91}},
+		"egg":    {Name: "egg", Amount: 1, Unit: "", Nutrition: Nutrition{Calories: 72, Protein: 6.3, Fat: 4.8}},
+		"milk":   {Name: "milk", Amount: 1, Unit: "cup", Nutrition: Nutrition{Calories: 149, Protein: 7.7}},
+	}
+}
+
+func TestMatchNutrition(t *testing.T) {
+	matcher := newIngredientMatcher(testNutritionDataset())
+	for name, want := range map[string]string{
+		"Flour":         "flour",
+		"eggs":          "egg",
+		"melted butter": "butter",
+		"whole milk":    "milk",
+	} {
+		entry, ok := matcher.match(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, entry.Name, name)
+	}
+
+	_, ok := matcher.match("buttermilk")
+	assert.False(t, ok)
+}
+
+func TestComputeNutrition(t *testing.T) {
+	calories := 50
+	total, unmatched := computeNutrition([]nutritionInput{
+		{Name: "flour", Amount: "200 g"},
+		{Name: "melted butter", Amount: "2 tbsp"},
+		{Name: "eggs", Amount: "2"},
+		{Name: "milk", Amount: "1/2 cup"},
+		// Calories from the recipe override the missing dataset entry
+		{Name: "vanilla", Amount: "1 tsp", Calories: &calories},
+		{Name: "salt", Amount: "a pinch"},
+		// Volume can't be converted to the dataset's weight
+		{Name: "flour", Amount: "1 cup"},
+	}, testNutritionDataset())
+
+	assert.InDelta(t, 728+204+144+74.5+50, total.Calories, 0.001)
+	assert.InDelta(t, 20+12.6+3.85, total.Protein, 0.001)
+	assert.Equal(t, []string{"salt", "flour"}, unmatched)
+}
+
+func TestRecipeNutrition(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM ingredient_nutrition").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "amount", "unit", "calories", "protein", "fat", "carbs", "fiber", "sodium", "source"}).
+			AddRow("flour", 100, "g", 364, 10, 1, 76, 2.7, 2, "").
+			AddRow("egg", 1, "", 72, 6.3, 4.8, 0.4, 0, 71, ""))
+
+	// Two batches of a pasta dough sub-recipe, plus a topping with set calories
+	servings := 4
+	batches := 2.0
+	toppingCalories := 50
+	summary, err := RecipeNutrition(db, &Recipe{
+		Servings: &servings,
+		Ingredients: []RecipeIngredient{
+			{Name: "pasta dough", Amount: "2 batches", Quantity: &batches, SubRecipe: &Recipe{
+				Ingredients: []RecipeIngredient{
+					{Name: "flour", Amount: "200 g"},
+					{Name: "eggs", Amount: "2"},
+					{Name: "garnish", Amount: "a little", Calories: &toppingCalories},
+				},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, summary.Servings)
+	assert.InDelta(t, 1456+288+100, summary.Total.Calories, 0.001)
+	assert.InDelta(t, 461, summary.PerServing.Calories, 0.001)
+	assert.Empty(t, summary.Unmatched)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlanNutrition(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	nutritionColumns := []string{"name", "amount", "unit", "calories", "protein", "fat", "carbs", "fiber", "sodium", "source"}
+	expectMeal := func(id int, eggs string, servings int) {
+		mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "servings"}).
+				AddRow(id, "Eggs", "Breakfast", "eggs", servings))
+		mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "name", "meal_id"}).AddRow(1, eggs, "eggs", id))
+		mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "text", "order", "meal_id"}))
+		mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+		mock.ExpectQuery("SELECT t.name FROM tags t").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectQuery("SELECT \\* FROM ingredient_nutrition").
+			WillReturnRows(sqlmock.NewRows(nutritionColumns).AddRow("egg", 1, "", 72, 6, 5, 0, 0, 70, ""))
+	}
+	// Meal 7 is planned for the second day, meal 8 for no day in particular
+	expectMeal(7, "4", 2)
+	expectMeal(8, "2", 1)
+
+	report, err := PlanNutrition(db, &Plan{
+		ID:        1,
+		StartDate: Date{Time: start},
+		EndDate:   Date{Time: start.AddDate(0, 0, 1)},
+		Meals:     []int{7, 8},
+		Slots:     []PlanSlot{{Date: Date{Time: start.AddDate(0, 0, 1)}, Slot: "breakfast", MealID: 7}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 432.0, report.Total.Calories)
+	assert.Equal(t, 216.0, report.PerDay.Calories)
+	assert.Equal(t, 144.0, report.PerPersonPerDay.Calories)
+	require.Len(t, report.ByDay, 2)
+	// Meal 8's 144 calories are shared between the days
+	assert.Equal(t, 72.0, report.ByDay[0].Total.Calories)
+	assert.Equal(t, 360.0, report.ByDay[1].Total.Calories)
+	assert.Equal(t, 216.0, report.ByDay[1].PerPerson.Calories)
+	require.Len(t, report.Meals, 2)
+	assert.Equal(t, start.AddDate(0, 0, 1), report.Meals[0].Day.Time)
+	assert.Nil(t, report.Meals[1].Day)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlanNutritionSkipsTrashedMeals(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "deleted_at"}).
+			AddRow(7, "Eggs", "Breakfast", "eggs", start))
+	mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "name", "meal_id"}).AddRow(1, "4", "eggs", 7))
+	mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "text", "order", "meal_id"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+	mock.ExpectQuery("SELECT t.name FROM tags t").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	report, err := PlanNutrition(db, &Plan{
+		ID:        1,
+		StartDate: Date{Time: start},
+		EndDate:   Date{Time: start},
+		Meals:     []int{7},
+		Slots:     []PlanSlot{{Date: Date{Time: start}, Slot: "breakfast", MealID: 7}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, report.Meals)
+	assert.Equal(t, 0.0, report.Total.Calories)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadNutritionCSV(t *testing.T) {
+	f, err := os.Open("../data/nutrients.csv")
+	require.NoError(t, err)
+	defer f.Close()
+
+	items, err := LoadNutritionCSV(f)
+	require.NoError(t, err)
+	require.NotEmpty(t, items)
+	for _, item := range items {
+		assert.Equal(t, strings.ToLower(item.Name), item.Name)
+		assert.Greater(t, item.Amount, 0.0, item.Name)
+	}
+
+	items, err = LoadNutritionCSV(strings.NewReader("Name,Calories,Protein\nTofu,76,8\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []IngredientNutrition{{Name: "tofu", Amount: 100, Unit: "g", Nutrition: Nutrition{Calories: 76, Protein: 8}}}, items)
+
+	_, err = LoadNutritionCSV(strings.NewReader("name,protein\ntofu,8\n"))
+	assert.Equal(t, ErrInvalidNutritionCSV, err)
+
+	_, err = LoadNutritionCSV(strings.NewReader("name,calories\ntofu,lots\n"))
+	assert.Error(t, err)
+}
@@ -92,6 +92,30 @@ func UpdatePantry(db *sqlx.DB, householdID int, pantry *Pantry) (*Pantry, error)
 	return GetPantry(db, householdID)
 }
 
+// writePantryItems adds items to pantryID inside tx, first removing the
+// existing ones if replace is set. It returns how many items were added.
+func writePantryItems(tx *sqlx.Tx, pantryID uint, items []string, replace bool) (int, error) {
+	if replace {
+		if _, err := tx.Exec("DELETE FROM pantry_items WHERE pantry_id=$1", pantryID); err != nil {
+			fmt.Println(err)
+			return 0, err
+		}
+	}
+	added := 0
+	for _, item := range items {
+		// A failed insert would abort tx, so duplicates are skipped instead
+		res, err := tx.Exec("INSERT INTO pantry_items (pantry_id, item_name) VALUES ($1, $2) ON CONFLICT DO NOTHING", pantryID, strings.ToLower(item))
+		if err != nil {
+			fmt.Println(err)
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	return added, nil
+}
+
 func DeletePantry(db *sqlx.DB, householdID int) error {
 	pantry, err := GetPantry(db, householdID)
 	if err != nil {
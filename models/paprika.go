@@ -0,0 +1,176 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// paprikaRecipe is the JSON document inside each gzipped .paprikarecipe entry
+type paprikaRecipe struct {
+	UID         string   `json:"uid"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Ingredients string   `json:"ingredients"`
+	Directions  string   `json:"directions"`
+	Notes       string   `json:"notes"`
+	Categories  []string `json:"categories"`
+	Servings    string   `json:"servings"`
+	Source      string   `json:"source"`
+	SourceURL   string   `json:"source_url"`
+	ImageURL    string   `json:"image_url"`
+	Photo       string   `json:"photo"`
+	PhotoData   string   `json:"photo_data"`
+}
+
+// DecodePaprika reads a .paprikarecipes archive (a zip of gzipped JSON
+// documents) or a single gzipped .paprikarecipe file.
+func DecodePaprika(data []byte) ([]ImportedRecipe, error) {
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		recipe, err := decodePaprikaEntry(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return []ImportedRecipe{*recipe}, nil
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	recipes := []ImportedRecipe{}
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".paprikarecipe") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		recipe, err := decodePaprikaEntry(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		recipes = append(recipes, *recipe)
+	}
+	return recipes, nil
+}
+
+func decodePaprikaEntry(r io.Reader) (*ImportedRecipe, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	b, err := readRecipeEntry(gz)
+	if err != nil {
+		return nil, err
+	}
+	var p paprikaRecipe
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+
+	imported := ImportedRecipe{Recipe: newImportedRecipe()}
+	recipe := &imported.Recipe
+	recipe.Name = strings.TrimSpace(p.Name)
+	recipe.Description = strings.TrimSpace(p.Description)
+	if recipe.Description == "" {
+		// Paprika recipes often only have notes; the description is required here
+		recipe.Description = strings.TrimSpace(p.Notes)
+	}
+
+	section := ""
+	for _, line := range strings.Split(strings.ReplaceAll(p.Ingredients, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if heading, ok := sectionHeading(line); ok {
+			section = heading
+			continue
+		}
+		amount, name := SplitIngredientLine(line)
+		recipe.Ingredients = append(recipe.Ingredients, RecipeIngredient{Name: name, Amount: amount, Section: section})
+	}
+
+	recipe.Steps = stepsFromText(p.Directions)
+	recipe.Tags = normalizeTags(p.Categories)
+	recipe.Image = NullStringWrapper(p.ImageURL)
+
+	if p.PhotoData != "" {
+		photo, err := base64.StdEncoding.DecodeString(p.PhotoData)
+		if err != nil {
+			return nil, fmt.Errorf("decoding photo: %w", err)
+		}
+		imported.Photo = photo
+		imported.PhotoName = p.Photo
+		if imported.PhotoName == "" {
+			imported.PhotoName = "photo.jpg"
+		}
+	}
+
+	return &imported, nil
+}
+
+// EncodePaprika writes recipes as a .paprikarecipes archive. Images are
+// referenced by URL rather than embedded.
+func EncodePaprika(w io.Writer, recipes []Recipe) error {
+	zw := zip.NewWriter(w)
+
+	for i, recipe := range recipes {
+		ingredients := make([]string, len(recipe.Ingredients))
+		ingredientSections := make([]string, len(recipe.Ingredients))
+		for j, ingredient := range recipe.Ingredients {
+			ingredients[j] = ingredientLine(ingredient)
+			ingredientSections[j] = ingredient.Section
+		}
+		directions := make([]string, len(recipe.Steps))
+		stepSections := make([]string, len(recipe.Steps))
+		for j, step := range recipe.Steps {
+			directions[j] = step.Text
+			stepSections[j] = step.Section
+		}
+
+		p := paprikaRecipe{
+			UID:         strings.ToUpper(uuid.NewString()),
+			Name:        recipe.Name,
+			Description: recipe.Description,
+			Ingredients: withHeadings(ingredients, ingredientSections, "\n"),
+			Directions:  withHeadings(directions, stepSections, "\n\n"),
+			Categories:  recipe.Tags,
+			ImageURL:    recipe.Image.String,
+		}
+		if p.Categories == nil {
+			p.Categories = []string{}
+		}
+
+		name := recipe.Slug
+		if name == "" {
+			name = fmt.Sprintf("recipe-%d", i+1)
+		}
+		f, err := zw.Create(name + ".paprikarecipe")
+		if err != nil {
+			return err
+		}
+		gz := gzip.NewWriter(f)
+		if err := json.NewEncoder(gz).Encode(p); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePaprika(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.paprikarecipes")
+	require.NoError(t, err)
+
+	recipes, err := DecodePaprika(data)
+	require.NoError(t, err)
+	require.Len(t, recipes, 2)
+
+	bread := recipes[0]
+	assert.Equal(t, "Banana Bread", bread.Recipe.Name)
+	// Falls back to notes when there is no description
+	assert.Equal(t, "Uses overripe bananas.", bread.Recipe.Description)
+	assert.Equal(t, []string{"baking", "breakfast"}, bread.Recipe.Tags)
+	require.Len(t, bread.Recipe.Ingredients, 4)
+	assert.Equal(t, RecipeIngredient{Amount: "1/3 cup", Name: "melted butter"}, bread.Recipe.Ingredients[1])
+	require.Len(t, bread.Recipe.Steps, 3)
+	assert.Equal(t, "Bake 60 minutes at 350F.", bread.Recipe.Steps[2].Text)
+	assert.Equal(t, []byte("\xff\xd8\xff\xe0fakejpeg"), bread.Photo)
+	assert.Equal(t, "banana.jpg", bread.PhotoName)
+
+	salad := recipes[1]
+	assert.Equal(t, "https://example.com/salad.jpg", salad.Recipe.Image.String)
+	assert.Nil(t, salad.Photo)
+	// Single newlines separate steps when there are no blank lines
+	assert.Len(t, salad.Recipe.Steps, 2)
+}
+
+func TestPaprikaRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.paprikarecipes")
+	require.NoError(t, err)
+	original, err := DecodePaprika(data)
+	require.NoError(t, err)
+
+	recipes := []Recipe{original[0].Recipe, original[1].Recipe}
+	var b bytes.Buffer
+	require.NoError(t, EncodePaprika(&b, recipes))
+
+	decoded, err := DecodePaprika(b.Bytes())
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	for i := range recipes {
+		assert.Equal(t, recipes[i].Name, decoded[i].Recipe.Name)
+		assert.Equal(t, recipes[i].Description, decoded[i].Recipe.Description)
+		assert.Equal(t, recipes[i].Ingredients, decoded[i].Recipe.Ingredients)
+		assert.Equal(t, recipes[i].Steps, decoded[i].Recipe.Steps)
+		assert.Equal(t, recipes[i].Tags, decoded[i].Recipe.Tags)
+		assert.Equal(t, recipes[i].Image, decoded[i].Recipe.Image)
+	}
+}
+
+func TestDecodePaprikaBoundsEntries(t *testing.T) {
+	// A gzipped entry that expands to more than MaxRecipeFileSize
+	var entry bytes.Buffer
+	gz := gzip.NewWriter(&entry)
+	_, err := gz.Write(make([]byte, MaxRecipeFileSize+1))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, err := zw.Create("bomb.paprikarecipe")
+	require.NoError(t, err)
+	_, err = f.Write(entry.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	_, err = DecodePaprika(archive.Bytes())
+	assert.ErrorIs(t, err, ErrRecipeFileTooLarge)
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidRotation = errors.New("invalid plan rotation")
+
+// rotationLeadDays is how far ahead of its start a rotation's next plan is
+// created, so next week's plan is in place during this week.
+const rotationLeadDays = 7
+
+// PlanRotation cycles through saved plans. Each time a plan is due, the plan
+// at Position is copied to NextStart.
+type PlanRotation struct {
+	ID          int    `db:"id" json:"id"`
+	HouseholdID int    `db:"household_id" json:"household_id"`
+	Name        string `db:"name" json:"name"`
+	NextStart   Date   `db:"next_start" json:"next_start"`
+	Position    int    `db:"position" json:"position"`
+	Active      bool   `db:"active" json:"active"`
+	Plans       []int  `db:"-" json:"plans"`
+}
+
+// CopyPlan creates a new plan with the source's meals starting on start.
+// Meals planned for a particular day keep their place relative to the start.
+func CopyPlan(db *sqlx.DB, source *Plan, start Date) (*Plan, error) {
+	days := int(math.Round(start.Sub(source.StartDate.Time).Hours() / 24))
+
+	plan := &Plan{
+		StartDate: start,
+		EndDate:   Date{Time: source.EndDate.AddDate(0, 0, days)},
+		Meals:     source.Meals,
+	}
+	for _, slot := range source.Slots {
+		slot.Date = Date{Time: slot.Date.AddDate(0, 0, days)}
+		plan.Slots = append(plan.Slots, slot)
+	}
+	return CreatePlan(db, plan, source.HouseholdID)
+}
+
+func loadRotationPlans(db *sqlx.DB, rotation *PlanRotation) error {
+	rotation.Plans = []int{}
+	return db.Select(&rotation.Plans, `SELECT plan_id FROM plan_rotation_entries WHERE rotation_id=$1 ORDER BY position`, rotation.ID)
+}
+
+func GetPlanRotations(db *sqlx.DB, householdID int) ([]PlanRotation, error) {
+	rotations := []PlanRotation{}
+	err := db.Select(&rotations, `SELECT * FROM plan_rotations WHERE household_id=$1 ORDER BY id`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rotations {
+		if err := loadRotationPlans(db, &rotations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rotations, nil
+}
+
+func GetPlanRotation(db *sqlx.DB, id int) (*PlanRotation, error) {
+	rotation := PlanRotation{}
+	if err := db.Get(&rotation, `SELECT * FROM plan_rotations WHERE id=$1`, id); err != nil {
+		return nil, err
+	}
+	if err := loadRotationPlans(db, &rotation); err != nil {
+		return nil, err
+	}
+	return &rotation, nil
+}
+
+// CreatePlanRotation saves a rotation of the household's plans
+func CreatePlanRotation(db *sqlx.DB, householdID int, rotation *PlanRotation) (*PlanRotation, error) {
+	if rotation.Name == "" || len(rotation.Plans) == 0 || rotation.NextStart.IsZero() {
+		return nil, fmt.Errorf("%w: name, plans and next_start are required", ErrInvalidRotation)
+	}
+	rotation.HouseholdID = householdID
+
+	var owned int
+	err := db.Get(&owned, `SELECT COUNT(DISTINCT id) FROM plans WHERE id = ANY($1) AND household_id=$2`, pq.Array(rotation.Plans), householdID)
+	if err != nil {
+		return nil, err
+	}
+	if owned != len(uniqueInts(rotation.Plans)) {
+		return nil, fmt.Errorf("%w: plans must belong to the household", ErrInvalidRotation)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Get(&rotation.ID, `INSERT INTO plan_rotations (household_id, name, next_start, position, active)
+		VALUES ($1, $2, $3, 0, TRUE) RETURNING id`, householdID, rotation.Name, rotation.NextStart)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for i, planID := range rotation.Plans {
+		_, err = tx.Exec(`INSERT INTO plan_rotation_entries (rotation_id, position, plan_id) VALUES ($1, $2, $3)`, rotation.ID, i, planID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetPlanRotation(db, rotation.ID)
+}
+
+func DeletePlanRotation(db *sqlx.DB, id int) error {
+	_, err := db.Exec(`DELETE FROM plan_rotations WHERE id=$1`, id)
+	return err
+}
+
+func uniqueInts(values []int) []int {
+	seen := map[int]bool{}
+	out := []int{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// RollRotation copies the rotation's next plan forward and advances the
+// rotation. A rotation that fell behind skips ahead whole plan lengths so new
+// plans never start in the past.
+func RollRotation(db *sqlx.DB, rotation *PlanRotation, now time.Time) (*Plan, error) {
+	if len(rotation.Plans) == 0 {
+		return nil, fmt.Errorf("%w: rotation %d has no plans", ErrInvalidRotation, rotation.ID)
+	}
+	source, err := GetPlan(db, rotation.Plans[rotation.Position%len(rotation.Plans)])
+	if err != nil {
+		return nil, err
+	}
+
+	length := planDays(source.StartDate.Time, source.EndDate.Time)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, rotation.NextStart.Location())
+	start := rotation.NextStart.Time
+	for start.Before(today) {
+		start = start.AddDate(0, 0, length)
+	}
+
+	// Claim this turn first, so that two servers rolling at once don't both
+	// create the plan
+	res, err := db.Exec(`UPDATE plan_rotations SET position=$1, next_start=$2 WHERE id=$3 AND position=$4`,
+		rotation.Position+1, start.AddDate(0, 0, length), rotation.ID, rotation.Position)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, fmt.Errorf("rotation %d was already rolled", rotation.ID)
+	}
+
+	plan, err := CopyPlan(db, source, Date{Time: start})
+	if err != nil {
+		db.Exec(`UPDATE plan_rotations SET position=$1, next_start=$2 WHERE id=$3`, rotation.Position, rotation.NextStart, rotation.ID)
+		return nil, err
+	}
+	rotation.Position++
+	rotation.NextStart = Date{Time: start.AddDate(0, 0, length)}
+	return plan, nil
+}
+
+// RollDueRotations creates the next plan of every active rotation whose next
+// plan starts within the coming week.
+func RollDueRotations(db *sqlx.DB, now time.Time) ([]Plan, error) {
+	ids := []int{}
+	err := db.Select(&ids, `SELECT id FROM plan_rotations WHERE active AND next_start <= $1 ORDER BY id`, now.AddDate(0, 0, rotationLeadDays))
+	if err != nil {
+		return nil, err
+	}
+
+	created := []Plan{}
+	var errs []error
+	for _, id := range ids {
+		rotation, err := GetPlanRotation(db, id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		plan, err := RollRotation(db, rotation, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rotation %d: %w", id, err))
+			continue
+		}
+		created = append(created, *plan)
+	}
+	return created, errors.Join(errs...)
+}
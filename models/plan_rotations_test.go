@@ -0,0 +1,115 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyPlan(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	sourceStart := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	source := &Plan{
+		ID:          1,
+		StartDate:   Date{Time: sourceStart},
+		EndDate:     Date{Time: sourceStart.AddDate(0, 0, 6)},
+		HouseholdID: 42,
+		Meals:       []int{7, 8, 7},
+		Slots: []PlanSlot{
+			{Date: Date{Time: sourceStart}, Slot: "dinner", MealID: 7},
+			{Date: Date{Time: sourceStart.AddDate(0, 0, 3)}, Slot: "dinner", MealID: 7},
+		},
+	}
+	now := time.Now().AddDate(0, 0, 2)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3").
+		WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}))
+	mock.ExpectExec("INSERT INTO plans").
+		WithArgs(Date{Time: start}, Date{Time: start.AddDate(0, 0, 6)}, 42).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM plans WHERE start_date").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM plan_meals WHERE plan_id").WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// Dated meals move with the plan; meal 8 had no day and stays undated
+	mock.ExpectExec("INSERT INTO plan_meals \\(plan_id, meal_id, day, slot\\)").
+		WithArgs(2, 7, Date{Time: start}, "dinner").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO plan_meals \\(plan_id, meal_id, day, slot\\)").
+		WithArgs(2, 7, Date{Time: start.AddDate(0, 0, 3)}, "dinner").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO plan_meals \\(plan_id, meal_id\\)").
+		WithArgs(2, 8).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(2, start, start.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id", "day", "slot"}).
+			AddRow(1, 2, 7, start, "dinner").
+			AddRow(2, 2, 7, start.AddDate(0, 0, 3), "dinner").
+			AddRow(3, 2, 8, nil, nil))
+
+	plan, err := CopyPlan(db, source, Date{Time: start})
+	require.NoError(t, err)
+	assert.Equal(t, 2, plan.ID)
+	assert.Equal(t, []int{7, 7, 8}, plan.Meals)
+	require.Len(t, plan.Slots, 2)
+	assert.Equal(t, start.AddDate(0, 0, 3), plan.Slots[1].Date.Time)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRollRotationAlreadyRolled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	sourceStart := time.Date(2026, 9, 28, 0, 0, 0, 0, time.UTC)
+	rotation := &PlanRotation{ID: 3, HouseholdID: 42, NextStart: Date{Time: time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)}, Position: 1, Plans: []int{1, 2}}
+
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(2, sourceStart, sourceStart.AddDate(0, 0, 6), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+	// The rotation is two weeks behind, so it skips ahead to the 19th
+	mock.ExpectExec("UPDATE plan_rotations SET position").
+		WithArgs(2, time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC), 3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := RollRotation(db, rotation, now)
+	assert.Error(t, err)
+	assert.Equal(t, 1, rotation.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePlanRotationValidation(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	_, err := CreatePlanRotation(db, 42, &PlanRotation{Name: "Fortnight"})
+	assert.ErrorIs(t, err, ErrInvalidRotation)
+
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT id\\) FROM plans").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	_, err = CreatePlanRotation(db, 42, &PlanRotation{
+		Name:      "Fortnight",
+		NextStart: Date{Time: time.Now()},
+		Plans:     []int{1, 99},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRotation)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
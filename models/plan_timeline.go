@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvalidTimeline = errors.New("invalid timeline range")
+
+// maxTimelineDays bounds a single timeline request
+const maxTimelineDays = 366
+
+// TimelineDay is one day of a timeline. PlanID is nil on days no plan covers.
+// Meals lists the meals planned for that day; meals a plan doesn't assign to
+// a day are listed on the plan in Timeline.Plans instead.
+type TimelineDay struct {
+	Date   Date       `json:"date"`
+	PlanID *int       `json:"plan_id"`
+	Meals  []PlanSlot `json:"meals"`
+}
+
+// DateRange is an inclusive run of days
+type DateRange struct {
+	Start Date `json:"start"`
+	End   Date `json:"end"`
+	Days  int  `json:"days"`
+}
+
+// Timeline is a household's plans laid out day by day, with the runs of days
+// that no plan covers.
+type Timeline struct {
+	From  Date          `json:"from"`
+	To    Date          `json:"to"`
+	Days  []TimelineDay `json:"days"`
+	Plans []Plan        `json:"plans"`
+	Gaps  []DateRange   `json:"gaps"`
+}
+
+// GetPlanTimeline builds the household's timeline from from to to inclusive
+func GetPlanTimeline(db *sqlx.DB, householdID int, from, to time.Time) (*Timeline, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidTimeline)
+	}
+	days := planDays(from, to)
+	if days > maxTimelineDays {
+		return nil, fmt.Errorf("%w: at most %d days can be shown at once", ErrInvalidTimeline, maxTimelineDays)
+	}
+
+	ids := []int{}
+	err := db.Select(&ids, `SELECT id FROM plans WHERE household_id=$1 AND start_date <= $3 AND end_date >= $2 AND deleted_at IS NULL ORDER BY start_date`,
+		householdID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := &Timeline{From: Date{Time: from}, To: Date{Time: to}, Days: []TimelineDay{}, Plans: []Plan{}, Gaps: []DateRange{}}
+	for _, id := range ids {
+		plan, err := GetPlan(db, id)
+		if err != nil {
+			return nil, err
+		}
+		timeline.Plans = append(timeline.Plans, *plan)
+	}
+
+	for d := 0; d < days; d++ {
+		day := TimelineDay{Date: Date{Time: from.AddDate(0, 0, d)}, Meals: []PlanSlot{}}
+		for i := range timeline.Plans {
+			plan := &timeline.Plans[i]
+			if day.Date.Before(plan.StartDate.Time) || day.Date.After(plan.EndDate.Time) {
+				continue
+			}
+			if day.PlanID == nil {
+				day.PlanID = &plan.ID
+			}
+			for _, slot := range plan.Slots {
+				if sameDay(slot.Date.Time, day.Date.Time) {
+					day.Meals = append(day.Meals, slot)
+				}
+			}
+		}
+		timeline.Days = append(timeline.Days, day)
+
+		if day.PlanID != nil {
+			continue
+		}
+		if n := len(timeline.Gaps); n > 0 && sameDay(timeline.Gaps[n-1].End.AddDate(0, 0, 1), day.Date.Time) {
+			timeline.Gaps[n-1].End = day.Date
+			timeline.Gaps[n-1].Days++
+		} else {
+			timeline.Gaps = append(timeline.Gaps, DateRange{Start: day.Date, End: day.Date, Days: 1})
+		}
+	}
+	return timeline, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPlanTimeline(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	day := func(d int) time.Time { return time.Date(2026, 11, d, 0, 0, 0, 0, time.UTC) }
+
+	mock.ExpectQuery("SELECT id FROM plans WHERE household_id=\\$1").
+		WithArgs(42, day(1), day(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(5, day(3), day(5), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id", "day", "slot"}).
+			AddRow(1, 5, 7, day(4), "dinner").
+			AddRow(2, 5, 8, nil, nil))
+
+	timeline, err := GetPlanTimeline(db, 42, day(1), day(7))
+	require.NoError(t, err)
+	require.Len(t, timeline.Days, 7)
+
+	assert.Nil(t, timeline.Days[0].PlanID)
+	require.NotNil(t, timeline.Days[2].PlanID)
+	assert.Equal(t, 5, *timeline.Days[2].PlanID)
+	assert.Empty(t, timeline.Days[2].Meals)
+	assert.Equal(t, []PlanSlot{{Date: Date{Time: day(4)}, Slot: "dinner", MealID: 7}}, timeline.Days[3].Meals)
+	require.Len(t, timeline.Plans, 1)
+	assert.Equal(t, []int{7, 8}, timeline.Plans[0].Meals)
+
+	assert.Equal(t, []DateRange{
+		{Start: Date{Time: day(1)}, End: Date{Time: day(2)}, Days: 2},
+		{Start: Date{Time: day(6)}, End: Date{Time: day(7)}, Days: 2},
+	}, timeline.Gaps)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPlanTimelineInvalidRange(t *testing.T) {
+	db, _ := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	_, err := GetPlanTimeline(db, 42, start, start.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, ErrInvalidTimeline)
+	_, err = GetPlanTimeline(db, 42, start, start.AddDate(2, 0, 0))
+	assert.ErrorIs(t, err, ErrInvalidTimeline)
+}
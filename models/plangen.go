@@ -0,0 +1,343 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvalidGeneration = errors.New("invalid plan generation request")
+
+// maxGeneratedDays keeps a single request from producing an unbounded plan
+const maxGeneratedDays = 31
+
+// PlanGenerationRequest describes the plan to draft. Slots name the meals
+// wanted each day ("dinner" by default); a meal tagged with a slot's name is
+// preferred for that slot. A meal used within NoRepeatDays, in this draft or
+// in one of the household's earlier plans, is not picked again. The same
+// Seed always produces the same draft for the same library.
+type PlanGenerationRequest struct {
+	StartDate    Date     `json:"start_date"`
+	EndDate      Date     `json:"end_date"`
+	Slots        []string `json:"slots"`
+	Tags         []string `json:"tags"`
+	ExcludeTags  []string `json:"exclude_tags"`
+	ExcludeMeals []int    `json:"exclude_meals"`
+	NoRepeatDays int      `json:"no_repeat_days"`
+	UsePantry    bool     `json:"use_pantry"`
+	Seed         int64    `json:"seed"`
+	Save         bool     `json:"save"`
+}
+
+// PlanSlot is one meal of a generated plan. MealID is 0 when nothing could
+// fill the slot.
+type PlanSlot struct {
+	Date     Date   `json:"date"`
+	Slot     string `json:"slot"`
+	MealID   int    `json:"meal_id,omitempty"`
+	MealName string `json:"meal_name,omitempty"`
+}
+
+// GeneratedPlan is a draft plan and the slot-by-slot picks behind it. Plan.ID
+// is only set when the draft was saved.
+type GeneratedPlan struct {
+	Plan     Plan       `json:"plan"`
+	Slots    []PlanSlot `json:"slots"`
+	Unfilled int        `json:"unfilled"`
+	Seed     int64      `json:"seed"`
+}
+
+// mealCandidate is a meal with what generation needs to score it
+type mealCandidate struct {
+	ID          int    `db:"id"`
+	Name        string `db:"name"`
+	tags        map[string]bool
+	ingredients []string
+}
+
+func (req *PlanGenerationRequest) validate() error {
+	if req.StartDate.IsZero() || req.EndDate.IsZero() {
+		return fmt.Errorf("%w: start_date and end_date are required", ErrInvalidGeneration)
+	}
+	if err := ValidatePlan(&Plan{StartDate: req.StartDate, EndDate: req.EndDate}); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidGeneration, err)
+	}
+	if days := planDays(req.StartDate.Time, req.EndDate.Time); days > maxGeneratedDays {
+		return fmt.Errorf("%w: at most %d days can be generated at once", ErrInvalidGeneration, maxGeneratedDays)
+	}
+	if req.NoRepeatDays < 0 {
+		return fmt.Errorf("%w: no_repeat_days can't be negative", ErrInvalidGeneration)
+	}
+
+	req.Slots = normalizeList(req.Slots, normalizeName)
+	if len(req.Slots) == 0 {
+		req.Slots = []string{"dinner"}
+	}
+	req.Tags = normalizeList(req.Tags, normalizeName)
+	req.ExcludeTags = normalizeList(req.ExcludeTags, normalizeName)
+	return nil
+}
+
+// planDays counts the days from start to end inclusive
+func planDays(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// getMealCandidates loads every meal with its tags and ingredient names,
+// including the raw ingredients of the meal's recipes.
+func getMealCandidates(db *sqlx.DB) ([]*mealCandidate, error) {
+	meals := []*mealCandidate{}
+	if err := db.Select(&meals, `SELECT id, name FROM meals WHERE deleted_at IS NULL ORDER BY id`); err != nil {
+		return nil, err
+	}
+	byID := map[int]*mealCandidate{}
+	for _, m := range meals {
+		m.tags = map[string]bool{}
+		byID[m.ID] = m
+	}
+
+	rows := []struct {
+		MealID int    `db:"meal_id"`
+		Name   string `db:"name"`
+	}{}
+	err := db.Select(&rows, `SELECT mt.meal_id, t.name FROM meal_tags mt JOIN tags t ON t.id = mt.tag_id`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if m, ok := byID[row.MealID]; ok {
+			m.tags[normalizeName(row.Name)] = true
+		}
+	}
+
+	rows = rows[:0]
+	// A meal's recipes are followed down through the recipes their lines use
+	err = db.Select(&rows, `WITH RECURSIVE meal_recipe_tree (meal_id, recipe_id) AS (
+			SELECT meal_id, recipe_id FROM meal_recipes
+			UNION
+			SELECT t.meal_id, ri.sub_recipe_id FROM meal_recipe_tree t
+			JOIN recipe_ingredients ri ON ri.recipe_id = t.recipe_id
+			WHERE ri.sub_recipe_id IS NOT NULL
+		)
+		SELECT meal_id, name FROM meal_ingredients
+		UNION ALL
+		SELECT t.meal_id, ri.name FROM meal_recipe_tree t
+		JOIN recipe_ingredients ri ON ri.recipe_id = t.recipe_id
+		WHERE ri.sub_recipe_id IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if m, ok := byID[row.MealID]; ok {
+			m.ingredients = append(m.ingredients, row.Name)
+		}
+	}
+	return meals, nil
+}
+
+// recentMeals returns when each meal was last planned by the household before
+// start, looking back days days. Meals planned for a day count on that day;
+// for the others the plan's end date is used.
+func recentMeals(db *sqlx.DB, householdID int, start time.Time, days int) (map[int]time.Time, error) {
+	recent := map[int]time.Time{}
+	if days == 0 {
+		return recent, nil
+	}
+
+	rows := []struct {
+		MealID   int       `db:"meal_id"`
+		LastUsed time.Time `db:"last_used"`
+	}{}
+	err := db.Select(&rows, `SELECT pm.meal_id, MAX(COALESCE(pm.day, p.end_date)) AS last_used
+		FROM plan_meals pm JOIN plans p ON p.id = pm.plan_id
+		WHERE p.household_id=$1 AND p.deleted_at IS NULL
+			AND COALESCE(pm.day, p.end_date) >= $2 AND COALESCE(pm.day, p.start_date) < $3
+		GROUP BY pm.meal_id`, householdID, start.AddDate(0, 0, -days), start)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		recent[row.MealID] = row.LastUsed
+	}
+	return recent, nil
+}
+
+// GeneratePlan drafts a plan for the household, saving it when req.Save is
+// set. Meals that conflict with a member's dietary profile are never picked,
+// nor are meals needing equipment the household doesn't own, once it has
+// listed its equipment.
+func GeneratePlan(db *sqlx.DB, householdID int, req *PlanGenerationRequest) (*GeneratedPlan, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	if req.Seed == 0 {
+		req.Seed = time.Now().UnixNano()
+	}
+
+	candidates, err := getMealCandidates(db)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := GetDietaryProfiles(db, householdID)
+	if err != nil {
+		return nil, err
+	}
+	catalog := []IngredientCategories{}
+	if len(profiles) > 0 {
+		if catalog, err = GetIngredientCatalog(db); err != nil {
+			return nil, err
+		}
+	}
+	// Pantry items are matched against every candidate, so compile them once
+	pantry := []*regexp.Regexp{}
+	if req.UsePantry {
+		p, err := GetPantry(db, householdID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range p.Items {
+			pantry = append(pantry, wholeWord(normalizeName(item)))
+		}
+	}
+	owned, err := GetHouseholdEquipment(db, householdID)
+	if err != nil {
+		return nil, err
+	}
+	needs := map[int][]string{}
+	if len(owned) > 0 {
+		if needs, err = GetMealEquipment(db); err != nil {
+			return nil, err
+		}
+	}
+	lastUsed, err := recentMeals(db, householdID, req.StartDate.Time, req.NoRepeatDays)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := map[int]bool{}
+	for _, id := range req.ExcludeMeals {
+		excluded[id] = true
+	}
+	eligible := []*mealCandidate{}
+	weights := map[int]int{}
+	for _, m := range candidates {
+		if excluded[m.ID] || m.hasAnyTag(req.ExcludeTags) {
+			continue
+		}
+		if len(CheckIngredients(profiles, catalog, m.ingredients)) > 0 {
+			continue
+		}
+		if len(MissingEquipment(needs[m.ID], owned)) > 0 {
+			continue
+		}
+		eligible = append(eligible, m)
+		weights[m.ID] = m.weight(req.Tags, pantry)
+	}
+
+	rng := rand.New(rand.NewSource(req.Seed))
+	result := &GeneratedPlan{
+		Plan:  Plan{StartDate: req.StartDate, EndDate: req.EndDate, HouseholdID: householdID, Meals: []int{}},
+		Slots: []PlanSlot{},
+		Seed:  req.Seed,
+	}
+	days := planDays(req.StartDate.Time, req.EndDate.Time)
+	for d := 0; d < days; d++ {
+		day := req.StartDate.AddDate(0, 0, d)
+		for _, slot := range req.Slots {
+			pick := pickMeal(rng, eligible, weights, slot, func(m *mealCandidate) bool {
+				last, used := lastUsed[m.ID]
+				return !used || req.NoRepeatDays == 0 || day.Sub(last) >= time.Duration(req.NoRepeatDays)*24*time.Hour
+			})
+
+			planSlot := PlanSlot{Date: Date{Time: day}, Slot: slot}
+			if pick == nil {
+				result.Unfilled++
+			} else {
+				planSlot.MealID = pick.ID
+				planSlot.MealName = pick.Name
+				result.Plan.Meals = append(result.Plan.Meals, pick.ID)
+				result.Plan.Slots = append(result.Plan.Slots, planSlot)
+				lastUsed[pick.ID] = day
+			}
+			result.Slots = append(result.Slots, planSlot)
+		}
+	}
+
+	if req.Save {
+		plan, err := CreatePlan(db, &result.Plan, householdID)
+		if err != nil {
+			return nil, err
+		}
+		result.Plan = *plan
+	}
+	return result, nil
+}
+
+func (m *mealCandidate) hasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		if m.tags[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// weight makes meals with preferred tags, and meals using pantry items, more
+// likely to be picked.
+func (m *mealCandidate) weight(tags []string, pantry []*regexp.Regexp) int {
+	weight := 1
+	for _, tag := range tags {
+		if m.tags[tag] {
+			weight += 3
+		}
+	}
+	for _, mention := range pantry {
+		for _, ingredient := range m.ingredients {
+			if mention.MatchString(normalizeName(ingredient)) {
+				weight++
+				break
+			}
+		}
+	}
+	return weight
+}
+
+// pickMeal makes a weighted random choice among the allowed meals, keeping to
+// meals tagged with the slot's name if there are any.
+func pickMeal(rng *rand.Rand, meals []*mealCandidate, weights map[int]int, slot string, allowed func(*mealCandidate) bool) *mealCandidate {
+	options := []*mealCandidate{}
+	tagged := []*mealCandidate{}
+	for _, m := range meals {
+		if !allowed(m) {
+			continue
+		}
+		options = append(options, m)
+		if m.tags[slot] {
+			tagged = append(tagged, m)
+		}
+	}
+	if len(tagged) > 0 {
+		options = tagged
+	}
+	if len(options) == 0 {
+		return nil
+	}
+
+	// Candidates are loaded in id order, which keeps the draw reproducible
+	total := 0
+	for _, m := range options {
+		total += weights[m.ID]
+	}
+	n := rng.Intn(total)
+	for _, m := range options {
+		n -= weights[m.ID]
+		if n < 0 {
+			return m
+		}
+	}
+	return options[len(options)-1]
+}
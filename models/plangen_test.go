@@ -0,0 +1,141 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePlan(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Now().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	end := start.AddDate(0, 0, 2)
+
+	expectLibrary := func() {
+		mock.ExpectQuery("SELECT id, name FROM meals WHERE deleted_at IS NULL ORDER BY id").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+				AddRow(1, "Tacos").
+				AddRow(2, "Pancakes").
+				AddRow(3, "Curry").
+				AddRow(4, "Lasagna").
+				AddRow(5, "Chili"))
+		mock.ExpectQuery("SELECT mt.meal_id, t.name FROM meal_tags").
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}).
+				AddRow(2, "Breakfast").
+				AddRow(5, "spicy"))
+		mock.ExpectQuery("SELECT meal_id, name FROM meal_ingredients").
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}).
+				AddRow(1, "tortillas").
+				AddRow(3, "rice"))
+		mock.ExpectQuery("SELECT p.\\* FROM dietary_profiles").WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "allergens", "excluded_ingredients", "diets"}))
+		mock.ExpectQuery("SELECT equipment FROM households WHERE id=\\$1").WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"equipment"}).AddRow("{}"))
+		// Lasagna was on last week's plan
+		mock.ExpectQuery("SELECT pm.meal_id, MAX\\(COALESCE\\(pm.day, p.end_date\\)\\)").
+			WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "last_used"}).AddRow(4, start.AddDate(0, 0, -2)))
+	}
+
+	generate := func() *GeneratedPlan {
+		expectLibrary()
+		generated, err := GeneratePlan(db, 42, &PlanGenerationRequest{
+			StartDate:    Date{Time: start},
+			EndDate:      Date{Time: end},
+			ExcludeTags:  []string{"Spicy"},
+			NoRepeatDays: 7,
+			Seed:         99,
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+		return generated
+	}
+
+	first := generate()
+	assert.Equal(t, int64(99), first.Seed)
+	assert.Zero(t, first.Plan.ID)
+	require.Len(t, first.Slots, 3)
+	assert.Equal(t, 0, first.Unfilled)
+	// Chili is excluded by tag and Lasagna was planned too recently, leaving
+	// three meals for three days
+	assert.ElementsMatch(t, []int{1, 2, 3}, first.Plan.Meals)
+	for i, slot := range first.Slots {
+		assert.Equal(t, "dinner", slot.Slot)
+		assert.Equal(t, start.AddDate(0, 0, i), slot.Date.Time)
+	}
+
+	second := generate()
+	assert.Equal(t, first.Slots, second.Slots, "the same seed gives the same plan")
+}
+
+func TestGeneratePlanSlots(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Now().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+
+	mock.ExpectQuery("SELECT id, name FROM meals WHERE deleted_at IS NULL ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tacos").AddRow(2, "Pancakes"))
+	mock.ExpectQuery("SELECT mt.meal_id, t.name FROM meal_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}).AddRow(2, "breakfast"))
+	mock.ExpectQuery("SELECT meal_id, name FROM meal_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "name"}))
+	mock.ExpectQuery("SELECT p.\\* FROM dietary_profiles").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "allergens", "excluded_ingredients", "diets"}))
+	mock.ExpectQuery("SELECT equipment FROM households WHERE id=\\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"equipment"}).AddRow("{}"))
+	mock.ExpectQuery("SELECT pm.meal_id, MAX\\(COALESCE\\(pm.day, p.end_date\\)\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "last_used"}))
+
+	generated, err := GeneratePlan(db, 42, &PlanGenerationRequest{
+		StartDate:    Date{Time: start},
+		EndDate:      Date{Time: start.AddDate(0, 0, 1)},
+		Slots:        []string{"Breakfast", "dinner"},
+		NoRepeatDays: 2,
+		Seed:         1,
+	})
+	require.NoError(t, err)
+	require.Len(t, generated.Slots, 4)
+
+	// Pancakes are tagged for breakfast, and nothing may repeat the next day
+	assert.Equal(t, PlanSlot{Date: Date{Time: start}, Slot: "breakfast", MealID: 2, MealName: "Pancakes"}, generated.Slots[0])
+	assert.Equal(t, 1, generated.Slots[1].MealID)
+	assert.Zero(t, generated.Slots[2].MealID)
+	assert.Zero(t, generated.Slots[3].MealID)
+	assert.Equal(t, 2, generated.Unfilled)
+	assert.Equal(t, []int{2, 1}, generated.Plan.Meals)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGeneratePlanValidation(t *testing.T) {
+	db, _ := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Now().AddDate(0, 0, 1)
+	tests := []PlanGenerationRequest{
+		{},
+		{StartDate: Date{Time: start}, EndDate: Date{Time: start.AddDate(0, 0, -1)}},
+		{StartDate: Date{Time: start}, EndDate: Date{Time: start.AddDate(0, 0, 60)}},
+		{StartDate: Date{Time: start}, EndDate: Date{Time: start}, NoRepeatDays: -1},
+	}
+	for _, req := range tests {
+		_, err := GeneratePlan(db, 42, &req)
+		assert.ErrorIs(t, err, ErrInvalidGeneration)
+	}
+}
+
+func TestMealCandidateWeight(t *testing.T) {
+	m := &mealCandidate{ID: 1, Name: "Curry", tags: map[string]bool{"dinner": true}, ingredients: []string{"Basmati Rice", "chickpeas", "rice vinegar"}}
+	pantry := []*regexp.Regexp{wholeWord(normalizeName("rice")), wholeWord(normalizeName("chickpea")), wholeWord(normalizeName("pasta"))}
+
+	assert.Equal(t, 1, m.weight(nil, nil))
+	// Each pantry item counts once, however many ingredients mention it
+	assert.Equal(t, 3, m.weight(nil, pantry))
+	assert.Equal(t, 6, m.weight([]string{"dinner", "lunch"}, pantry))
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"time"
 
@@ -37,17 +38,26 @@ func (t Date) Value() (driver.Value, error) {
 }
 
 type Plan struct {
-	ID          int   `db:"id" json:"id"`
-	StartDate   Date  `db:"start_date" json:"start_date"`
-	EndDate     Date  `db:"end_date" json:"end_date"`
-	HouseholdID int   `db:"household_id" json:"household_id"`
-	Meals       []int `json:"meals,omitempty"`
+	ID          int              `db:"id" json:"id"`
+	StartDate   Date             `db:"start_date" json:"start_date"`
+	EndDate     Date             `db:"end_date" json:"end_date"`
+	HouseholdID int              `db:"household_id" json:"household_id"`
+	Archived    bool             `db:"archived" json:"archived"`
+	DeletedAt   *time.Time       `db:"deleted_at" json:"deleted_at,omitempty"`
+	Meals       []int            `json:"meals,omitempty"`
+	Slots       []PlanSlot       `json:"slots,omitempty"`
+	Warnings    []DietaryWarning `db:"-" json:"warnings,omitempty"`
+	Cost        *PlanCostReport  `db:"-" json:"cost,omitempty"`
 }
 
+// PlanMeals is a meal on a plan. Day and Slot are only set for meals that
+// were planned for a particular day.
 type PlanMeals struct {
-	ID     int `db:"id" json:"id"`
-	PlanID int `db:"plan_id" json:"plan_id"`
-	MealID int `db:"meal_id" json:"meal_id"`
+	ID     int     `db:"id" json:"id"`
+	PlanID int     `db:"plan_id" json:"plan_id"`
+	MealID int     `db:"meal_id" json:"meal_id"`
+	Day    *Date   `db:"day" json:"day,omitempty"`
+	Slot   *string `db:"slot" json:"slot,omitempty"`
 }
 
 type Ingredient struct {
@@ -55,9 +65,16 @@ type Ingredient struct {
 	Amount string `db:"amount" json:"amount"`
 }
 
-func GetPlans(db *sqlx.DB, householdID int) (*[]Plan, error) {
+// GetPlans lists the household's plans, leaving out archived plans unless
+// includeArchived is set
+func GetPlans(db *sqlx.DB, householdID int, includeArchived bool) (*[]Plan, error) {
+	query := "SELECT * FROM plans WHERE household_id = $1 AND deleted_at IS NULL AND NOT archived ORDER BY start_date ASC"
+	if includeArchived {
+		query = "SELECT * FROM plans WHERE household_id = $1 AND deleted_at IS NULL ORDER BY start_date ASC"
+	}
+
 	plans := []Plan{}
-	err := db.Select(&plans, "SELECT * FROM plans WHERE household_id = $1 ORDER BY start_date ASC", householdID)
+	err := db.Select(&plans, query, householdID)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -68,7 +85,7 @@ func GetPlans(db *sqlx.DB, householdID int) (*[]Plan, error) {
 
 func GetLastPlan(db *sqlx.DB, householdID int) (*Plan, error) {
 	plan := Plan{}
-	err := db.Get(&plan, "SELECT * FROM plans WHERE household_id=$1 ORDER BY start_date DESC LIMIT 1", householdID)
+	err := db.Get(&plan, "SELECT * FROM plans WHERE household_id=$1 AND deleted_at IS NULL ORDER BY start_date DESC LIMIT 1", householdID)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -79,7 +96,7 @@ func GetLastPlan(db *sqlx.DB, householdID int) (*Plan, error) {
 
 func GetNextPlan(db *sqlx.DB, householdID int) (*Plan, error) {
 	plan := Plan{}
-	err := db.Get(&plan, "SELECT * FROM plans WHERE household_id=$1 AND start_date > NOW() ORDER BY start_date ASC LIMIT 1", householdID)
+	err := db.Get(&plan, "SELECT * FROM plans WHERE household_id=$1 AND start_date > NOW() AND deleted_at IS NULL ORDER BY start_date ASC LIMIT 1", householdID)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -106,27 +123,110 @@ func GetPlan(db *sqlx.DB, id int) (*Plan, error) {
 	plan.Meals = make([]int, len(planMeals))
 	for i, pm := range planMeals {
 		plan.Meals[i] = pm.MealID
+		if pm.Day != nil {
+			slot := PlanSlot{Date: *pm.Day, MealID: pm.MealID}
+			if pm.Slot != nil {
+				slot.Slot = *pm.Slot
+			}
+			plan.Slots = append(plan.Slots, slot)
+		}
 	}
 
 	return &plan, nil
 }
 
-func ValidatePlan(p *Plan) error {
+var ErrInvalidPlanDates = errors.New("invalid plan dates")
+var ErrPlanArchived = errors.New("plan is archived")
+var ErrPlanEnded = errors.New("plan has ended")
+var ErrPlanNotEnded = errors.New("only plans that have ended can be archived")
+
+// PlanOptions relax the checks made when creating or editing a plan
+type PlanOptions struct {
+	// AllowPast permits plans in the past, for logging meals already eaten
+	// and correcting plans that have ended
+	AllowPast bool
+}
+
+// startOfToday returns midnight at the start of the current day
+func startOfToday() time.Time {
 	now := time.Now()
-	// Get the beginning of today (00:00:00)
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
 
-	if p.StartDate.Before(todayStart) || p.EndDate.Before(todayStart) {
-		return fmt.Errorf("start date and end date must be in the future")
+func ValidatePlan(p *Plan) error {
+	return ValidatePlanWithOptions(p, PlanOptions{})
+}
+
+func ValidatePlanWithOptions(p *Plan, opts PlanOptions) error {
+	todayStart := startOfToday()
+
+	if !opts.AllowPast && (p.StartDate.Before(todayStart) || p.EndDate.Before(todayStart)) {
+		return fmt.Errorf("%w: start date and end date must be in the future", ErrInvalidPlanDates)
 	} else if p.StartDate.After(p.EndDate.Time) {
-		return fmt.Errorf("start date must be before end date")
+		return fmt.Errorf("%w: start date must be before end date", ErrInvalidPlanDates)
 	}
 
 	return nil
 }
 
+// PlanEnded reports whether the plan's last day is before today
+func PlanEnded(p *Plan) bool {
+	return p.EndDate.Before(startOfToday())
+}
+
+// CanEditPlan checks that an existing plan may be changed. Plans in progress
+// can be edited freely; plans that have ended only with AllowPast, and
+// archived plans not at all.
+func CanEditPlan(p *Plan, opts PlanOptions) error {
+	if p.Archived {
+		return ErrPlanArchived
+	}
+	if PlanEnded(p) && !opts.AllowPast {
+		return fmt.Errorf("%w; pass past=true to correct it", ErrPlanEnded)
+	}
+	return nil
+}
+
+// SetPlanArchived archives or restores a plan. Only plans that have ended can
+// be archived.
+func SetPlanArchived(db *sqlx.DB, p *Plan, archived bool) (*Plan, error) {
+	if archived && !PlanEnded(p) {
+		return nil, ErrPlanNotEnded
+	}
+	if _, err := db.Exec("UPDATE plans SET archived=$1 WHERE id=$2", archived, p.ID); err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	return GetPlan(db, p.ID)
+}
+
+// PlanOverlapError is returned when a plan's dates overlap the household's
+// existing plans, which are listed in Plans.
+type PlanOverlapError struct {
+	Plans []Plan
+}
+
+func (e *PlanOverlapError) Error() string {
+	return fmt.Sprintf("plan overlaps %d existing plan(s)", len(e.Plans))
+}
+
+// overlappingPlansQuery finds a household's plans sharing at least one day
+// with the range $2..$3, other than plan $4
+const overlappingPlansQuery = `SELECT * FROM plans WHERE household_id=$1 AND start_date <= $3 AND end_date >= $2 AND id <> $4 AND deleted_at IS NULL ORDER BY start_date`
+
+// GetOverlappingPlans returns the household's plans sharing a day with p
+func GetOverlappingPlans(db *sqlx.DB, householdID int, p *Plan) ([]Plan, error) {
+	plans := []Plan{}
+	err := db.Select(&plans, overlappingPlansQuery, householdID, p.StartDate, p.EndDate, p.ID)
+	return plans, err
+}
+
 func CreatePlan(db *sqlx.DB, p *Plan, householdID int) (*Plan, error) {
-	if err := ValidatePlan(p); err != nil {
+	return CreatePlanWithOptions(db, p, householdID, PlanOptions{})
+}
+
+func CreatePlanWithOptions(db *sqlx.DB, p *Plan, householdID int, opts PlanOptions) (*Plan, error) {
+	if err := ValidatePlanWithOptions(p, opts); err != nil {
 		return nil, err
 	}
 
@@ -138,6 +238,18 @@ func CreatePlan(db *sqlx.DB, p *Plan, householdID int) (*Plan, error) {
 		return nil, err
 	}
 
+	overlaps := []Plan{}
+	err = tx.Select(&overlaps, overlappingPlansQuery, p.HouseholdID, p.StartDate, p.EndDate, 0)
+	if err != nil {
+		tx.Rollback()
+		fmt.Println(err)
+		return nil, err
+	}
+	if len(overlaps) > 0 {
+		tx.Rollback()
+		return nil, &PlanOverlapError{Plans: overlaps}
+	}
+
 	_, err = tx.Exec("INSERT INTO plans (start_date, end_date, household_id) VALUES ($1, $2, $3)", p.StartDate, p.EndDate, p.HouseholdID)
 	if err != nil {
 		tx.Rollback()
@@ -145,7 +257,7 @@ func CreatePlan(db *sqlx.DB, p *Plan, householdID int) (*Plan, error) {
 		return nil, err
 	}
 
-	err = tx.Get(&p.ID, "SELECT id FROM plans WHERE start_date=$1 AND household_id=$2", p.StartDate, p.HouseholdID)
+	err = tx.Get(&p.ID, "SELECT id FROM plans WHERE start_date=$1 AND household_id=$2 AND deleted_at IS NULL", p.StartDate, p.HouseholdID)
 	if err != nil {
 		tx.Rollback()
 		fmt.Println(err)
@@ -156,7 +268,14 @@ func CreatePlan(db *sqlx.DB, p *Plan, householdID int) (*Plan, error) {
 	return UpdatePlan(db, p.ID, p)
 }
 
-func UpdatePlan(db *sqlx.DB, id int, p *Plan) (*Plan, error) {
+// MergePlan folds p into the household's plans that overlap it, in one
+// transaction. The earliest of them is stretched to cover all of their days
+// and takes every meal; the others are moved to the trash. Without overlaps,
+// p is simply created.
+func MergePlan(db *sqlx.DB, p *Plan, householdID int, opts PlanOptions) (*Plan, error) {
+	if err := ValidatePlanWithOptions(p, opts); err != nil {
+		return nil, err
+	}
 
 	tx, err := db.Beginx()
 	if err != nil {
@@ -164,21 +283,123 @@ func UpdatePlan(db *sqlx.DB, id int, p *Plan) (*Plan, error) {
 		return nil, err
 	}
 
-	_, err = tx.Exec("DELETE FROM plan_meals WHERE plan_id=$1", id)
+	// Lock the overlapping plans so they can't change until the merge is done
+	overlaps := []Plan{}
+	err = tx.Select(&overlaps, overlappingPlansQuery+" FOR UPDATE", householdID, p.StartDate, p.EndDate, p.ID)
 	if err != nil {
 		tx.Rollback()
 		fmt.Println(err)
 		return nil, err
 	}
+	if len(overlaps) == 0 {
+		tx.Rollback()
+		return CreatePlanWithOptions(db, p, householdID, opts)
+	}
 
-	for _, meal := range p.Meals {
-		_, err = tx.Exec("INSERT INTO plan_meals (plan_id, meal_id) VALUES ($1, $2)", id, meal)
+	merged := &Plan{ID: overlaps[0].ID, StartDate: p.StartDate, EndDate: p.EndDate, HouseholdID: householdID}
+	undated := []int{}
+	seenMeals := map[int]bool{}
+	seenSlots := map[PlanSlot]bool{}
+	addMeals := func(plan *Plan) {
+		for _, slot := range plan.Slots {
+			slot.MealName = ""
+			if !seenSlots[slot] {
+				seenSlots[slot] = true
+				merged.Slots = append(merged.Slots, slot)
+				merged.Meals = append(merged.Meals, slot.MealID)
+			}
+		}
+		for _, meal := range undatedMeals(plan) {
+			if !seenMeals[meal] {
+				seenMeals[meal] = true
+				undated = append(undated, meal)
+			}
+		}
+	}
+	for _, overlap := range overlaps {
+		existing, err := GetPlan(db, overlap.ID)
 		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if existing.StartDate.Before(merged.StartDate.Time) {
+			merged.StartDate = existing.StartDate
+		}
+		if existing.EndDate.After(merged.EndDate.Time) {
+			merged.EndDate = existing.EndDate
+		}
+		addMeals(existing)
+	}
+	addMeals(p)
+	merged.Meals = append(merged.Meals, undated...)
+
+	// The other plans keep their meals in the trash, so they can be restored
+	// if the merged plan is deleted
+	for _, overlap := range overlaps[1:] {
+		if _, err := tx.Exec("UPDATE plans SET deleted_at=NOW() WHERE id=$1", overlap.ID); err != nil {
 			tx.Rollback()
 			fmt.Println(err)
 			return nil, err
 		}
 	}
+	_, err = tx.Exec("UPDATE plans SET start_date=$1, end_date=$2 WHERE id=$3", merged.StartDate, merged.EndDate, merged.ID)
+	if err != nil {
+		tx.Rollback()
+		fmt.Println(err)
+		return nil, err
+	}
+	if err := writePlanMeals(tx, merged.ID, merged); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	return GetPlan(db, merged.ID)
+}
+
+// MealIDs lists each meal of p once, whether it is in Meals or in Slots
+func (p *Plan) MealIDs() []int {
+	ids := append([]int{}, p.Meals...)
+	for _, slot := range p.Slots {
+		if slot.MealID != 0 {
+			ids = append(ids, slot.MealID)
+		}
+	}
+	return uniqueInts(ids)
+}
+
+// undatedMeals lists the meals of p that aren't planned for a particular day
+func undatedMeals(p *Plan) []int {
+	dated := map[int]int{}
+	for _, slot := range p.Slots {
+		dated[slot.MealID]++
+	}
+	meals := []int{}
+	for _, meal := range p.Meals {
+		if dated[meal] > 0 {
+			dated[meal]--
+			continue
+		}
+		meals = append(meals, meal)
+	}
+	return meals
+}
+
+func UpdatePlan(db *sqlx.DB, id int, p *Plan) (*Plan, error) {
+
+	tx, err := db.Beginx()
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	if err := writePlanMeals(tx, id, p); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	err = tx.Commit()
 	if err != nil {
@@ -189,7 +410,40 @@ func UpdatePlan(db *sqlx.DB, id int, p *Plan) (*Plan, error) {
 	return GetPlan(db, id)
 }
 
-func DeletePlan(db *sqlx.DB, id int) error {
+// writePlanMeals replaces plan id's meals with p's inside tx
+func writePlanMeals(tx *sqlx.Tx, id int, p *Plan) error {
+	_, err := tx.Exec("DELETE FROM plan_meals WHERE plan_id=$1", id)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	// Meals given in Slots are saved with their day. Meals also listed in
+	// Slots are not added a second time; the rest belong to the plan as a
+	// whole.
+	for _, slot := range p.Slots {
+		if slot.MealID == 0 {
+			continue
+		}
+		_, err = tx.Exec("INSERT INTO plan_meals (plan_id, meal_id, day, slot) VALUES ($1, $2, $3, $4)", id, slot.MealID, slot.Date, slot.Slot)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+	}
+
+	for _, meal := range undatedMeals(p) {
+		_, err = tx.Exec("INSERT INTO plan_meals (plan_id, meal_id) VALUES ($1, $2)", id, meal)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// purgePlan permanently deletes a plan
+func purgePlan(db *sqlx.DB, id int) error {
 	tx, err := db.Beginx()
 	if err != nil {
 		fmt.Println(err)
@@ -219,9 +473,11 @@ func DeletePlan(db *sqlx.DB, id int) error {
 	return nil
 }
 
+// GetPlanIngredients lists the ingredients of a plan's meals, leaving out
+// meals in the trash
 func GetPlanIngredients(db *sqlx.DB, id int, householdID int) (*[]Ingredient, error) {
 	ingredients := []Ingredient{}
-	err := db.Select(&ingredients, "SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE pm.plan_id=$1 AND pm.household_id=$2", id, householdID)
+	err := db.Select(&ingredients, "SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p ON p.id = pm.plan_id JOIN meals m ON m.id = pm.meal_id WHERE pm.plan_id=$1 AND p.household_id=$2 AND m.deleted_at IS NULL ORDER BY pm.id, i.position, i.id", id, householdID)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -233,7 +489,7 @@ func GetPlanIngredients(db *sqlx.DB, id int, householdID int) (*[]Ingredient, er
 func GetFuturePlans(db *sqlx.DB, householdID int) (*[]Plan, error) {
 	plans := []Plan{}
 	plan_ids := []int{}
-	err := db.Select(&plan_ids, "SELECT id FROM plans WHERE end_date > NOW() AND household_id=$1 ORDER BY start_date ASC", householdID)
+	err := db.Select(&plan_ids, "SELECT id FROM plans WHERE end_date > NOW() AND household_id=$1 AND deleted_at IS NULL ORDER BY start_date ASC", householdID)
 	if err != nil {
 		fmt.Println("Error fetching plan IDs:", err)
 		return nil, err
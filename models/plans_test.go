@@ -9,6 +9,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Helper function to create a mock sqlx database
@@ -78,7 +79,7 @@ func TestGetFuturePlans(t *testing.T) {
 	planIDsRows := sqlmock.NewRows([]string{"id"}).
 		AddRow(1).
 		AddRow(2)
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM plans WHERE end_date > NOW() AND household_id=$1 ORDER BY start_date ASC")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM plans WHERE end_date > NOW() AND household_id=$1 AND deleted_at IS NULL ORDER BY start_date ASC")).
 		WithArgs(householdID).
 		WillReturnRows(planIDsRows)
 
@@ -127,12 +128,13 @@ func TestGetPlanIngredients(t *testing.T) {
 	planID := 1
 	householdID := 42
 
-	// Mock the ingredients query
+	// Mock the ingredients query; plan_meals has no household_id, the
+	// household is the plan's
 	ingredientsRows := sqlmock.NewRows([]string{"name", "amount"}).
 		AddRow("Flour", "2 cups").
 		AddRow("Sugar", "1 cup").
 		AddRow("Eggs", "2")
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE pm.plan_id=$1 AND pm.household_id=$2")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p ON p.id = pm.plan_id JOIN meals m ON m.id = pm.meal_id WHERE pm.plan_id=$1 AND p.household_id=$2 AND m.deleted_at IS NULL")).
 		WithArgs(planID, householdID).
 		WillReturnRows(ingredientsRows)
 
@@ -150,6 +152,28 @@ func TestGetPlanIngredients(t *testing.T) {
 	}
 }
 
+func TestGetPlanIngredientsUsesMealIngredientsOnly(t *testing.T) {
+	sqlxDB, mock, err := newMockDB()
+	if err != nil {
+		t.Fatalf("Error creating mock db: %v", err)
+	}
+	defer sqlxDB.Close()
+
+	// The meal's ingredients already repeat its pancake recipe's, so the
+	// recipe must not be expanded a second time
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i")).
+		WithArgs(1, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "amount"}).
+			AddRow("Flour", "2 cups").
+			AddRow("Milk", "1 cup").
+			AddRow("Maple syrup", "1/4 cup"))
+
+	ingredients, err := GetPlanIngredients(sqlxDB, 1, 42)
+	require.NoError(t, err)
+	assert.Len(t, *ingredients, 3)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestCreatePlan(t *testing.T) {
 	sqlxDB, mock, err := newMockDB()
 	if err != nil {
@@ -170,6 +194,9 @@ func TestCreatePlan(t *testing.T) {
 
 	// Mock the database interactions for CreatePlan
 	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3").
+		WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}))
 	mock.ExpectExec("INSERT INTO plans").
 		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 42).
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -342,7 +369,7 @@ func TestUpdatePlan(t *testing.T) {
 	}
 }
 
-func TestDeletePlan(t *testing.T) {
+func TestPurgePlan(t *testing.T) {
 	sqlxDB, mock, err := newMockDB()
 	if err != nil {
 		t.Fatalf("Error creating mock db: %v", err)
@@ -351,7 +378,7 @@ func TestDeletePlan(t *testing.T) {
 
 	planID := 1
 
-	// Mock the transaction for DeletePlan
+	// Mock the transaction for purgePlan
 	mock.ExpectBegin()
 	mock.ExpectExec("DELETE FROM plan_meals WHERE plan_id=\\$1").
 		WithArgs(planID).
@@ -362,7 +389,7 @@ func TestDeletePlan(t *testing.T) {
 	mock.ExpectCommit()
 
 	// Execute the function
-	err = DeletePlan(sqlxDB, planID)
+	err = purgePlan(sqlxDB, planID)
 
 	// Assertions
 	assert.NoError(t, err)
@@ -411,3 +438,133 @@ func TestDateMethods(t *testing.T) {
 		assert.Equal(t, driver.Value(timeVal), val)
 	})
 }
+
+func TestCreatePlanOverlap(t *testing.T) {
+	sqlxDB, mock, err := newMockDB()
+	require.NoError(t, err)
+	defer sqlxDB.Close()
+
+	start := time.Now().AddDate(0, 0, 3)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3").
+		WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(9, start.AddDate(0, 0, -2), start.AddDate(0, 0, 4), 42))
+	mock.ExpectRollback()
+
+	_, err = CreatePlan(sqlxDB, &Plan{StartDate: Date{Time: start}, EndDate: Date{Time: start.AddDate(0, 0, 6)}}, 42)
+	var overlap *PlanOverlapError
+	require.ErrorAs(t, err, &overlap)
+	require.Len(t, overlap.Plans, 1)
+	assert.Equal(t, 9, overlap.Plans[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergePlan(t *testing.T) {
+	sqlxDB, mock, err := newMockDB()
+	require.NoError(t, err)
+	defer sqlxDB.Close()
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 3)
+	planColumns := []string{"id", "start_date", "end_date", "household_id"}
+
+	// Plans 9 and 10 both overlap the new plan
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3 .* FOR UPDATE").
+		WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows(planColumns).
+			AddRow(9, start.AddDate(0, 0, -2), start.AddDate(0, 0, 1), 42).
+			AddRow(10, start.AddDate(0, 0, 5), start.AddDate(0, 0, 8), 42))
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(planColumns).AddRow(9, start.AddDate(0, 0, -2), start.AddDate(0, 0, 1), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}).AddRow(1, 9, 101))
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(10).
+		WillReturnRows(sqlmock.NewRows(planColumns).AddRow(10, start.AddDate(0, 0, 5), start.AddDate(0, 0, 8), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}).AddRow(2, 10, 102))
+
+	// Plan 10 goes to the trash with its meals
+	mock.ExpectExec("UPDATE plans SET deleted_at=NOW\\(\\) WHERE id=\\$1").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE plans SET start_date=\\$1, end_date=\\$2 WHERE id=\\$3").
+		WithArgs(Date{Time: start.AddDate(0, 0, -2)}, Date{Time: start.AddDate(0, 0, 8)}, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// The merged plan keeps each meal once
+	mock.ExpectExec("DELETE FROM plan_meals WHERE plan_id=\\$1").WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, meal := range []int{101, 102, 103} {
+		mock.ExpectExec("INSERT INTO plan_meals").WithArgs(9, meal).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(planColumns).AddRow(9, start.AddDate(0, 0, -2), start.AddDate(0, 0, 8), 42))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}).AddRow(1, 9, 101).AddRow(2, 9, 102).AddRow(3, 9, 103))
+
+	plan, err := MergePlan(sqlxDB, &Plan{
+		StartDate: Date{Time: start},
+		EndDate:   Date{Time: start.AddDate(0, 0, 6)},
+		Meals:     []int{101, 103},
+	}, 42, PlanOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 9, plan.ID)
+	assert.Equal(t, []int{101, 102, 103}, plan.Meals)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlanOptions(t *testing.T) {
+	now := time.Now()
+	lastWeek := &Plan{
+		StartDate: Date{Time: now.AddDate(0, 0, -8)},
+		EndDate:   Date{Time: now.AddDate(0, 0, -2)},
+	}
+	inProgress := &Plan{
+		StartDate: Date{Time: now.AddDate(0, 0, -2)},
+		EndDate:   Date{Time: now.AddDate(0, 0, 4)},
+	}
+
+	assert.ErrorIs(t, ValidatePlan(lastWeek), ErrInvalidPlanDates)
+	assert.NoError(t, ValidatePlanWithOptions(lastWeek, PlanOptions{AllowPast: true}))
+	assert.ErrorIs(t, ValidatePlanWithOptions(&Plan{StartDate: lastWeek.EndDate, EndDate: lastWeek.StartDate}, PlanOptions{AllowPast: true}), ErrInvalidPlanDates)
+
+	assert.NoError(t, CanEditPlan(inProgress, PlanOptions{}))
+	assert.ErrorIs(t, CanEditPlan(lastWeek, PlanOptions{}), ErrPlanEnded)
+	assert.NoError(t, CanEditPlan(lastWeek, PlanOptions{AllowPast: true}))
+	archived := *lastWeek
+	archived.Archived = true
+	assert.Equal(t, ErrPlanArchived, CanEditPlan(&archived, PlanOptions{AllowPast: true}))
+}
+
+func TestSetPlanArchived(t *testing.T) {
+	sqlxDB, mock, err := newMockDB()
+	require.NoError(t, err)
+	defer sqlxDB.Close()
+
+	now := time.Now()
+	_, err = SetPlanArchived(sqlxDB, &Plan{ID: 1, StartDate: Date{Time: now}, EndDate: Date{Time: now.AddDate(0, 0, 6)}}, true)
+	assert.Equal(t, ErrPlanNotEnded, err)
+
+	ended := &Plan{ID: 2, StartDate: Date{Time: now.AddDate(0, 0, -9)}, EndDate: Date{Time: now.AddDate(0, 0, -3)}}
+	mock.ExpectExec("UPDATE plans SET archived=\\$1 WHERE id=\\$2").WithArgs(true, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id", "archived"}).
+			AddRow(2, ended.StartDate.Time, ended.EndDate.Time, 42, true))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+
+	plan, err := SetPlanArchived(sqlxDB, ended, true)
+	require.NoError(t, err)
+	assert.True(t, plan.Archived)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlanMealIDs(t *testing.T) {
+	plan := &Plan{
+		Meals: []int{3, 1},
+		Slots: []PlanSlot{{Slot: "dinner", MealID: 1}, {Slot: "lunch", MealID: 5}, {Slot: "breakfast"}},
+	}
+	assert.Equal(t, []int{3, 1, 5}, plan.MealIDs())
+	assert.Empty(t, (&Plan{}).MealIDs())
+}
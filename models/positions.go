@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// MoveRecipeIngredient moves one ingredient line of a recipe to position
+// (counted from 1) and renumbers the rest. Positions past the end move the
+// line to the end. The recipe as it was becomes a revision.
+func MoveRecipeIngredient(db *sqlx.DB, id int, ingredientID int, position int) (*Recipe, error) {
+	previous, err := GetRecipe(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := moveIngredient(db, "recipe_ingredients", "recipe_id", id, ingredientID, position, previous); err != nil {
+		return nil, err
+	}
+	return GetRecipe(db, id)
+}
+
+// MoveMealIngredient moves one ingredient line of a meal, like
+// MoveRecipeIngredient.
+func MoveMealIngredient(db *sqlx.DB, id int, ingredientID int, position int) (*Meal, error) {
+	previous, err := GetMeal(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := moveIngredient(db, "meal_ingredients", "meal_id", id, ingredientID, position, previous); err != nil {
+		return nil, err
+	}
+	return GetMeal(db, id)
+}
+
+// moveIngredient renumbers the lines in table belonging to id in column with
+// ingredientID at position. It returns sql.ErrNoRows when the line isn't
+// one of them.
+func moveIngredient(db *sqlx.DB, table string, column string, id int, ingredientID int, position int, previous interface{}) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	ids := []int{}
+	if err := tx.Select(&ids, `SELECT id FROM `+table+` WHERE `+column+`=$1 ORDER BY position, id FOR UPDATE`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	ordered, ok := moveID(ids, ingredientID, position)
+	if !ok {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+
+	if err := saveRevision(tx, column, id, previous); err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec(`UPDATE `+table+` t SET position = p.position
+		FROM UNNEST($1::int[]) WITH ORDINALITY AS p(id, position)
+		WHERE t.id = p.id`, pq.Array(ordered))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// moveID returns ids with id moved to position, counted from 1 and clamped
+// to the list. ok is false when id isn't in the list.
+func moveID(ids []int, id int, position int) (ordered []int, ok bool) {
+	ordered = make([]int, 0, len(ids))
+	for _, other := range ids {
+		if other == id {
+			ok = true
+			continue
+		}
+		ordered = append(ordered, other)
+	}
+	if !ok {
+		return nil, false
+	}
+	position = min(max(position, 1), len(ids))
+	ordered = append(ordered[:position-1], append([]int{id}, ordered[position-1:]...)...)
+	return ordered, true
+}
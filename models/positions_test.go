@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveID(t *testing.T) {
+	ids := []int{10, 11, 12, 13}
+
+	ordered, ok := moveID(ids, 13, 1)
+	assert.True(t, ok)
+	assert.Equal(t, []int{13, 10, 11, 12}, ordered)
+
+	ordered, ok = moveID(ids, 10, 3)
+	assert.True(t, ok)
+	assert.Equal(t, []int{11, 12, 10, 13}, ordered)
+
+	ordered, ok = moveID(ids, 11, 99)
+	assert.True(t, ok)
+	assert.Equal(t, []int{10, 12, 13, 11}, ordered)
+
+	_, ok = moveID(ids, 20, 1)
+	assert.False(t, ok)
+}
+
+func TestMoveRecipeIngredient(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	columns := []string{"id", "name", "description", "slug"}
+	expectRecipe(mock, sqlmock.NewRows(columns).AddRow(1, "Stew", "Hearty", "stew"), 1)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM recipe_ingredients WHERE recipe_id=\\$1 ORDER BY position, id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11).AddRow(12))
+	mock.ExpectExec("INSERT INTO revisions \\(recipe_id, revision, snapshot\\)").
+		WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE recipe_ingredients t SET position = p.position").
+		WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	expectRecipe(mock, sqlmock.NewRows(columns).AddRow(1, "Stew", "Hearty", "stew"), 1)
+
+	recipe, err := MoveRecipeIngredient(db, 1, 12, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recipe.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveMealIngredientNotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug"}).AddRow(1, "Dinner", "Weeknight", "dinner"))
+	mock.ExpectQuery("SELECT \\* FROM meal_ingredients").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "meal_id", "name", "amount"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_steps").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "meal_id", "text", "order"}))
+	mock.ExpectQuery("SELECT \\* FROM meal_recipes").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectRollback()
+
+	_, err := MoveMealIngredient(db, 1, 99, 1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
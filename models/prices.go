@@ -0,0 +1,345 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvalidPrice  = errors.New("invalid price")
+	ErrInvalidBudget = errors.New("invalid budget")
+)
+
+// IngredientPrice is a price point: what a household paid for Amount Unit
+// of an ingredient at a store. Points are never changed, so the older ones
+// are the ingredient's price history.
+type IngredientPrice struct {
+	ID          int       `db:"id" json:"id"`
+	HouseholdID int       `db:"household_id" json:"household_id"`
+	Name        string    `db:"name" json:"name"`
+	Store       string    `db:"store" json:"store"`
+	Amount      float64   `db:"amount" json:"amount"`
+	Unit        string    `db:"unit" json:"unit"`
+	Price       float64   `db:"price" json:"price"`
+	ObservedOn  Date      `db:"observed_on" json:"observed_on"`
+	ReceiptID   *int      `db:"receipt_id" json:"receipt_id,omitempty"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// CostSummary is attached to recipes and meals for households that record
+// prices. Unpriced lists the ingredients without a price that converts to
+// their amount, so the totals are known to be incomplete.
+type CostSummary struct {
+	Servings   int      `json:"servings"`
+	Total      float64  `json:"total"`
+	PerServing float64  `json:"per_serving"`
+	Unpriced   []string `json:"unpriced"`
+}
+
+func (p *IngredientPrice) validate() error {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	p.Store = strings.TrimSpace(p.Store)
+	p.Unit = NormalizeUnit(p.Unit)
+	if p.Amount == 0 {
+		p.Amount = 1
+	}
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPrice)
+	}
+	if p.Amount < 0 || p.Price < 0 {
+		return fmt.Errorf("%w: amount and price can't be negative", ErrInvalidPrice)
+	}
+	return nil
+}
+
+// RecordPrice adds a price point for the household. Amount defaults to one
+// unit and the day to today.
+func RecordPrice(db *sqlx.DB, householdID int, p *IngredientPrice) (*IngredientPrice, error) {
+	// Only SaveReceipt ties prices to receipts
+	p.ReceiptID = nil
+	return recordPrice(db, householdID, p)
+}
+
+func recordPrice(q sqlx.Queryer, householdID int, p *IngredientPrice) (*IngredientPrice, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	p.HouseholdID = householdID
+	if p.ObservedOn.IsZero() {
+		p.ObservedOn = Date{Time: startOfToday()}
+	}
+	err := sqlx.Get(q, p, `INSERT INTO ingredient_prices (household_id, name, store, amount, unit, price, observed_on, receipt_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *`,
+		p.HouseholdID, p.Name, p.Store, p.Amount, p.Unit, p.Price, p.ObservedOn, p.ReceiptID)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPriceHistory lists the household's price points, newest first. An empty
+// name or store doesn't filter.
+func GetPriceHistory(db *sqlx.DB, householdID int, name string, store string) ([]IngredientPrice, error) {
+	prices := []IngredientPrice{}
+	err := db.Select(&prices, `SELECT * FROM ingredient_prices WHERE household_id=$1
+		AND ($2 = '' OR name=$2) AND ($3 = '' OR store=$3)
+		ORDER BY observed_on DESC, id DESC`, householdID, strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(store))
+	return prices, err
+}
+
+// GetCurrentPrices returns the latest price point of each ingredient at
+// each store. An empty name or store doesn't filter.
+func GetCurrentPrices(db *sqlx.DB, householdID int, name string, store string) ([]IngredientPrice, error) {
+	prices := []IngredientPrice{}
+	err := db.Select(&prices, `SELECT DISTINCT ON (name, store) * FROM ingredient_prices WHERE household_id=$1
+		AND ($2 = '' OR name=$2) AND ($3 = '' OR store=$3)
+		ORDER BY name, store, observed_on DESC, id DESC`, householdID, strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(store))
+	return prices, err
+}
+
+// DeletePrice removes one of the household's price points
+func DeletePrice(db *sqlx.DB, householdID int, id int) error {
+	res, err := db.Exec(`DELETE FROM ingredient_prices WHERE id=$1 AND household_id=$2`, id, householdID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPriceList returns the household's current prices keyed by ingredient
+// name, one per store
+func GetPriceList(db *sqlx.DB, householdID int) (map[string][]IngredientPrice, error) {
+	current, err := GetCurrentPrices(db, householdID, "", "")
+	if err != nil {
+		return nil, err
+	}
+	prices := map[string][]IngredientPrice{}
+	for _, p := range current {
+		prices[p.Name] = append(prices[p.Name], p)
+	}
+	return prices, nil
+}
+
+// linePrice prices an ingredient amount at the cheapest store whose price
+// converts to it
+func linePrice(amount string, prices []IngredientPrice) (float64, bool) {
+	q, ok := ParseQuantity(amount)
+	if !ok {
+		return 0, false
+	}
+	best, found := 0.0, false
+	for _, p := range prices {
+		v, ok := ConvertQuantity(q, p.Unit)
+		if !ok || p.Amount <= 0 {
+			continue
+		}
+		if cost := p.Price * v / p.Amount; !found || cost < best {
+			best, found = cost, true
+		}
+	}
+	return best, found
+}
+
+// computeCost totals the cost of a list of ingredients
+func computeCost(ingredients []Ingredient, prices map[string][]IngredientPrice) (float64, []string) {
+	total := 0.0
+	unpriced := []string{}
+	matcher := newIngredientMatcher(prices)
+	for _, in := range ingredients {
+		if in.Name == "" {
+			continue
+		}
+		cost, priced := 0.0, false
+		if matched, ok := matcher.match(in.Name); ok {
+			cost, priced = linePrice(in.Amount, matched)
+		}
+		if !priced {
+			unpriced = append(unpriced, in.Name)
+			continue
+		}
+		total += cost
+	}
+	return total, unpriced
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func newCostSummary(total float64, servings int, unpriced []string) *CostSummary {
+	if servings < 1 {
+		servings = 1
+	}
+	return &CostSummary{
+		Servings:   servings,
+		Total:      roundCents(total),
+		PerServing: roundCents(total / float64(servings)),
+		Unpriced:   unpriced,
+	}
+}
+
+// RecipeCost prices a loaded recipe, including the ingredients of the
+// recipes its lines use, at the household's current prices. It returns nil
+// when the household hasn't recorded any prices.
+func RecipeCost(db *sqlx.DB, householdID int, recipe *Recipe) (*CostSummary, error) {
+	prices, err := GetPriceList(db, householdID)
+	if err != nil || len(prices) == 0 {
+		return nil, err
+	}
+	return recipeCost(recipe, prices), nil
+}
+
+func recipeCost(recipe *Recipe, prices map[string][]IngredientPrice) *CostSummary {
+	total, unpriced := computeCost(recipe.RawIngredients(), prices)
+	servings := 1
+	if recipe.Servings != nil {
+		servings = *recipe.Servings
+	}
+	return newCostSummary(total, servings, unpriced)
+}
+
+// MealCost prices a loaded meal: its own ingredients plus each of its
+// recipes in full. Servings follow the same rules as MealNutrition. It
+// returns nil when the household hasn't recorded any prices.
+func MealCost(db *sqlx.DB, householdID int, meal *Meal) (*CostSummary, error) {
+	prices, err := GetPriceList(db, householdID)
+	if err != nil || len(prices) == 0 {
+		return nil, err
+	}
+	return mealCost(db, meal, prices)
+}
+
+func mealCost(db *sqlx.DB, meal *Meal, prices map[string][]IngredientPrice) (*CostSummary, error) {
+	ingredients := make([]Ingredient, len(meal.Ingredients))
+	for i, ingredient := range meal.Ingredients {
+		ingredients[i] = Ingredient{Name: ingredient.Name, Amount: ingredient.Amount}
+	}
+
+	recipes, err := activeRecipes(db, meal)
+	if err != nil {
+		return nil, err
+	}
+	servings := 1
+	for _, recipe := range recipes {
+		ingredients = append(ingredients, recipe.RawIngredients()...)
+		if recipe.Servings != nil && *recipe.Servings > servings {
+			servings = *recipe.Servings
+		}
+	}
+	if meal.Servings != nil {
+		servings = *meal.Servings
+	}
+
+	total, unpriced := computeCost(ingredients, prices)
+	return newCostSummary(total, servings, unpriced), nil
+}
+
+// MealCostLine is one meal's contribution to a plan's cost
+type MealCostLine struct {
+	MealID     int     `json:"meal_id"`
+	Name       string  `json:"name"`
+	Servings   int     `json:"servings"`
+	Total      float64 `json:"total"`
+	PerServing float64 `json:"per_serving"`
+}
+
+// PlanCostReport projects what a plan's meals will cost, each meal made
+// once. Budget is the household's weekly budget prorated over the plan's
+// days, and OverBudget is set when the projected total exceeds it.
+type PlanCostReport struct {
+	PlanID       int            `json:"plan_id"`
+	Days         int            `json:"days"`
+	Total        float64        `json:"total"`
+	PerDay       float64        `json:"per_day"`
+	WeeklyBudget *float64       `json:"weekly_budget"`
+	Budget       *float64       `json:"budget"`
+	OverBudget   bool           `json:"over_budget"`
+	Meals        []MealCostLine `json:"meals"`
+	Unpriced     []string       `json:"unpriced"`
+}
+
+// PlanCost builds the cost report for a loaded plan. It returns nil when the
+// household hasn't recorded any prices.
+func PlanCost(db *sqlx.DB, plan *Plan) (*PlanCostReport, error) {
+	prices, err := GetPriceList(db, plan.HouseholdID)
+	if err != nil || len(prices) == 0 {
+		return nil, err
+	}
+	weeklyBudget, err := GetHouseholdBudget(db, plan.HouseholdID)
+	if err != nil {
+		return nil, err
+	}
+
+	days := int(plan.EndDate.Sub(plan.StartDate.Time).Hours()/24) + 1
+	if days < 1 {
+		days = 1
+	}
+
+	report := &PlanCostReport{PlanID: plan.ID, Days: days, WeeklyBudget: weeklyBudget, Meals: []MealCostLine{}, Unpriced: []string{}}
+	total := 0.0
+	seen := map[string]bool{}
+	for _, mealID := range plan.Meals {
+		meal, err := GetMeal(db, mealID)
+		if err != nil {
+			return nil, err
+		}
+		if meal.DeletedAt != nil {
+			continue
+		}
+		summary, err := mealCost(db, meal, prices)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Meals = append(report.Meals, MealCostLine{
+			MealID:     meal.ID,
+			Name:       meal.Name,
+			Servings:   summary.Servings,
+			Total:      summary.Total,
+			PerServing: summary.PerServing,
+		})
+		total += summary.Total
+		for _, name := range summary.Unpriced {
+			if !seen[name] {
+				seen[name] = true
+				report.Unpriced = append(report.Unpriced, name)
+			}
+		}
+	}
+
+	report.Total = roundCents(total)
+	report.PerDay = roundCents(total / float64(days))
+	if weeklyBudget != nil {
+		budget := roundCents(*weeklyBudget * float64(days) / 7)
+		report.Budget = &budget
+		report.OverBudget = report.Total > budget
+	}
+	return report, nil
+}
+
+// GetHouseholdBudget returns the household's weekly grocery budget, or nil
+// when it hasn't set one
+func GetHouseholdBudget(db *sqlx.DB, householdID int) (*float64, error) {
+	var budget *float64
+	err := db.Get(&budget, `SELECT weekly_budget FROM households WHERE id=$1`, householdID)
+	return budget, err
+}
+
+// SetHouseholdBudget sets the household's weekly budget, or clears it when
+// budget is nil
+func SetHouseholdBudget(db *sqlx.DB, householdID int, budget *float64) error {
+	if budget != nil && *budget < 0 {
+		return fmt.Errorf("%w: the weekly budget can't be negative", ErrInvalidBudget)
+	}
+	_, err := db.Exec(`UPDATE households SET weekly_budget=$1 WHERE id=$2`, budget, householdID)
+	return err
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var priceColumns = []string{"id", "household_id", "name", "store", "amount", "unit", "price", "observed_on", "created_at"}
+
+func testPriceList() map[string][]IngredientPrice {
+	return map[string][]IngredientPrice{
+		"flour": {
+			{Name: "flour", Store: "corner shop", Amount: 1, Unit: "kg", Price: 2.40},
+			{Name: "flour", Store: "supermarket", Amount: 2, Unit: "kg", Price: 3.00},
+		},
+		"egg":  {{Name: "egg", Store: "supermarket", Amount: 12, Unit: "", Price: 4.20}},
+		"milk": {{Name: "milk", Store: "supermarket", Amount: 1, Unit: "l", Price: 1.10}},
+	}
+}
+
+func TestIngredientPriceValidate(t *testing.T) {
+	p := IngredientPrice{Name: " Flour ", Store: " Supermarket ", Unit: "Kilograms", Price: 3}
+	require.NoError(t, p.validate())
+	assert.Equal(t, "flour", p.Name)
+	assert.Equal(t, "Supermarket", p.Store)
+	assert.Equal(t, "kg", p.Unit)
+	assert.Equal(t, 1.0, p.Amount)
+
+	assert.ErrorIs(t, (&IngredientPrice{Price: 1}).validate(), ErrInvalidPrice)
+	assert.ErrorIs(t, (&IngredientPrice{Name: "flour", Price: -1}).validate(), ErrInvalidPrice)
+}
+
+func TestComputeCost(t *testing.T) {
+	total, unpriced := computeCost([]Ingredient{
+		// The supermarket's 1.50 a kilo beats the corner shop
+		{Name: "flour", Amount: "500 g"},
+		{Name: "eggs", Amount: "3"},
+		{Name: "whole milk", Amount: "250 ml"},
+		{Name: "salt", Amount: "a pinch"},
+		// Volume can't be converted to the price's weight
+		{Name: "flour", Amount: "1 cup"},
+	}, testPriceList())
+
+	assert.InDelta(t, 0.75+1.05+0.275, total, 0.0001)
+	assert.Equal(t, []string{"salt", "flour"}, unpriced)
+}
+
+func TestRecipeCost(t *testing.T) {
+	servings, batches := 4, 2.0
+	summary := recipeCost(&Recipe{
+		Servings: &servings,
+		Ingredients: []RecipeIngredient{
+			{Name: "flour", Amount: "1 kg"},
+			// Two batches of a batter that needs 6 eggs
+			{Name: "batter", Quantity: &batches, SubRecipe: &Recipe{Ingredients: []RecipeIngredient{
+				{Name: "eggs", Amount: "6"},
+			}}},
+		},
+	}, testPriceList())
+
+	assert.Equal(t, 4, summary.Servings)
+	assert.Equal(t, 5.70, summary.Total)
+	assert.Equal(t, 1.43, summary.PerServing)
+	assert.Empty(t, summary.Unpriced)
+}
+
+func TestRecipeCostWithoutPrices(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT DISTINCT ON \\(name, store\\) \\* FROM ingredient_prices").WithArgs(42, "", "").
+		WillReturnRows(sqlmock.NewRows(priceColumns))
+
+	summary, err := RecipeCost(db, 42, &Recipe{Ingredients: []RecipeIngredient{{Name: "flour", Amount: "1 kg"}}})
+	require.NoError(t, err)
+	assert.Nil(t, summary)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlanCost(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	now := time.Now()
+	mock.ExpectQuery("SELECT DISTINCT ON \\(name, store\\) \\* FROM ingredient_prices").WithArgs(42, "", "").
+		WillReturnRows(sqlmock.NewRows(priceColumns).
+			AddRow(1, 42, "egg", "supermarket", 12, "", 6.00, start, now))
+	mock.ExpectQuery("SELECT weekly_budget FROM households WHERE id=\\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"weekly_budget"}).AddRow(14.0))
+
+	// GetMeal, twice for the same meal
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT \\* FROM meals WHERE id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image", "servings"}).
+				AddRow(7, "Omelette", "Breakfast", "omelette", nil, 2))
+		mock.ExpectQuery("SELECT \\* FROM meal_ingredients WHERE meal_id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "name", "meal_id"}).
+				AddRow(1, "6", "eggs", 7).
+				AddRow(2, "a pinch", "chives", 7))
+		mock.ExpectQuery("SELECT \\* FROM meal_steps WHERE meal_id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "text", "order", "meal_id"}))
+		mock.ExpectQuery("SELECT \\* FROM meal_recipes WHERE meal_id=\\$1").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}))
+		mock.ExpectQuery("SELECT t.name FROM tags t").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	}
+
+	report, err := PlanCost(db, &Plan{
+		ID:          1,
+		HouseholdID: 42,
+		StartDate:   Date{start},
+		EndDate:     Date{start.AddDate(0, 0, 1)},
+		Meals:       []int{7, 7},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Days)
+	assert.Equal(t, 6.0, report.Total)
+	assert.Equal(t, 3.0, report.PerDay)
+	require.NotNil(t, report.Budget)
+	// Two days of a 14 a week budget
+	assert.Equal(t, 4.0, *report.Budget)
+	assert.True(t, report.OverBudget)
+	require.Len(t, report.Meals, 2)
+	assert.Equal(t, 1.5, report.Meals[0].PerServing)
+	assert.Equal(t, []string{"chives"}, report.Unpriced)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetHouseholdBudget(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	negative := -5.0
+	assert.ErrorIs(t, SetHouseholdBudget(db, 42, &negative), ErrInvalidBudget)
+
+	mock.ExpectExec("UPDATE households SET weekly_budget=\\$1 WHERE id=\\$2").WithArgs(nil, 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, SetHouseholdBudget(db, 42, nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a parsed ingredient amount such as "1 1/2 cups"
+type Quantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+var unicodeFractions = map[rune]float64{
+	'¼': 0.25, '½': 0.5, '¾': 0.75,
+	'⅓': 1.0 / 3, '⅔': 2.0 / 3,
+	'⅛': 0.125, '⅜': 0.375, '⅝': 0.625, '⅞': 0.875,
+}
+
+// unitAliases maps the spellings found in recipes onto a canonical unit
+var unitAliases = map[string]string{
+	"g": "g", "gram": "g", "grams": "g",
+	"kg": "kg", "kilogram": "kg", "kilograms": "kg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml",
+	"l": "l", "liter": "l", "liters": "l",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"tbsp": "tbsp", "tbs": "tbsp", "tbl": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"c": "cup", "cup": "cup", "cups": "cup",
+	"pt": "pint", "pint": "pint", "pints": "pint",
+	"qt": "quart", "quart": "quart", "quarts": "quart",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can",
+	"slice": "slice", "slices": "slice",
+	"stick": "stick", "sticks": "stick",
+	"bunch": "bunch", "bunches": "bunch",
+	"pinch": "pinch", "pinches": "pinch",
+	"dash": "dash", "dashes": "dash",
+	"package": "package", "packages": "package", "pkg": "package",
+	"each": "", "whole": "", "large": "", "medium": "", "small": "",
+}
+
+// unitScale gives the size of each convertible unit in its dimension's base
+// unit: grams for mass, millilitres for volume.
+var unitScale = map[string]struct {
+	dimension string
+	factor    float64
+}{
+	"g":     {"mass", 1},
+	"kg":    {"mass", 1000},
+	"oz":    {"mass", 28.3495},
+	"lb":    {"mass", 453.592},
+	"ml":    {"volume", 1},
+	"l":     {"volume", 1000},
+	"tsp":   {"volume", 4.92892},
+	"tbsp":  {"volume", 14.7868},
+	"cup":   {"volume", 236.588},
+	"pint":  {"volume", 473.176},
+	"quart": {"volume", 946.353},
+}
+
+// NormalizeUnit returns the canonical spelling of unit, or unit itself
+// lowercased when it isn't a known unit.
+func NormalizeUnit(unit string) string {
+	unit = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(unit)), ".")
+	if canonical, ok := unitAliases[unit]; ok {
+		return canonical
+	}
+	return unit
+}
+
+// ParseQuantity reads the leading number and unit from an ingredient amount.
+// Mixed numbers ("1 1/2"), fractions, decimals, unicode fractions and ranges
+// ("2-3", averaged) are understood. Amounts without a number, like "to taste",
+// are not parsed.
+func ParseQuantity(amount string) (Quantity, bool) {
+	words := strings.Fields(amount)
+	total := 0.0
+	i := 0
+	for i < len(words) && quantityToken.MatchString(words[i]) {
+		v, ok := parseNumber(words[i])
+		if !ok {
+			break
+		}
+		total += v
+		i++
+	}
+	if i == 0 {
+		return Quantity{}, false
+	}
+
+	unit := ""
+	if i < len(words) {
+		unit = NormalizeUnit(words[i])
+		if _, known := unitAliases[strings.TrimSuffix(strings.ToLower(words[i]), ".")]; !known {
+			// The rest is part of the name ("2 eggs"), not a unit
+			unit = ""
+		}
+	}
+	return Quantity{Value: total, Unit: unit}, true
+}
+
+func parseNumber(s string) (float64, bool) {
+	if lo, hi, ok := strings.Cut(s, "-"); ok {
+		a, okA := parseNumber(lo)
+		b, okB := parseNumber(hi)
+		return (a + b) / 2, okA && okB
+	}
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, errN := strconv.ParseFloat(num, 64)
+		d, errD := strconv.ParseFloat(den, 64)
+		if errN != nil || errD != nil || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+
+	value := 0.0
+	digits := s
+	if r := []rune(s); len(r) > 0 {
+		if frac, ok := unicodeFractions[r[len(r)-1]]; ok {
+			value = frac
+			digits = string(r[:len(r)-1])
+		}
+	}
+	if digits == "" {
+		return value, true
+	}
+	v, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value + v, true
+}
+
+// ConvertQuantity expresses q in the given unit. Mass and volume units convert
+// within their dimension; any other unit only converts to itself.
+func ConvertQuantity(q Quantity, unit string) (float64, bool) {
+	unit = NormalizeUnit(unit)
+	if q.Unit == unit {
+		return q.Value, true
+	}
+	from, okFrom := unitScale[q.Unit]
+	to, okTo := unitScale[unit]
+	if !okFrom || !okTo || from.dimension != to.dimension {
+		return 0, false
+	}
+	return q.Value * from.factor / to.factor, true
+}
+
+// ScaleAmount multiplies the leading number of an ingredient amount, keeping
+// the rest as written: "1 1/2 cups flour" scaled by 2 is "3 cups flour".
+// Amounts without a number are returned unchanged.
+func ScaleAmount(amount string, factor float64) string {
+	if factor == 1 {
+		return amount
+	}
+	words := strings.Fields(amount)
+	total := 0.0
+	i := 0
+	for i < len(words) && quantityToken.MatchString(words[i]) {
+		v, ok := parseNumber(words[i])
+		if !ok {
+			break
+		}
+		total += v
+		i++
+	}
+	if i == 0 {
+		return amount
+	}
+	scaled := strconv.FormatFloat(math.Round(total*factor*100)/100, 'f', -1, 64)
+	return strings.Join(append([]string{scaled}, words[i:]...), " ")
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   Quantity
+		ok     bool
+	}{
+		{"2 cups", Quantity{2, "cup"}, true},
+		{"1 1/2 tbsp", Quantity{1.5, "tbsp"}, true},
+		{"½ tsp", Quantity{0.5, "tsp"}, true},
+		{"1½ lbs", Quantity{1.5, "lb"}, true},
+		{"2-3 cloves", Quantity{2.5, "clove"}, true},
+		{"0.25 kg", Quantity{0.25, "kg"}, true},
+		{"3", Quantity{3, ""}, true},
+		{"2 large", Quantity{2, ""}, true},
+		{"to taste", Quantity{}, false},
+		{"", Quantity{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseQuantity(tt.amount)
+		assert.Equal(t, tt.ok, ok, tt.amount)
+		assert.InDelta(t, tt.want.Value, got.Value, 1e-9, tt.amount)
+		assert.Equal(t, tt.want.Unit, got.Unit, tt.amount)
+	}
+}
+
+func TestConvertQuantity(t *testing.T) {
+	v, ok := ConvertQuantity(Quantity{1, "lb"}, "g")
+	assert.True(t, ok)
+	assert.InDelta(t, 453.592, v, 0.001)
+
+	v, ok = ConvertQuantity(Quantity{3, "tsp"}, "tablespoons")
+	assert.True(t, ok)
+	assert.InDelta(t, 1, v, 0.001)
+
+	v, ok = ConvertQuantity(Quantity{2, "clove"}, "cloves")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, v)
+
+	_, ok = ConvertQuantity(Quantity{1, "cup"}, "g")
+	assert.False(t, ok)
+	_, ok = ConvertQuantity(Quantity{1, "clove"}, "g")
+	assert.False(t, ok)
+}
+
+func TestScaleAmount(t *testing.T) {
+	assert.Equal(t, "3 cups flour", ScaleAmount("1 1/2 cups flour", 2))
+	assert.Equal(t, "0.25 tsp", ScaleAmount("½ tsp", 0.5))
+	assert.Equal(t, "1.33 cup", ScaleAmount("4 cup", 1.0/3))
+	assert.Equal(t, "to taste", ScaleAmount("to taste", 3))
+	assert.Equal(t, "2 eggs", ScaleAmount("2 eggs", 1))
+}
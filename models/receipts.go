@@ -0,0 +1,276 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvalidReceipt    = errors.New("invalid receipt")
+	ErrInvalidReceiptCSV = errors.New("receipt CSV must have item and price columns")
+)
+
+// ReceiptLine is one line of a receipt: Price paid for Amount Unit of what
+// Description names. Ingredient is the catalog entry the line was matched
+// to; setting it skips matching. Lines with a negative price, such as
+// coupons, count towards the total but are never matched.
+type ReceiptLine struct {
+	Description string  `json:"description"`
+	Ingredient  string  `json:"ingredient"`
+	Amount      float64 `json:"amount"`
+	Unit        string  `json:"unit"`
+	Price       float64 `json:"price"`
+}
+
+type ReceiptLines []ReceiptLine
+
+func (l *ReceiptLines) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, &l)
+}
+
+func (l ReceiptLines) Value() (driver.Value, error) {
+	if l == nil {
+		l = ReceiptLines{}
+	}
+	return json.Marshal(l)
+}
+
+// Receipt is a shopping trip. Saving one records a price point for each
+// matched line. Purchased lists the shopping list items it checked off.
+type Receipt struct {
+	ID          int                `db:"id" json:"id"`
+	HouseholdID int                `db:"household_id" json:"household_id"`
+	Store       string             `db:"store" json:"store"`
+	PurchasedOn Date               `db:"purchased_on" json:"purchased_on"`
+	Total       float64            `db:"total" json:"total"`
+	Lines       ReceiptLines       `db:"lines" json:"lines"`
+	CreatedAt   time.Time          `db:"created_at" json:"created_at"`
+	Purchased   []ShoppingListItem `db:"-" json:"purchased,omitempty"`
+}
+
+func (r *Receipt) validate() error {
+	r.Store = strings.TrimSpace(r.Store)
+	if len(r.Lines) == 0 {
+		return fmt.Errorf("%w: a receipt needs at least one line", ErrInvalidReceipt)
+	}
+	for i := range r.Lines {
+		line := &r.Lines[i]
+		line.Description = strings.TrimSpace(line.Description)
+		line.Ingredient = strings.ToLower(strings.TrimSpace(line.Ingredient))
+		line.Unit = NormalizeUnit(line.Unit)
+		if line.Amount == 0 {
+			line.Amount = 1
+		}
+		if line.Description == "" && line.Ingredient == "" {
+			return fmt.Errorf("%w: line %d needs a description", ErrInvalidReceipt, i+1)
+		}
+		if line.Amount < 0 {
+			return fmt.Errorf("%w: line %d has a negative amount", ErrInvalidReceipt, i+1)
+		}
+		if line.Price < 0 {
+			line.Ingredient = ""
+		}
+	}
+	return nil
+}
+
+// Ingredients lists the ingredients the receipt's lines were matched to
+func (r *Receipt) Ingredients() []string {
+	ingredients := []string{}
+	for _, line := range r.Lines {
+		if line.Ingredient != "" {
+			ingredients = append(ingredients, line.Ingredient)
+		}
+	}
+	return ingredients
+}
+
+// receiptCandidates returns the names receipt lines can match, keyed by
+// themselves: the ingredient catalog's and those the household already has
+// prices for
+func receiptCandidates(db *sqlx.DB, householdID int) (map[string]string, error) {
+	catalog, err := GetIngredientCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := GetCurrentPrices(db, householdID, "", "")
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, entry := range catalog {
+		names[entry.Name] = entry.Name
+	}
+	for _, p := range prices {
+		names[p.Name] = p.Name
+	}
+	return names, nil
+}
+
+// matchReceiptLines sets the ingredient of each line that hasn't got one
+// and whose description names a candidate
+func matchReceiptLines(lines ReceiptLines, candidates map[string]string) {
+	matcher := newIngredientMatcher(candidates)
+	for i := range lines {
+		if lines[i].Ingredient != "" || lines[i].Price < 0 {
+			continue
+		}
+		if name, ok := matcher.match(lines[i].Description); ok {
+			lines[i].Ingredient = name
+		}
+	}
+}
+
+// SaveReceipt matches a receipt's lines to ingredients and saves it with a
+// price point at the receipt's store for each matched line. The day
+// defaults to today.
+func SaveReceipt(db *sqlx.DB, householdID int, receipt *Receipt) (*Receipt, error) {
+	if err := receipt.validate(); err != nil {
+		return nil, err
+	}
+	candidates, err := receiptCandidates(db, householdID)
+	if err != nil {
+		return nil, err
+	}
+	matchReceiptLines(receipt.Lines, candidates)
+
+	receipt.HouseholdID = householdID
+	if receipt.PurchasedOn.IsZero() {
+		receipt.PurchasedOn = Date{Time: startOfToday()}
+	}
+	total := 0.0
+	for _, line := range receipt.Lines {
+		total += line.Price
+	}
+	receipt.Total = roundCents(total)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	saved := Receipt{}
+	err = tx.Get(&saved, `INSERT INTO receipts (household_id, store, purchased_on, total, lines)
+		VALUES ($1, $2, $3, $4, $5) RETURNING *`,
+		receipt.HouseholdID, receipt.Store, receipt.PurchasedOn, receipt.Total, receipt.Lines)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, line := range saved.Lines {
+		if line.Ingredient == "" {
+			continue
+		}
+		_, err := recordPrice(tx, householdID, &IngredientPrice{
+			Name:       line.Ingredient,
+			Store:      saved.Store,
+			Amount:     line.Amount,
+			Unit:       line.Unit,
+			Price:      line.Price,
+			ObservedOn: saved.PurchasedOn,
+			ReceiptID:  &saved.ID,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("recording the price of %s: %w", line.Ingredient, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// GetReceipts lists the household's receipts, newest first
+func GetReceipts(db *sqlx.DB, householdID int) ([]Receipt, error) {
+	receipts := []Receipt{}
+	err := db.Select(&receipts, `SELECT * FROM receipts WHERE household_id=$1 ORDER BY purchased_on DESC, id DESC`, householdID)
+	return receipts, err
+}
+
+// currencySymbols strips what stores print around prices, e.g. "-$1,299.00"
+var currencySymbols = strings.NewReplacer("$", "", "€", "", "£", "", ",", "")
+
+// LoadReceiptCSV reads the lines of a receipt exported by a grocery store.
+// The header names the columns; an item (or description, name or product)
+// and a price (or total) column are required. Quantity (or qty) and unit
+// are optional, and a quantity such as "2 lb" carries its own unit.
+func LoadReceiptCSV(r io.Reader) (ReceiptLines, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	column := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	itemColumn := column("item", "description", "name", "product")
+	priceColumn := column("price", "total", "line total")
+	quantityColumn := column("quantity", "qty")
+	unitColumn := column("unit")
+	if itemColumn < 0 || priceColumn < 0 {
+		return nil, ErrInvalidReceiptCSV
+	}
+
+	lines := ReceiptLines{}
+	for n := 2; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(i int) string {
+			if i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		line := ReceiptLine{Description: field(itemColumn), Unit: field(unitColumn)}
+		if line.Description == "" {
+			continue
+		}
+		if line.Price, err = strconv.ParseFloat(currencySymbols.Replace(field(priceColumn)), 64); err != nil {
+			return nil, fmt.Errorf("line %d: price: %w", n, err)
+		}
+		if quantity := field(quantityColumn); quantity != "" {
+			q, ok := ParseQuantity(quantity)
+			if !ok {
+				return nil, fmt.Errorf("line %d: quantity %q isn't a number", n, quantity)
+			}
+			line.Amount = q.Value
+			if line.Unit == "" {
+				line.Unit = q.Unit
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReceiptCSV(t *testing.T) {
+	lines, err := LoadReceiptCSV(strings.NewReader(`Item,Qty,Unit,Price
+ORGANIC BANANAS,2 lb,,$1.38
+"MILK, WHOLE",1,gal,4.29
+,,,
+COUPON,,,-$1.00
+`))
+	require.NoError(t, err)
+	assert.Equal(t, ReceiptLines{
+		{Description: "ORGANIC BANANAS", Amount: 2, Unit: "lb", Price: 1.38},
+		{Description: "MILK, WHOLE", Amount: 1, Unit: "gal", Price: 4.29},
+		{Description: "COUPON", Price: -1},
+	}, lines)
+
+	_, err = LoadReceiptCSV(strings.NewReader("item,qty\nmilk,1\n"))
+	assert.Equal(t, ErrInvalidReceiptCSV, err)
+
+	_, err = LoadReceiptCSV(strings.NewReader("item,price\nmilk,cheap\n"))
+	assert.Error(t, err)
+}
+
+func TestMatchReceiptLines(t *testing.T) {
+	receipt := &Receipt{Lines: ReceiptLines{
+		{Description: "ORGANIC BANANAS", Price: 1.38},
+		{Description: "GV FLOUR AP 5LB", Price: 3.12},
+		{Description: "SHOPPER BAG", Price: 0.10},
+		// An explicit ingredient wins
+		{Description: "HEINZ 57", Ingredient: " Steak Sauce ", Price: 3.99},
+		{Description: "COUPON BANANAS", Price: -0.50},
+	}}
+	require.NoError(t, receipt.validate())
+	matchReceiptLines(receipt.Lines, map[string]string{"banana": "banana", "flour": "flour"})
+
+	assert.Equal(t, []string{"banana", "flour", "steak sauce"}, receipt.Ingredients())
+	assert.Equal(t, 1.0, receipt.Lines[0].Amount)
+
+	assert.ErrorIs(t, (&Receipt{}).validate(), ErrInvalidReceipt)
+	assert.ErrorIs(t, (&Receipt{Lines: ReceiptLines{{Price: 1}}}).validate(), ErrInvalidReceipt)
+}
+
+func TestSaveReceipt(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT name, category FROM ingredient_categories").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "category"}).AddRow("milk", "dairy"))
+	mock.ExpectQuery("SELECT DISTINCT ON \\(name, store\\) \\* FROM ingredient_prices").WithArgs(42, "", "").
+		WillReturnRows(sqlmock.NewRows(priceColumns).
+			AddRow(1, 42, "banana", "Grocer", 1, "lb", 0.59, day, day))
+
+	lines := ReceiptLines{
+		{Description: "BANANAS", Ingredient: "banana", Amount: 2, Unit: "lb", Price: 1.38},
+		{Description: "WHOLE MILK", Ingredient: "milk", Amount: 1, Unit: "l", Price: 1.10},
+		{Description: "BAG", Amount: 1, Price: 0.10},
+	}
+	linesJSON, _ := json.Marshal(lines)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO receipts").
+		WithArgs(42, "Grocer", sqlmock.AnyArg(), 2.58, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "store", "purchased_on", "total", "lines", "created_at"}).
+			AddRow(5, 42, "Grocer", day, 2.58, linesJSON, day))
+	mock.ExpectQuery("INSERT INTO ingredient_prices").
+		WithArgs(42, "banana", "Grocer", 2.0, "lb", 1.38, sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows(priceColumns).AddRow(2, 42, "banana", "Grocer", 2, "lb", 1.38, day, day))
+	mock.ExpectQuery("INSERT INTO ingredient_prices").
+		WithArgs(42, "milk", "Grocer", 1.0, "l", 1.10, sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows(priceColumns).AddRow(3, 42, "milk", "Grocer", 1, "l", 1.10, day, day))
+	mock.ExpectCommit()
+
+	saved, err := SaveReceipt(db, 42, &Receipt{Store: " Grocer ", Lines: ReceiptLines{
+		{Description: "BANANAS", Amount: 2, Unit: "lb", Price: 1.38},
+		{Description: "WHOLE MILK", Amount: 1, Unit: "liter", Price: 1.10},
+		{Description: "BAG", Price: 0.10},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 5, saved.ID)
+	assert.Equal(t, 2.58, saved.Total)
+	assert.Equal(t, []string{"banana", "milk"}, saved.Ingredients())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkPurchased(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	statusJSON, _ := json.Marshal(Status{Items: []StatusItem{{Name: "Bread", Amount: "1 loaf"}}})
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM plans WHERE id = $1")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "start_date", "end_date"}).AddRow(1, 42, now, now))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM shopping_status WHERE plan_id = $1")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"plan_id", "status"}).AddRow(1, statusJSON))
+	mock.ExpectQuery("SELECT i.name, i.amount FROM meal_ingredients").WithArgs(1, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "amount"}).
+			AddRow("Bread", "1 loaf").
+			AddRow("Whole milk", "2 cups").
+			AddRow("Eggs", "6"))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM plans WHERE id = $1 AND household_id = $2")).WithArgs(1, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	updatedJSON, _ := json.Marshal(Status{Items: []StatusItem{{Name: "Bread", Amount: "1 loaf"}, {Name: "Whole milk", Amount: "2 cups"}}})
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE shopping_status SET status = $1 WHERE plan_id = $2")).WithArgs(updatedJSON, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	purchased, err := MarkPurchased(db, 42, 1, []string{"milk", "bread"})
+	require.NoError(t, err)
+	// Bread was already checked off
+	assert.Equal(t, []ShoppingListItem{{Name: "Whole milk", Amount: "2 cups", Checked: true}}, purchased)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
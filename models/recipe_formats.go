@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// Recipe interchange formats supported by DecodeRecipes and EncodeRecipes
+const (
+	RecipeFormatPaprika  = "paprika"
+	RecipeFormatMealie   = "mealie"
+	RecipeFormatCooklang = "cooklang"
+)
+
+var ErrUnknownRecipeFormat = errors.New("unknown recipe format, expected one of paprika, mealie or cooklang")
+var ErrRecipeFileTooLarge = errors.New("recipe file is too large")
+
+// MaxRecipeFileSize is the largest recipe file accepted for import, and the
+// most any file compressed inside one may expand to
+const MaxRecipeFileSize = 50 << 20
+
+// readRecipeEntry reads a file decompressed from a recipe archive. Its
+// compressed size says nothing about how far it expands, so reading stops
+// one byte past MaxRecipeFileSize to spot larger files.
+func readRecipeEntry(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, MaxRecipeFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxRecipeFileSize {
+		return nil, ErrRecipeFileTooLarge
+	}
+	return b, nil
+}
+
+// ImportedRecipe is a recipe read from another app's format. Some formats
+// embed the photo itself rather than a URL; those bytes are returned in
+// Photo so the caller can store them and set Recipe.Image.
+type ImportedRecipe struct {
+	Recipe    Recipe
+	Photo     []byte
+	PhotoName string
+}
+
+// DecodeRecipes reads one or more recipes from data in the given format
+func DecodeRecipes(format string, data []byte) ([]ImportedRecipe, error) {
+	switch format {
+	case RecipeFormatPaprika:
+		return DecodePaprika(data)
+	case RecipeFormatMealie:
+		return DecodeMealie(data)
+	case RecipeFormatCooklang:
+		return DecodeCooklang(data)
+	}
+	return nil, ErrUnknownRecipeFormat
+}
+
+// EncodeRecipes writes recipes to w in the given format
+func EncodeRecipes(format string, w io.Writer, recipes []Recipe) error {
+	switch format {
+	case RecipeFormatPaprika:
+		return EncodePaprika(w, recipes)
+	case RecipeFormatMealie:
+		return EncodeMealie(w, recipes)
+	case RecipeFormatCooklang:
+		return EncodeCooklang(w, recipes)
+	}
+	return ErrUnknownRecipeFormat
+}
+
+// RecipeFormatFileName returns a sensible download name for an export
+func RecipeFormatFileName(format string, recipes []Recipe) string {
+	base := "recipes"
+	if len(recipes) == 1 && recipes[0].Slug != "" {
+		base = recipes[0].Slug
+	}
+	switch format {
+	case RecipeFormatPaprika:
+		return base + ".paprikarecipes"
+	case RecipeFormatMealie:
+		return base + ".json"
+	case RecipeFormatCooklang:
+		if len(recipes) == 1 {
+			return base + ".cook"
+		}
+		return base + ".zip"
+	}
+	return base
+}
+
+// ingredientLine joins an ingredient back into a single human readable line
+func ingredientLine(i RecipeIngredient) string {
+	return strings.TrimSpace(i.Amount + " " + i.Name)
+}
+
+// newImportedRecipe returns a recipe with empty, non-nil collections so the
+// JSON output and UpdateRecipe treat it consistently.
+func newImportedRecipe() Recipe {
+	return Recipe{
+		Ingredients: []RecipeIngredient{},
+		Steps:       []RecipeStep{},
+		Tags:        []string{},
+	}
+}
+
+// stepsFromText splits free-form directions into steps on blank lines or,
+// when there are none, on single newlines. Headings such as "For the
+// frosting:" start a section.
+func stepsFromText(text string) []RecipeStep {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	sep := "\n"
+	if strings.Contains(text, "\n\n") {
+		sep = "\n\n"
+	}
+
+	steps := []RecipeStep{}
+	section := ""
+	for _, chunk := range strings.Split(text, sep) {
+		first, rest, _ := strings.Cut(strings.TrimSpace(chunk), "\n")
+		if heading, ok := sectionHeading(first); ok {
+			section = heading
+			chunk = rest
+		}
+		chunk = strings.Join(strings.Fields(chunk), " ")
+		if chunk == "" {
+			continue
+		}
+		steps = append(steps, RecipeStep{Order: len(steps) + 1, Text: chunk, Section: section})
+	}
+	return steps
+}
+
+// sectionHeading reports whether a line of free-form text is a short heading
+// ending in a colon, and returns it without the colon.
+func sectionHeading(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasSuffix(line, ":") {
+		return "", false
+	}
+	name := cleanSection(strings.TrimSuffix(line, ":"))
+	if name == "" || len(strings.Fields(name)) > 6 {
+		return "", false
+	}
+	return name, true
+}
+
+// withHeadings joins lines, writing a "Section:" heading before the first
+// line of each section
+func withHeadings(lines []string, sections []string, sep string) string {
+	out := []string{}
+	current := ""
+	for i, line := range lines {
+		if sections[i] != current {
+			current = sections[i]
+			if current != "" {
+				out = append(out, current+":")
+			}
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, sep)
+}
+
+func normalizeTags(raw []string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
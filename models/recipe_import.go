@@ -0,0 +1,406 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrNoRecipeFound = errors.New("no schema.org Recipe found in page")
+var ErrInvalidImportURL = errors.New("url must be an absolute http or https URL")
+var ErrPrivateAddress = errors.New("refusing to fetch from a private or local address")
+
+// Fetcher retrieves the raw body of a page. It is a function type so the HTTP
+// client can be swapped out for local fixtures in tests.
+type Fetcher func(ctx context.Context, pageURL string) ([]byte, error)
+
+// HTTPFetcher returns a Fetcher that downloads pages with client, refusing
+// bodies larger than maxBytes.
+func HTTPFetcher(client *http.Client, maxBytes int64) Fetcher {
+	return func(ctx context.Context, pageURL string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		req.Header.Set("User-Agent", "mealplan-recipe-importer/1.0")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("fetching %s: unexpected status %s", pageURL, resp.Status)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > maxBytes {
+			return nil, fmt.Errorf("fetching %s: page larger than %d bytes", pageURL, maxBytes)
+		}
+		return body, nil
+	}
+}
+
+// PublicHTTPClient returns a client for fetching user supplied URLs. It only
+// connects to public addresses, checking every redirect and the address each
+// connection is actually made to, so pages can't be used to reach the server's
+// own network.
+func PublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialPublicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return checkPublicURL(req.Context(), req.URL)
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which IsPrivate doesn't
+// cover but which is just as unreachable from the internet
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether ip is routable on the internet, as opposed to a
+// loopback, private, shared, link-local or unspecified address. IPv4-mapped
+// IPv6 addresses are checked as the IPv4 address they carry.
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// dialPublicOnly is a net.Dialer Control function refusing connections to
+// addresses that aren't public. It sees the address after DNS resolution.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// checkPublicURL resolves u's host and fails unless it is an http or https
+// URL whose every address is public
+func checkPublicURL(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrInvalidImportURL
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, u.Hostname())
+		}
+	}
+	return nil
+}
+
+// ImportRecipeFromURL fetches pageURL and extracts a draft recipe from it.
+// URLs naming a local host or a private address are refused up front; the
+// fetcher is expected to check where names resolve to.
+func ImportRecipeFromURL(ctx context.Context, fetch Fetcher, pageURL string) (*Recipe, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidImportURL
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return nil, ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return nil, ErrPrivateAddress
+	}
+
+	body, err := fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	recipe, err := ParseRecipeHTML(body)
+	if err != nil {
+		return nil, err
+	}
+	if recipe.Image.Valid {
+		if imageURL, err := u.Parse(recipe.Image.String); err == nil {
+			recipe.Image.String = imageURL.String()
+		}
+	}
+	return recipe, nil
+}
+
+var jsonLDScript = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// ParseRecipeHTML finds the first schema.org Recipe in the page's JSON-LD
+// blocks and maps it onto a Recipe. The result is a draft and is not saved.
+func ParseRecipeHTML(page []byte) (*Recipe, error) {
+	for _, match := range jsonLDScript.FindAllSubmatch(page, -1) {
+		var doc interface{}
+		if err := json.Unmarshal(match[1], &doc); err != nil {
+			fmt.Println("Skipping invalid JSON-LD block:", err)
+			continue
+		}
+		if node := findRecipeNode(doc); node != nil {
+			return recipeFromJSONLD(node), nil
+		}
+	}
+	return nil, ErrNoRecipeFound
+}
+
+// findRecipeNode walks arrays and @graph containers looking for a node whose
+// @type is (or includes) Recipe.
+func findRecipeNode(doc interface{}) map[string]interface{} {
+	switch v := doc.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if node := findRecipeNode(item); node != nil {
+				return node
+			}
+		}
+	case map[string]interface{}:
+		if isRecipeType(v["@type"]) {
+			return v
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findRecipeNode(graph)
+		}
+	}
+	return nil
+}
+
+func isRecipeType(t interface{}) bool {
+	return isSchemaType(t, "Recipe")
+}
+
+// isSchemaType reports whether a JSON-LD @type names the schema.org type
+func isSchemaType(t interface{}, schemaType string) bool {
+	for _, name := range jsonLDStrings(t) {
+		if name == schemaType || strings.HasSuffix(name, "/"+schemaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonLDStrings flattens a JSON-LD value that may be a string or a list of strings
+func jsonLDStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		values := []string{}
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func cleanText(s string) string {
+	s = htmlTag.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func recipeFromJSONLD(node map[string]interface{}) *Recipe {
+	recipe := &Recipe{
+		Ingredients: []RecipeIngredient{},
+		Steps:       []RecipeStep{},
+		Tags:        []string{},
+	}
+
+	if name, ok := node["name"].(string); ok {
+		recipe.Name = cleanText(name)
+	}
+	if description, ok := node["description"].(string); ok {
+		recipe.Description = cleanText(description)
+	}
+
+	for _, line := range jsonLDStrings(node["recipeIngredient"]) {
+		line = cleanText(line)
+		if line == "" {
+			continue
+		}
+		amount, name := SplitIngredientLine(line)
+		recipe.Ingredients = append(recipe.Ingredients, RecipeIngredient{Name: name, Amount: amount})
+	}
+
+	for i, step := range instructionSteps(node["recipeInstructions"], "") {
+		step.Order = i + 1
+		recipe.Steps = append(recipe.Steps, step)
+	}
+
+	recipe.Image = NullStringWrapper(imageURL(node["image"]))
+	recipe.Tags = keywordTags(node["keywords"])
+	if prep, ok := node["prepTime"].(string); ok {
+		recipe.PrepMinutes = durationMinutes(prep)
+	}
+	if cook, ok := node["cookTime"].(string); ok {
+		recipe.CookMinutes = durationMinutes(cook)
+	}
+
+	return recipe
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:[\d.]+S)?)?$`)
+
+// durationMinutes reads an ISO 8601 duration such as PT1H30M as whole
+// minutes. It returns nil for anything else.
+func durationMinutes(s string) *int {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return nil
+	}
+	minutes := 0
+	for i, scale := range []int{24 * 60, 60, 1} {
+		if m[i+1] != "" {
+			n, err := strconv.Atoi(m[i+1])
+			if err != nil {
+				return nil
+			}
+			minutes += n * scale
+		}
+	}
+	return &minutes
+}
+
+// instructionSteps flattens the many shapes recipeInstructions takes in the
+// wild: a single string, a list of strings, HowToStep objects, or
+// HowToSection objects containing further steps. A HowToSection's name
+// becomes the section of its steps.
+func instructionSteps(v interface{}, section string) []RecipeStep {
+	steps := []RecipeStep{}
+	switch t := v.(type) {
+	case string:
+		for _, line := range strings.Split(t, "\n") {
+			if line = cleanText(line); line != "" {
+				steps = append(steps, RecipeStep{Text: line, Section: section})
+			}
+		}
+	case []interface{}:
+		for _, item := range t {
+			steps = append(steps, instructionSteps(item, section)...)
+		}
+	case map[string]interface{}:
+		if items, ok := t["itemListElement"]; ok {
+			if name, ok := t["name"].(string); ok && isSchemaType(t["@type"], "HowToSection") {
+				section = cleanText(name)
+			}
+			return instructionSteps(items, section)
+		}
+		text, _ := t["text"].(string)
+		if text == "" {
+			text, _ = t["name"].(string)
+		}
+		if text = cleanText(text); text != "" {
+			steps = append(steps, RecipeStep{Text: text, Section: section})
+		}
+	}
+	return steps
+}
+
+func imageURL(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if u := imageURL(item); u != "" {
+				return u
+			}
+		}
+	case map[string]interface{}:
+		if u, ok := t["url"].(string); ok {
+			return u
+		}
+		if u, ok := t["contentUrl"].(string); ok {
+			return u
+		}
+	}
+	return ""
+}
+
+// keywordTags converts schema.org keywords (comma separated or a list) into
+// lowercase tags, matching how UpdateRecipe stores them.
+func keywordTags(v interface{}) []string {
+	raw := []string{}
+	for _, s := range jsonLDStrings(v) {
+		for _, tag := range strings.Split(s, ",") {
+			raw = append(raw, cleanText(tag))
+		}
+	}
+	return normalizeTags(raw)
+}
+
+var ingredientUnits = map[string]bool{
+	"c": true, "cup": true, "cups": true,
+	"tbsp": true, "tablespoon": true, "tablespoons": true, "tbs": true, "tbl": true,
+	"tsp": true, "teaspoon": true, "teaspoons": true,
+	"oz": true, "ounce": true, "ounces": true,
+	"lb": true, "lbs": true, "pound": true, "pounds": true,
+	"g": true, "gram": true, "grams": true, "kg": true, "kilogram": true, "kilograms": true,
+	"ml": true, "milliliter": true, "milliliters": true, "l": true, "liter": true, "liters": true,
+	"pinch": true, "pinches": true, "dash": true, "dashes": true,
+	"clove": true, "cloves": true, "can": true, "cans": true,
+	"package": true, "packages": true, "pkg": true, "stick": true, "sticks": true,
+	"slice": true, "slices": true, "bunch": true, "bunches": true,
+	"qt": true, "quart": true, "quarts": true, "pt": true, "pint": true, "pints": true,
+}
+
+var quantityToken = regexp.MustCompile(`^([0-9]+([./][0-9]+)?|[0-9]*[¼½¾⅓⅔⅛⅜⅝⅞]|[0-9]+-[0-9]+)$`)
+
+// SplitIngredientLine splits a free-form ingredient line like "2 1/2 cups flour"
+// into its amount ("2 1/2 cups") and name ("flour"). Lines without a leading
+// quantity are returned entirely as the name.
+func SplitIngredientLine(line string) (amount string, name string) {
+	words := strings.Fields(line)
+	i := 0
+	for i < len(words) && quantityToken.MatchString(words[i]) {
+		i++
+	}
+	if i == 0 {
+		return "", strings.TrimSpace(line)
+	}
+	if i < len(words) && ingredientUnits[strings.TrimSuffix(strings.ToLower(words[i]), ".")] {
+		i++
+	}
+	if i == len(words) {
+		return strings.Join(words, " "), ""
+	}
+	return strings.Join(words[:i], " "), strings.Join(words[i:], " ")
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureFetcher serves files from testdata instead of the network
+func fixtureFetcher(t *testing.T, pages map[string]string) Fetcher {
+	return func(ctx context.Context, pageURL string) ([]byte, error) {
+		file, ok := pages[pageURL]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		body, err := os.ReadFile("testdata/" + file)
+		require.NoError(t, err)
+		return body, nil
+	}
+}
+
+func TestParseRecipeHTML(t *testing.T) {
+	t.Run("graph with sections", func(t *testing.T) {
+		page, err := os.ReadFile("testdata/recipe_jsonld_graph.html")
+		require.NoError(t, err)
+
+		recipe, err := ParseRecipeHTML(page)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Weeknight Chili", recipe.Name)
+		assert.Equal(t, "A quick beef & bean chili.", recipe.Description)
+		assert.Equal(t, "/images/chili.jpg", recipe.Image.String)
+		assert.Equal(t, []string{"chili", "beef", "quick"}, recipe.Tags)
+		require.NotNil(t, recipe.PrepMinutes)
+		assert.Equal(t, 15, *recipe.PrepMinutes)
+		require.NotNil(t, recipe.CookMinutes)
+		assert.Equal(t, 70, *recipe.CookMinutes)
+
+		require.Len(t, recipe.Ingredients, 4)
+		assert.Equal(t, "1 lb", recipe.Ingredients[0].Amount)
+		assert.Equal(t, "ground beef", recipe.Ingredients[0].Name)
+		assert.Equal(t, "1 ½ cups", recipe.Ingredients[2].Amount)
+		assert.Equal(t, "diced tomatoes", recipe.Ingredients[2].Name)
+		assert.Equal(t, "", recipe.Ingredients[3].Amount)
+		assert.Equal(t, "salt to taste", recipe.Ingredients[3].Name)
+
+		require.Len(t, recipe.Steps, 3)
+		assert.Equal(t, 1, recipe.Steps[0].Order)
+		assert.Equal(t, "Brown the beef in a large pot.", recipe.Steps[0].Text)
+		assert.Equal(t, 3, recipe.Steps[2].Order)
+		assert.Equal(t, "Simmer for 20 minutes.", recipe.Steps[2].Text)
+		assert.Equal(t, "Brown", recipe.Steps[0].Section)
+		assert.Equal(t, "Simmer", recipe.Steps[2].Section)
+	})
+
+	t.Run("array with string instructions", func(t *testing.T) {
+		page, err := os.ReadFile("testdata/recipe_jsonld_simple.html")
+		require.NoError(t, err)
+
+		recipe, err := ParseRecipeHTML(page)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Pancakes", recipe.Name)
+		assert.Equal(t, "https://cdn.example.com/pancakes.png", recipe.Image.String)
+		assert.Equal(t, []string{"breakfast", "sweet"}, recipe.Tags)
+		assert.Equal(t, "2 tbsp.", recipe.Ingredients[1].Amount)
+		assert.Equal(t, "sugar", recipe.Ingredients[1].Name)
+		require.Len(t, recipe.Steps, 3)
+		assert.Equal(t, "Cook on a hot griddle.", recipe.Steps[2].Text)
+	})
+
+	t.Run("no recipe", func(t *testing.T) {
+		page, err := os.ReadFile("testdata/no_recipe.html")
+		require.NoError(t, err)
+
+		_, err = ParseRecipeHTML(page)
+		assert.Equal(t, ErrNoRecipeFound, err)
+	})
+}
+
+func TestImportRecipeFromURL(t *testing.T) {
+	fetch := fixtureFetcher(t, map[string]string{
+		"https://example.com/recipes/chili": "recipe_jsonld_graph.html",
+	})
+
+	recipe, err := ImportRecipeFromURL(context.Background(), fetch, "https://example.com/recipes/chili")
+	require.NoError(t, err)
+	assert.Equal(t, "Weeknight Chili", recipe.Name)
+	// Relative image URLs are resolved against the page
+	assert.Equal(t, "https://example.com/images/chili.jpg", recipe.Image.String)
+
+	_, err = ImportRecipeFromURL(context.Background(), fetch, "file:///etc/passwd")
+	assert.Equal(t, ErrInvalidImportURL, err)
+
+	_, err = ImportRecipeFromURL(context.Background(), fetch, "https://example.com/missing")
+	assert.Error(t, err)
+
+	for _, local := range []string{"http://localhost:8080/", "http://127.0.0.1/", "http://[::1]/", "http://169.254.169.254/latest/meta-data", "http://10.0.0.5/", "http://0.0.0.0/"} {
+		_, err = ImportRecipeFromURL(context.Background(), fetch, local)
+		assert.ErrorIs(t, err, ErrPrivateAddress, local)
+	}
+}
+
+func TestPublicHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, so the connection is refused
+	fetch := HTTPFetcher(PublicHTTPClient(5*time.Second), 1<<10)
+	_, err := fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+
+	// Bodies over the limit are refused
+	_, err = HTTPFetcher(server.Client(), 4)(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "larger than 4 bytes")
+
+	assert.True(t, isPublicIP(net.ParseIP("93.184.216.34")))
+	assert.True(t, isPublicIP(net.ParseIP("2606:2800:220:1::")))
+	assert.True(t, isPublicIP(net.ParseIP("100.128.0.1")))
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "::ffff:10.0.0.1", "100.64.0.1", "100.127.255.254", "::ffff:100.64.0.1"} {
+		assert.False(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestSplitIngredientLine(t *testing.T) {
+	cases := []struct {
+		line, amount, name string
+	}{
+		{"2 cups flour", "2 cups", "flour"},
+		{"2 1/2 cups milk", "2 1/2 cups", "milk"},
+		{"½ tsp salt", "½ tsp", "salt"},
+		{"3 eggs", "3", "eggs"},
+		{"1-2 cloves garlic", "1-2 cloves", "garlic"},
+		{"salt and pepper", "", "salt and pepper"},
+		{"2", "2", ""},
+	}
+	for _, c := range cases {
+		amount, name := SplitIngredientLine(c.line)
+		assert.Equal(t, c.amount, amount, c.line)
+		assert.Equal(t, c.name, name, c.line)
+	}
+}
@@ -4,21 +4,31 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gosimple/slug"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var ErrValidation = errors.New("name and description are required")
 
+// RecipeIngredient is one ingredient line. A line can use another recipe as
+// a component: SubRecipeID names it and Quantity says how many batches of it
+// are needed (1 when unset). GetRecipe fills in SubRecipe.
 type RecipeIngredient struct {
-	ID       int    `db:"id" json:"id"`
-	RecipeID int    `db:"recipe_id" json:"recipe_id"`
-	Name     string `db:"name" json:"name"`
-	Amount   string `db:"amount" json:"amount"`
-	Calories *int   `db:"calories" json:"calories"`
+	ID          int      `db:"id" json:"id"`
+	RecipeID    int      `db:"recipe_id" json:"recipe_id"`
+	Name        string   `db:"name" json:"name"`
+	Amount      string   `db:"amount" json:"amount"`
+	Calories    *int     `db:"calories" json:"calories"`
+	SubRecipeID *int     `db:"sub_recipe_id" json:"sub_recipe_id,omitempty"`
+	Quantity    *float64 `db:"quantity" json:"quantity,omitempty"`
+	SubRecipe   *Recipe  `db:"-" json:"sub_recipe,omitempty"`
+	Section     string   `db:"section" json:"section,omitempty"`
+	Position    int      `db:"position" json:"position"`
 }
 
 type RecipeStep struct {
@@ -26,6 +36,8 @@ type RecipeStep struct {
 	RecipeID int    `db:"recipe_id" json:"recipe_id"`
 	Order    int    `db:"order" json:"order"`
 	Text     string `db:"text" json:"text"`
+	Section  string `db:"section" json:"section,omitempty"`
+	StepTiming
 }
 
 type Recipe struct {
@@ -34,14 +46,31 @@ type Recipe struct {
 	Description string             `db:"description" json:"description"`
 	Slug        string             `db:"slug" json:"slug"`
 	Image       sql.NullString     `db:"image" json:"image"`
+	Servings    *int               `db:"servings" json:"servings"`
 	Ingredients []RecipeIngredient `json:"ingredients"`
 	Steps       []RecipeStep       `json:"steps"`
 	Tags        []string           `json:"tags"`
+	Equipment   pq.StringArray     `db:"equipment" json:"equipment"`
+	DeletedAt   *time.Time         `db:"deleted_at" json:"deleted_at,omitempty"`
+	Nutrition   *NutritionSummary  `db:"-" json:"nutrition,omitempty"`
+	Cost        *CostSummary       `db:"-" json:"cost,omitempty"`
+
+	// PrepMinutes and CookMinutes override the times added up from the
+	// steps. Time is the result.
+	PrepMinutes *int         `db:"prep_minutes" json:"prep_minutes"`
+	CookMinutes *int         `db:"cook_minutes" json:"cook_minutes"`
+	Time        *CookingTime `db:"-" json:"time,omitempty"`
+
+	// Forks are copies owned by one household. ForkedRevision is how many
+	// revisions the parent had when it was forked.
+	ForkedFrom     *int `db:"forked_from" json:"forked_from"`
+	ForkedRevision *int `db:"forked_revision" json:"forked_revision,omitempty"`
+	HouseholdID    *int `db:"household_id" json:"household_id"`
 }
 
 func GetRecipes(db *sqlx.DB) (*[]Recipe, error) {
 	recipes := []Recipe{}
-	err := db.Select(&recipes, "SELECT * FROM recipes")
+	err := db.Select(&recipes, "SELECT * FROM recipes WHERE deleted_at IS NULL")
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -59,9 +88,31 @@ func GetRecipes(db *sqlx.DB) (*[]Recipe, error) {
 	return &recipes, nil
 }
 
+// GetRecipesWithDetails loads complete recipes, including ingredients, steps
+// and tags, for the given IDs, or for every recipe when ids is empty.
+func GetRecipesWithDetails(db *sqlx.DB, ids []int) ([]Recipe, error) {
+	if len(ids) == 0 {
+		err := db.Select(&ids, "SELECT id FROM recipes WHERE deleted_at IS NULL ORDER BY id ASC")
+		if err != nil {
+			fmt.Println(err)
+			return nil, err
+		}
+	}
+
+	recipes := []Recipe{}
+	for _, id := range ids {
+		recipe, err := GetRecipe(db, id)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, *recipe)
+	}
+	return recipes, nil
+}
+
 func GetRecipeIdFromSlug(db *sqlx.DB, slug string) (int, error) {
 	var id int
-	err := db.Get(&id, "SELECT id FROM recipes WHERE slug=$1", slug)
+	err := db.Get(&id, "SELECT id FROM recipes WHERE slug=$1 AND deleted_at IS NULL", slug)
 	if err != nil {
 		fmt.Println(err)
 		return 0, err
@@ -70,7 +121,15 @@ func GetRecipeIdFromSlug(db *sqlx.DB, slug string) (int, error) {
 	return id, nil
 }
 
+// GetRecipe loads a recipe with its ingredients, steps and tags. Ingredient
+// lines that use another recipe carry that recipe, loaded the same way.
 func GetRecipe(db *sqlx.DB, i int) (*Recipe, error) {
+	return getRecipe(db, i, nil)
+}
+
+// getRecipe loads a recipe as a component of the recipes in parents, which
+// are skipped if a line refers back to them.
+func getRecipe(db *sqlx.DB, i int, parents []int) (*Recipe, error) {
 	recipe := Recipe{}
 	err := db.Get(&recipe, "SELECT * FROM recipes WHERE id=$1", i)
 	if err != nil {
@@ -79,7 +138,7 @@ func GetRecipe(db *sqlx.DB, i int) (*Recipe, error) {
 	}
 
 	ingredients := []RecipeIngredient{}
-	err = db.Select(&ingredients, "SELECT * FROM recipe_ingredients WHERE recipe_id=$1", recipe.ID)
+	err = db.Select(&ingredients, "SELECT * FROM recipe_ingredients WHERE recipe_id=$1 ORDER BY position, id", recipe.ID)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -97,8 +156,23 @@ func GetRecipe(db *sqlx.DB, i int) (*Recipe, error) {
 		recipe.Tags = tags
 	}
 
+	parents = append(parents, recipe.ID)
+	for j := range ingredients {
+		subID := ingredients[j].SubRecipeID
+		if subID == nil || slices.Contains(parents, *subID) {
+			continue
+		}
+		sub, err := getRecipe(db, *subID, parents)
+		if err != nil {
+			fmt.Printf("Error loading sub-recipe %d of recipe %d: %v\n", *subID, recipe.ID, err)
+			continue
+		}
+		ingredients[j].SubRecipe = sub
+	}
+
 	recipe.Ingredients = ingredients
 	recipe.Steps = steps
+	recipe.setTime()
 
 	return &recipe, nil
 }
@@ -110,7 +184,33 @@ func NullStringWrapper(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
 
+// UpdateRecipe replaces the recipe, keeping its previous version as a
+// revision
 func UpdateRecipe(db *sqlx.DB, i int, r *Recipe) (*Recipe, error) {
+	if err := validateRecipe(db, i, r); err != nil {
+		return nil, err
+	}
+	previous, err := GetRecipe(db, i)
+	if err != nil {
+		return nil, err
+	}
+	return updateRecipe(db, i, r, previous)
+}
+
+// validateRecipe checks r before it is saved as recipe i, or as a new recipe
+// when i is 0
+func validateRecipe(db sqlx.Queryer, i int, r *Recipe) error {
+	if r.Name == "" || r.Description == "" {
+		return ErrValidation
+	}
+	if err := checkSubRecipes(db, i, r.Ingredients); err != nil {
+		return err
+	}
+	return validateRecipeTimes(r)
+}
+
+// updateRecipe saves previous as a revision, if given, and replaces the recipe
+func updateRecipe(db *sqlx.DB, i int, r *Recipe, previous *Recipe) (*Recipe, error) {
 	if r.Name == "" || r.Description == "" {
 		return nil, ErrValidation
 	}
@@ -120,43 +220,74 @@ func UpdateRecipe(db *sqlx.DB, i int, r *Recipe) (*Recipe, error) {
 		fmt.Println(err)
 		return nil, err
 	}
-
-	_, err = tx.Exec("UPDATE recipes SET name=$1, description=$2, image=$3 WHERE id=$4", r.Name, r.Description, r.Image, i)
-	if err != nil {
+	if err := writeRecipe(tx, i, r, previous); err != nil {
 		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
 		fmt.Println(err)
 		return nil, err
 	}
 
+	return GetRecipe(db, i)
+}
+
+// writeRecipe replaces recipe i's fields, ingredients, steps and tags with
+// r's inside tx, saving previous as a revision first if given
+func writeRecipe(tx *sqlx.Tx, i int, r *Recipe, previous *Recipe) error {
+	var err error
+	if previous != nil {
+		if err := saveRevision(tx, "recipe_id", i, previous); err != nil {
+			fmt.Println(err)
+			return err
+		}
+	}
+
+	r.Equipment = NormalizeEquipment(r.Equipment)
+
+	_, err = tx.Exec("UPDATE recipes SET name=$1, description=$2, image=$3, servings=$4, prep_minutes=$5, cook_minutes=$6, equipment=$7 WHERE id=$8",
+		r.Name, r.Description, r.Image, r.Servings, r.PrepMinutes, r.CookMinutes, r.Equipment, i)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
 	_, err = tx.Exec("DELETE FROM recipe_ingredients WHERE recipe_id=$1", i)
 	if err != nil {
-		tx.Rollback()
 		fmt.Println(err)
-		return nil, err
+		return err
 	}
 
-	for _, ingredient := range r.Ingredients {
-		_, err = tx.Exec("INSERT INTO recipe_ingredients (recipe_id, name, amount, calories) VALUES ($1, $2, $3, $4)", i, ingredient.Name, ingredient.Amount, ingredient.Calories)
+	for j := range r.Ingredients {
+		r.Ingredients[j].Section = cleanSection(r.Ingredients[j].Section)
+	}
+	// Ingredients are kept in the order given, numbered from 1
+	for j, ingredient := range groupSections(r.Ingredients, func(i RecipeIngredient) string { return i.Section }) {
+		_, err = tx.Exec("INSERT INTO recipe_ingredients (recipe_id, name, amount, calories, sub_recipe_id, quantity, section, position) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			i, ingredient.Name, ingredient.Amount, ingredient.Calories, ingredient.SubRecipeID, ingredient.Quantity, ingredient.Section, j+1)
 		if err != nil {
-			tx.Rollback()
 			fmt.Println(err)
-			return nil, err
+			return err
 		}
 	}
 
 	_, err = tx.Exec("DELETE FROM recipe_steps WHERE recipe_id=$1", i)
 	if err != nil {
-		tx.Rollback()
 		fmt.Println(err)
-		return nil, err
+		return err
 	}
 
-	for _, step := range r.Steps {
-		_, err = tx.Exec("INSERT INTO recipe_steps (recipe_id, \"order\", text) VALUES ($1, $2, $3)", i, step.Order, step.Text)
+	for j := range r.Steps {
+		r.Steps[j].Section = cleanSection(r.Steps[j].Section)
+	}
+	steps := groupSteps(r.Steps, func(s *RecipeStep) *int { return &s.Order }, func(s RecipeStep) string { return s.Section })
+	for _, step := range steps {
+		_, err = tx.Exec("INSERT INTO recipe_steps (recipe_id, \"order\", text, section, active_minutes, passive_minutes, temperature) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			i, step.Order, step.Text, step.Section, step.ActiveMinutes, step.PassiveMinutes, strings.TrimSpace(step.Temperature))
 		if err != nil {
-			tx.Rollback()
 			fmt.Println(err)
-			return nil, err
+			return err
 		}
 	}
 
@@ -165,9 +296,8 @@ func UpdateRecipe(db *sqlx.DB, i int, r *Recipe) (*Recipe, error) {
 		// Remove all existing tags for this recipe
 		_, err = tx.Exec("DELETE FROM recipe_tags WHERE recipe_id=$1", i)
 		if err != nil {
-			tx.Rollback()
 			fmt.Println(err)
-			return nil, err
+			return err
 		}
 		for _, tag := range r.Tags {
 			tag = strings.ToLower(tag)
@@ -177,79 +307,130 @@ func UpdateRecipe(db *sqlx.DB, i int, r *Recipe) (*Recipe, error) {
 				// Tag does not exist, insert it
 				err = tx.Get(&tagID, "INSERT INTO tags (name) VALUES ($1) RETURNING id", tag)
 				if err != nil {
-					tx.Rollback()
 					fmt.Println(err)
-					return nil, err
+					return err
 				}
 			}
 			_, err = tx.Exec("INSERT INTO recipe_tags (recipe_id, tag_id) VALUES ($1, $2)", i, tagID)
 			if err != nil {
-				tx.Rollback()
 				fmt.Println(err)
-				return nil, err
+				return err
 			}
 		}
 	}
 
+	return nil
+}
+
+func CreateRecipe(db *sqlx.DB, r *Recipe) (*Recipe, error) {
+	if err := validateRecipe(db, 0, r); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	id, err := insertRecipe(tx, r)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Now add tags, ingredients, steps, etc.
+	if err := writeRecipe(tx, id, r, nil); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
 	}
 
-	return GetRecipe(db, i)
+	return GetRecipe(db, id)
 }
 
-func CreateRecipe(db *sqlx.DB, r *Recipe) (*Recipe, error) {
-	if r.Name == "" || r.Description == "" {
-		return nil, ErrValidation
+// CreateRecipes creates a batch of recipes in one transaction, so either all
+// of them are saved or none are. Errors name the recipe by its position.
+func CreateRecipes(db *sqlx.DB, recipes []Recipe) ([]Recipe, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	for i := range recipes {
+		if err := validateRecipe(tx, 0, &recipes[i]); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("recipe %d: %w", i+1, err)
+		}
+	}
+	ids := make([]int, len(recipes))
+	for i := range recipes {
+		id, err := insertRecipe(tx, &recipes[i])
+		if err == nil {
+			err = writeRecipe(tx, id, &recipes[i], nil)
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("recipe %d: %w", i+1, err)
+		}
+		ids[i] = id
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
 	}
 
+	created := make([]Recipe, len(ids))
+	for i, id := range ids {
+		recipe, err := GetRecipe(db, id)
+		if err != nil {
+			return nil, err
+		}
+		created[i] = *recipe
+	}
+	return created, nil
+}
+
+// insertRecipe adds a bare recipe row inside tx, giving it the first free
+// slug made from its name, and returns its ID
+func insertRecipe(tx *sqlx.Tx, r *Recipe) (int, error) {
 	r.Slug = slug.Make(r.Name)
 	var id int
-	err := db.Get(&id, "SELECT id FROM recipes WHERE slug=$1", r.Slug)
+	err := tx.Get(&id, "SELECT id FROM recipes WHERE slug=$1", r.Slug)
 	if err == nil {
 		i := 1
 		for err == nil {
 			fmt.Printf("slug %s already exists\n", r.Slug)
 			r.Slug = slug.Make(r.Name + "-" + fmt.Sprint(i))
 			fmt.Printf("trying %s\n", r.Slug)
-			err = db.Get(&id, "SELECT id FROM recipes WHERE slug=$1", r.Slug)
+			err = tx.Get(&id, "SELECT id FROM recipes WHERE slug=$1", r.Slug)
 			i++
 		}
 	}
 
-	tx, err := db.Beginx()
-	if err != nil {
-		fmt.Println(err)
-		return nil, err
-	}
-
 	_, err = tx.Exec("INSERT INTO recipes (name, description, slug) VALUES ($1, $2, $3)", r.Name, r.Description, r.Slug)
 	if err != nil {
-		tx.Rollback()
 		fmt.Println(err)
-		return nil, err
+		return 0, err
 	}
 
 	err = tx.Get(&id, "SELECT id FROM recipes WHERE slug=$1", r.Slug)
 	if err != nil {
-		tx.Rollback()
 		fmt.Println(err)
-		return nil, err
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		fmt.Println(err)
-		return nil, err
+		return 0, err
 	}
-
-	// Now update tags, ingredients, steps, etc.
-	return UpdateRecipe(db, id, r)
+	return id, nil
 }
 
-func DeleteRecipe(db *sqlx.DB, i int) error {
+// purgeRecipe permanently deletes a recipe, taking it out of any meals
+func purgeRecipe(db *sqlx.DB, i int) error {
 	tx, err := db.Beginx()
 	if err != nil {
 		fmt.Println(err)
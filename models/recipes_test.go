@@ -139,13 +139,11 @@ func TestCreateRecipe(t *testing.T) {
 		},
 	}
 
-	// First check if slug exists
+	// The recipe is created in one transaction, starting with a free slug
+	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT id FROM recipes WHERE slug=\\$1").
 		WithArgs(slug).
 		WillReturnError(sql.ErrNoRows)
-
-	// Mock the transaction for insert
-	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO recipes").
 		WithArgs(name, description, slug).
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -154,12 +152,9 @@ func TestCreateRecipe(t *testing.T) {
 		WithArgs(slug).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	mock.ExpectCommit()
-
-	// For UpdateRecipe call within CreateRecipe
-	mock.ExpectBegin()
+	// Then the rest of the recipe is written
 	mock.ExpectExec("UPDATE recipes SET").
-		WithArgs(name, description, image, 1).
+		WithArgs(name, description, image, nil, nil, nil, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Delete old ingredients and insert new
@@ -167,9 +162,9 @@ func TestCreateRecipe(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	for _, ing := range newRecipe.Ingredients {
+	for j, ing := range newRecipe.Ingredients {
 		mock.ExpectExec("INSERT INTO recipe_ingredients").
-			WithArgs(1, ing.Name, ing.Amount, ing.Calories).
+			WithArgs(1, ing.Name, ing.Amount, ing.Calories, ing.SubRecipeID, ing.Quantity, ing.Section, j+1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -180,7 +175,7 @@ func TestCreateRecipe(t *testing.T) {
 
 	for _, step := range newRecipe.Steps {
 		mock.ExpectExec("INSERT INTO recipe_steps").
-			WithArgs(1, step.Order, step.Text).
+			WithArgs(1, step.Order, step.Text, step.Section, step.ActiveMinutes, step.PassiveMinutes, step.Temperature).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -229,6 +224,24 @@ func TestCreateRecipe(t *testing.T) {
 	}
 }
 
+func TestCreateRecipesIsAllOrNothing(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	// The second recipe has no description, so nothing is inserted
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	created, err := CreateRecipes(db, []Recipe{
+		{Name: "Toast", Description: "Bread, but hot."},
+		{Name: "Jam"},
+	})
+	assert.Nil(t, created)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "recipe 2")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateRecipe(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -262,19 +275,32 @@ func TestUpdateRecipe(t *testing.T) {
 		},
 	}
 
+	// The current version is kept as a revision
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(recipeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).AddRow(recipeID, "Old Recipe", "Old description", "old", nil))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients WHERE recipe_id=\\$1").WithArgs(recipeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "name", "amount", "calories"}))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps WHERE recipe_id=\\$1 ORDER BY").WithArgs(recipeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(recipeID).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
 	// Mock the transaction for UpdateRecipe
 	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO revisions \\(recipe_id, revision, snapshot\\)").
+		WithArgs(recipeID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("UPDATE recipes SET").
-		WithArgs(name, description, image, recipeID).
+		WithArgs(name, description, image, nil, nil, nil, sqlmock.AnyArg(), recipeID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.ExpectExec("DELETE FROM recipe_ingredients WHERE recipe_id").
 		WithArgs(recipeID).
 		WillReturnResult(sqlmock.NewResult(0, 2))
 
-	for _, ing := range updateRecipe.Ingredients {
+	for j, ing := range updateRecipe.Ingredients {
 		mock.ExpectExec("INSERT INTO recipe_ingredients").
-			WithArgs(recipeID, ing.Name, ing.Amount, ing.Calories).
+			WithArgs(recipeID, ing.Name, ing.Amount, ing.Calories, ing.SubRecipeID, ing.Quantity, ing.Section, j+1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -284,7 +310,7 @@ func TestUpdateRecipe(t *testing.T) {
 
 	for _, step := range updateRecipe.Steps {
 		mock.ExpectExec("INSERT INTO recipe_steps").
-			WithArgs(recipeID, step.Order, step.Text).
+			WithArgs(recipeID, step.Order, step.Text, step.Section, step.ActiveMinutes, step.PassiveMinutes, step.Temperature).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 	}
 
@@ -333,7 +359,7 @@ func TestUpdateRecipe(t *testing.T) {
 	}
 }
 
-func TestDeleteRecipe(t *testing.T) {
+func TestPurgeRecipe(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -344,7 +370,7 @@ func TestDeleteRecipe(t *testing.T) {
 
 	recipeID := 1
 
-	// Mock the transaction for DeleteRecipe
+	// Mock the transaction for purgeRecipe
 	mock.ExpectBegin()
 	mock.ExpectExec("DELETE FROM recipe_ingredients WHERE recipe_id=\\$1").
 		WithArgs(recipeID).
@@ -361,7 +387,7 @@ func TestDeleteRecipe(t *testing.T) {
 	mock.ExpectCommit()
 
 	// Call the function
-	err = DeleteRecipe(sqlxDB, recipeID)
+	err = purgeRecipe(sqlxDB, recipeID)
 
 	// Assertions
 	assert.NoError(t, err)
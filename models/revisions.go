@@ -0,0 +1,254 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Revision is a recipe or meal as it was before one of its updates. Changes
+// lists what that update changed.
+type Revision struct {
+	ID        int            `db:"id" json:"id"`
+	RecipeID  *int           `db:"recipe_id" json:"recipe_id,omitempty"`
+	MealID    *int           `db:"meal_id" json:"meal_id,omitempty"`
+	Revision  int            `db:"revision" json:"revision"`
+	Snapshot  types.JSONText `db:"snapshot" json:"snapshot"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+	Changes   []Change       `db:"-" json:"changes"`
+}
+
+// Change is one difference between two versions. Text fields have Before
+// and After; list fields (ingredients, steps, equipment, tags, recipes) list the lines
+// that were added and removed.
+type Change struct {
+	Field   string   `json:"field"`
+	Before  string   `json:"before,omitempty"`
+	After   string   `json:"after,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// versionFields flattens a recipe or meal into the fields compared between
+// versions
+type versionFields struct {
+	values map[string]string
+	lists  map[string][]string
+}
+
+var versionFieldOrder = []string{"name", "description", "image", "servings", "prep_minutes", "cook_minutes", "ingredients", "steps", "equipment", "tags", "recipes"}
+
+func optionalIntText(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return fmt.Sprint(*n)
+}
+
+// stepText is a step's line in a diff, with its timing if it has any
+func stepText(text string, timing StepTiming) string {
+	if described := timing.describe(); described != "" {
+		return text + " (" + described + ")"
+	}
+	return text
+}
+
+func recipeVersion(r *Recipe) versionFields {
+	v := versionFields{
+		values: map[string]string{
+			"name":         r.Name,
+			"description":  r.Description,
+			"image":        r.Image.String,
+			"servings":     optionalIntText(r.Servings),
+			"prep_minutes": optionalIntText(r.PrepMinutes),
+			"cook_minutes": optionalIntText(r.CookMinutes),
+		},
+		lists: map[string][]string{"tags": r.Tags, "equipment": r.Equipment},
+	}
+	for _, ingredient := range r.Ingredients {
+		v.lists["ingredients"] = append(v.lists["ingredients"], sectionLine(ingredient.Section, ingredientLine(ingredient)))
+	}
+	for _, step := range r.Steps {
+		v.lists["steps"] = append(v.lists["steps"], sectionLine(step.Section, stepText(step.Text, step.StepTiming)))
+	}
+	return v
+}
+
+func mealVersion(m *Meal) versionFields {
+	v := versionFields{
+		values: map[string]string{
+			"name":        m.Name,
+			"description": m.Description,
+			"image":       m.Image.String,
+			"servings":    optionalIntText(m.Servings),
+		},
+		lists: map[string][]string{"tags": m.Tags, "equipment": m.Equipment},
+	}
+	for _, ingredient := range m.Ingredients {
+		v.lists["ingredients"] = append(v.lists["ingredients"], sectionLine(ingredient.Section, ingredientLine(RecipeIngredient{Name: ingredient.Name, Amount: ingredient.Amount})))
+	}
+	for _, step := range m.Steps {
+		v.lists["steps"] = append(v.lists["steps"], sectionLine(step.Section, stepText(step.Text, step.StepTiming)))
+	}
+	for _, recipe := range m.MealRecipes {
+		v.lists["recipes"] = append(v.lists["recipes"], fmt.Sprint(recipe.RecipeID))
+	}
+	return v
+}
+
+func diffVersions(before, after versionFields) []Change {
+	changes := []Change{}
+	for _, field := range versionFieldOrder {
+		if b, a := before.values[field], after.values[field]; b != a {
+			changes = append(changes, Change{Field: field, Before: b, After: a})
+		}
+		added, removed := diffLines(before.lists[field], after.lists[field])
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, Change{Field: field, Added: added, Removed: removed})
+		}
+	}
+	return changes
+}
+
+// diffLines compares two lists by their longest common subsequence, so a
+// line that moved shows as removed and added.
+func diffLines(before, after []string) (added, removed []string) {
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, before[i])
+			i++
+		default:
+			added = append(added, after[j])
+			j++
+		}
+	}
+	removed = append(removed, before[i:]...)
+	added = append(added, after[j:]...)
+	return added, removed
+}
+
+// saveRevision records snapshot as the next revision of the recipe or meal.
+// column is recipe_id or meal_id.
+func saveRevision(tx *sqlx.Tx, column string, id int, snapshot interface{}) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO revisions (`+column+`, revision, snapshot)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2 FROM revisions WHERE `+column+`=$1`, id, types.JSONText(data))
+	return err
+}
+
+func getRevisions(db *sqlx.DB, column string, id int) ([]Revision, error) {
+	revisions := []Revision{}
+	err := db.Select(&revisions, `SELECT * FROM revisions WHERE `+column+`=$1 ORDER BY revision DESC`, id)
+	return revisions, err
+}
+
+func getRevision(db *sqlx.DB, column string, id int, revision int) (*Revision, error) {
+	r := Revision{}
+	if err := db.Get(&r, `SELECT * FROM revisions WHERE `+column+`=$1 AND revision=$2`, id, revision); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetRecipeRevisions lists a recipe's revisions, newest first, each with the
+// changes between it and the version that followed.
+func GetRecipeRevisions(db *sqlx.DB, id int) ([]Revision, error) {
+	current, err := GetRecipe(db, id)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := getRevisions(db, "recipe_id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	next := recipeVersion(current)
+	for i := range revisions {
+		snapshot := Recipe{}
+		if err := json.Unmarshal(revisions[i].Snapshot, &snapshot); err != nil {
+			return nil, err
+		}
+		version := recipeVersion(&snapshot)
+		revisions[i].Changes = diffVersions(version, next)
+		next = version
+	}
+	return revisions, nil
+}
+
+// RestoreRecipeRevision updates the recipe back to a revision. The version
+// being replaced becomes a revision itself, so a restore can be undone.
+func RestoreRecipeRevision(db *sqlx.DB, id int, revision int) (*Recipe, error) {
+	r, err := getRevision(db, "recipe_id", id, revision)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := Recipe{}
+	if err := json.Unmarshal(r.Snapshot, &snapshot); err != nil {
+		return nil, err
+	}
+	return UpdateRecipe(db, id, &snapshot)
+}
+
+// GetMealRevisions lists a meal's revisions, newest first, each with the
+// changes between it and the version that followed.
+func GetMealRevisions(db *sqlx.DB, id int) ([]Revision, error) {
+	current, err := GetMeal(db, id)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := getRevisions(db, "meal_id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	next := mealVersion(current)
+	for i := range revisions {
+		snapshot := Meal{}
+		if err := json.Unmarshal(revisions[i].Snapshot, &snapshot); err != nil {
+			return nil, err
+		}
+		version := mealVersion(&snapshot)
+		revisions[i].Changes = diffVersions(version, next)
+		next = version
+	}
+	return revisions, nil
+}
+
+// RestoreMealRevision updates the meal back to a revision, keeping the
+// version being replaced as a revision.
+func RestoreMealRevision(db *sqlx.DB, id int, revision int) (*Meal, error) {
+	r, err := getRevision(db, "meal_id", id, revision)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := Meal{}
+	if err := json.Unmarshal(r.Snapshot, &snapshot); err != nil {
+		return nil, err
+	}
+	return UpdateMeal(db, id, &snapshot)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	added, removed := diffLines([]string{"chop", "fry", "serve"}, []string{"chop", "season", "fry", "serve"})
+	assert.Equal(t, []string{"season"}, added)
+	assert.Empty(t, removed)
+
+	added, removed = diffLines([]string{"a", "b", "c"}, []string{"c", "a", "b"})
+	assert.Equal(t, []string{"c"}, added)
+	assert.Equal(t, []string{"c"}, removed)
+
+	added, removed = diffLines(nil, nil)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+}
+
+func TestDiffVersions(t *testing.T) {
+	servings := 4
+	before := recipeVersion(&Recipe{
+		Name:        "Soup",
+		Ingredients: []RecipeIngredient{{Name: "carrot", Amount: "2"}, {Name: "salt", Amount: "1 tsp"}},
+		Tags:        []string{"easy"},
+	})
+	after := recipeVersion(&Recipe{
+		Name:        "Carrot soup",
+		Servings:    &servings,
+		Ingredients: []RecipeIngredient{{Name: "carrot", Amount: "3"}, {Name: "salt", Amount: "1 tsp"}},
+		Tags:        []string{"easy"},
+	})
+
+	assert.Equal(t, []Change{
+		{Field: "name", Before: "Soup", After: "Carrot soup"},
+		{Field: "servings", After: "4"},
+		{Field: "ingredients", Added: []string{"3 carrot"}, Removed: []string{"2 carrot"}},
+	}, diffVersions(before, after))
+}
+
+func TestGetRecipeRevisions(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug", "image"}).AddRow(1, "Stew", "Hearty", "stew", nil))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "name", "amount", "calories"}))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}).AddRow(1, 1, 1, "Simmer"))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM revisions WHERE recipe_id=\\$1 ORDER BY revision DESC").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "meal_id", "revision", "snapshot", "created_at"}).
+			AddRow(8, 1, nil, 2, []byte(`{"name": "Stew", "description": "Hearty", "steps": [{"text": "Boil"}]}`), created).
+			AddRow(5, 1, nil, 1, []byte(`{"name": "Stew", "description": "Thin", "steps": [{"text": "Boil"}]}`), created))
+
+	revisions, err := GetRecipeRevisions(db, 1)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, []Change{{Field: "steps", Added: []string{"Simmer"}, Removed: []string{"Boil"}}}, revisions[0].Changes)
+	assert.Equal(t, []Change{{Field: "description", Before: "Thin", After: "Hearty"}}, revisions[1].Changes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// Ingredients and steps can be split into named sections, such as "For the
+// frosting". The section is stored on each line; lines without one belong to
+// the recipe or meal as a whole. Sections are ordered by their first line.
+
+// groupSections reorders items so each section's lines are together, with
+// sections in the order they first appear. Lines keep their order within a
+// section.
+func groupSections[T any](items []T, section func(T) string) []T {
+	names := []string{}
+	for _, item := range items {
+		if name := section(item); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) < 2 {
+		return items
+	}
+
+	grouped := make([]T, 0, len(items))
+	for _, name := range names {
+		for _, item := range items {
+			if section(item) == name {
+				grouped = append(grouped, item)
+			}
+		}
+	}
+	return grouped
+}
+
+// groupSteps groups steps by section like groupSections, numbering them
+// again in their new order. Steps without sections are left as they are.
+func groupSteps[T any](steps []T, order func(*T) *int, section func(T) string) []T {
+	if !slices.ContainsFunc(steps, func(step T) bool { return section(step) != "" }) {
+		return steps
+	}
+	steps = slices.Clone(steps)
+	slices.SortStableFunc(steps, func(a, b T) int { return *order(&a) - *order(&b) })
+	steps = groupSections(steps, section)
+	for i := range steps {
+		*order(&steps[i]) = i + 1
+	}
+	return steps
+}
+
+func cleanSection(section string) string {
+	return strings.Join(strings.Fields(section), " ")
+}
+
+// sectionLine prefixes a line with its section, for comparing versions
+func sectionLine(section string, line string) string {
+	if section == "" {
+		return line
+	}
+	return section + ": " + line
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupSections(t *testing.T) {
+	ingredients := []RecipeIngredient{
+		{Name: "flour", Section: "Cake"},
+		{Name: "butter", Section: "Frosting"},
+		{Name: "eggs", Section: "Cake"},
+		{Name: "sugar", Section: "Frosting"},
+	}
+	names := []string{}
+	for _, i := range groupSections(ingredients, func(i RecipeIngredient) string { return i.Section }) {
+		names = append(names, i.Name)
+	}
+	assert.Equal(t, []string{"flour", "eggs", "butter", "sugar"}, names)
+
+	plain := []RecipeIngredient{{Name: "salt"}, {Name: "pepper"}}
+	assert.Equal(t, plain, groupSections(plain, func(i RecipeIngredient) string { return i.Section }))
+}
+
+func TestGroupSteps(t *testing.T) {
+	steps := []RecipeStep{
+		{Order: 3, Text: "Frost", Section: "Frosting"},
+		{Order: 1, Text: "Bake", Section: "Cake"},
+		{Order: 2, Text: "Whip", Section: "Frosting"},
+	}
+	grouped := groupSteps(steps, func(s *RecipeStep) *int { return &s.Order }, func(s RecipeStep) string { return s.Section })
+	assert.Equal(t, []RecipeStep{
+		{Order: 1, Text: "Bake", Section: "Cake"},
+		{Order: 2, Text: "Whip", Section: "Frosting"},
+		{Order: 3, Text: "Frost", Section: "Frosting"},
+	}, grouped)
+	assert.Equal(t, 3, steps[0].Order, "the caller's steps are left alone")
+}
+
+func sectionedRecipe() Recipe {
+	recipe := newImportedRecipe()
+	recipe.Name = "Layer cake"
+	recipe.Description = "Two layers"
+	recipe.Slug = "layer-cake"
+	recipe.Ingredients = []RecipeIngredient{
+		{Name: "flour", Amount: "2 cups", Section: "For the cake"},
+		{Name: "butter", Amount: "1 cup", Section: "For the frosting"},
+	}
+	recipe.Steps = []RecipeStep{
+		{Order: 1, Text: "Bake the flour.", Section: "For the cake"},
+		{Order: 2, Text: "Whip the butter.", Section: "For the frosting"},
+	}
+	return recipe
+}
+
+func TestSectionsRoundTrip(t *testing.T) {
+	recipe := sectionedRecipe()
+	for _, format := range []string{RecipeFormatPaprika, RecipeFormatMealie} {
+		var b bytes.Buffer
+		require.NoError(t, EncodeRecipes(format, &b, []Recipe{recipe}), format)
+		decoded, err := DecodeRecipes(format, b.Bytes())
+		require.NoError(t, err, format)
+		require.Len(t, decoded, 1, format)
+		assert.Equal(t, recipe.Ingredients, decoded[0].Recipe.Ingredients, format)
+		assert.Equal(t, recipe.Steps, decoded[0].Recipe.Steps, format)
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeCooklang(&b, []Recipe{recipe}))
+	assert.Contains(t, b.String(), "== For the frosting ==")
+	decoded, err := DecodeCooklang(b.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, recipe.Steps, decoded[0].Recipe.Steps)
+	assert.Equal(t, "For the frosting", decoded[0].Recipe.Ingredients[1].Section)
+}
@@ -134,3 +134,38 @@ func UpdateShoppingList(db *sqlx.DB, householdID int, list *ShoppingList) error
 	_, err = db.Exec("UPDATE shopping_status SET status = $1 WHERE plan_id = $2", status, planID)
 	return err
 }
+
+// MarkPurchased checks off the items on a plan's shopping list that match
+// any of the ingredients bought, and returns the items it checked off
+func MarkPurchased(db *sqlx.DB, householdID int, planID int, ingredients []string) ([]ShoppingListItem, error) {
+	purchased := []ShoppingListItem{}
+	if len(ingredients) == 0 {
+		return purchased, nil
+	}
+	list, err := GetShoppingList(db, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	bought := map[string]bool{}
+	for _, name := range ingredients {
+		bought[name] = true
+	}
+	matcher := newIngredientMatcher(bought)
+	for i, item := range list.Ingredients {
+		if item.Checked {
+			continue
+		}
+		if _, ok := matcher.match(item.Name); ok {
+			list.Ingredients[i].Checked = true
+			purchased = append(purchased, list.Ingredients[i])
+		}
+	}
+	if len(purchased) == 0 {
+		return purchased, nil
+	}
+	if err := UpdateShoppingList(db, householdID, list); err != nil {
+		return nil, err
+	}
+	return purchased, nil
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrUnknownExportFormat = errors.New("unknown export format, expected one of txt, md, csv or html")
+
+// Shopping list export formats accepted by RenderShoppingList
+const (
+	ExportFormatText     = "txt"
+	ExportFormatMarkdown = "md"
+	ExportFormatCSV      = "csv"
+	ExportFormatHTML     = "html"
+)
+
+// ShoppingCategoryOther is used for ingredients that don't match any keyword
+const ShoppingCategoryOther = "Other"
+
+// shoppingCategories is ordered roughly the way a grocery store is walked
+var shoppingCategories = []struct {
+	Name     string
+	Keywords []string
+}{
+	{"Produce", []string{"apple", "avocado", "banana", "basil", "bean sprout", "blueberries", "broccoli", "cabbage", "carrot", "celery", "cilantro", "cucumber", "eggplant", "garlic", "ginger", "herb", "kale", "lemon", "lettuce", "lime", "mushroom", "onion", "orange", "parsley", "pepper", "potato", "raspberries", "scallion", "shallot", "spinach", "squash", "strawberries", "tomato", "zucchini"}},
+	{"Meat & Seafood", []string{"bacon", "beef", "chicken", "fish", "ham", "lamb", "pork", "salmon", "sausage", "shrimp", "steak", "tuna", "turkey"}},
+	{"Dairy & Eggs", []string{"butter", "cheddar", "cheese", "cream", "egg", "milk", "mozzarella", "parmesan", "yogurt"}},
+	{"Bakery", []string{"bagel", "baguette", "bread", "bun", "pita", "roll", "tortilla"}},
+	{"Frozen", []string{"frozen", "ice cream"}},
+	{"Pantry", []string{"broth", "flour", "honey", "noodle", "oil", "pasta", "rice", "salt", "sauce", "spice", "stock", "sugar", "vinegar"}},
+}
+
+// CategorizeIngredient returns the store section an ingredient is most likely found in.
+// Keywords are matched against whole words (allowing a plural suffix) so that
+// "aluminum foil" doesn't end up next to the olive oil.
+func CategorizeIngredient(name string) string {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}), " ") + " "
+	for _, category := range shoppingCategories {
+		for _, keyword := range category.Keywords {
+			for _, suffix := range []string{" ", "s ", "es "} {
+				if strings.Contains(words, " "+keyword+suffix) {
+					return category.Name
+				}
+			}
+		}
+	}
+	return ShoppingCategoryOther
+}
+
+// ShoppingListSection is a group of shopping list items that share a category
+type ShoppingListSection struct {
+	Category string
+	Items    []ShoppingListItem
+}
+
+// GroupShoppingList splits the list into category sections, preserving item order
+// within each section and the store order between sections.
+func GroupShoppingList(list *ShoppingList) []ShoppingListSection {
+	byCategory := map[string][]ShoppingListItem{}
+	for _, item := range list.Ingredients {
+		category := CategorizeIngredient(item.Name)
+		byCategory[category] = append(byCategory[category], item)
+	}
+
+	sections := []ShoppingListSection{}
+	for _, category := range shoppingCategories {
+		if items, ok := byCategory[category.Name]; ok {
+			sections = append(sections, ShoppingListSection{Category: category.Name, Items: items})
+		}
+	}
+	if items, ok := byCategory[ShoppingCategoryOther]; ok {
+		sections = append(sections, ShoppingListSection{Category: ShoppingCategoryOther, Items: items})
+	}
+	return sections
+}
+
+// ExportContentType returns the HTTP content type for an export format
+func ExportContentType(format string) (string, error) {
+	switch format {
+	case ExportFormatText:
+		return "text/plain; charset=utf-8", nil
+	case ExportFormatMarkdown:
+		return "text/markdown; charset=utf-8", nil
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8", nil
+	case ExportFormatHTML:
+		return "text/html; charset=utf-8", nil
+	}
+	return "", ErrUnknownExportFormat
+}
+
+func shoppingListTitle(list *ShoppingList) string {
+	return fmt.Sprintf("Shopping list for %s to %s",
+		list.Plan.StartDate.Format("Jan 2"), list.Plan.EndDate.Format("Jan 2, 2006"))
+}
+
+func itemLabel(item ShoppingListItem) string {
+	if item.Amount == "" {
+		return item.Name
+	}
+	return item.Amount + " " + item.Name
+}
+
+// RenderShoppingList writes the shopping list to w in the requested format
+func RenderShoppingList(w io.Writer, list *ShoppingList, format string) error {
+	sections := GroupShoppingList(list)
+
+	switch format {
+	case ExportFormatText:
+		var b bytes.Buffer
+		fmt.Fprintln(&b, shoppingListTitle(list))
+		for _, section := range sections {
+			fmt.Fprintf(&b, "\n%s\n", strings.ToUpper(section.Category))
+			for _, item := range section.Items {
+				box := "[ ]"
+				if item.Checked {
+					box = "[x]"
+				}
+				fmt.Fprintf(&b, "%s %s\n", box, itemLabel(item))
+			}
+		}
+		_, err := w.Write(b.Bytes())
+		return err
+
+	case ExportFormatMarkdown:
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "# %s\n", shoppingListTitle(list))
+		for _, section := range sections {
+			fmt.Fprintf(&b, "\n## %s\n\n", section.Category)
+			for _, item := range section.Items {
+				box := "[ ]"
+				if item.Checked {
+					box = "[x]"
+				}
+				fmt.Fprintf(&b, "- %s %s\n", box, itemLabel(item))
+			}
+		}
+		_, err := w.Write(b.Bytes())
+		return err
+
+	case ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"category", "name", "amount", "checked"})
+		for _, section := range sections {
+			for _, item := range section.Items {
+				cw.Write([]string{section.Category, item.Name, item.Amount, fmt.Sprint(item.Checked)})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case ExportFormatHTML:
+		return shoppingListTemplate.Execute(w, struct {
+			Title    string
+			Sections []ShoppingListSection
+		}{shoppingListTitle(list), sections})
+	}
+
+	return ErrUnknownExportFormat
+}
+
+var shoppingListTemplate = template.Must(template.New("shopping-list").Funcs(template.FuncMap{
+	"label": itemLabel,
+}).Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{.Title}}</title>
+    <meta charset="utf-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1" />
+    <style>
+      body { font-family: sans-serif; max-width: 40em; margin: 2em auto; }
+      h2 { border-bottom: 1px solid #ccc; font-size: 1.1em; }
+      ul { list-style: none; padding: 0; }
+      li { padding: 0.2em 0; }
+      li.checked { color: #888; text-decoration: line-through; }
+      @media print { body { margin: 0; } }
+    </style>
+  </head>
+  <body>
+    <h1>{{.Title}}</h1>
+    {{- range .Sections}}
+    <h2>{{.Category}}</h2>
+    <ul>
+      {{- range .Items}}
+      <li{{if .Checked}} class="checked"{{end}}><input type="checkbox" disabled{{if .Checked}} checked{{end}} /> {{label .}}</li>
+      {{- end}}
+    </ul>
+    {{- end}}
+  </body>
+</html>
+`))
+
+type ShoppingListShare struct {
+	Token       string    `db:"token" json:"token"`
+	PlanID      int       `db:"plan_id" json:"plan_id"`
+	HouseholdID int       `db:"household_id" json:"household_id"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// shareToken returns an unguessable, URL-safe token for a shopping list share
+func shareToken() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateShoppingListShare creates a token that grants read-only access to a plan's shopping list
+func CreateShoppingListShare(db *sqlx.DB, planID int, householdID int, duration time.Duration) (*ShoppingListShare, error) {
+	token, err := shareToken()
+	if err != nil {
+		fmt.Println("Error generating shopping list share token:", err)
+		return nil, err
+	}
+	share := ShoppingListShare{}
+	err = db.Get(&share, `INSERT INTO shopping_list_shares (token, plan_id, household_id, expires_at)
+		VALUES ($1, $2, $3, $4) RETURNING *`, token, planID, householdID, time.Now().Add(duration))
+	if err != nil {
+		fmt.Println("Error creating shopping list share:", err)
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetShoppingListShare looks up an unexpired share by token. Shares of plans
+// in the trash aren't found.
+func GetShoppingListShare(db *sqlx.DB, token string) (*ShoppingListShare, error) {
+	share := ShoppingListShare{}
+	err := db.Get(&share, `SELECT s.* FROM shopping_list_shares s JOIN plans p ON p.id = s.plan_id
+		WHERE s.token=$1 AND s.expires_at > NOW() AND p.deleted_at IS NULL`, token)
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
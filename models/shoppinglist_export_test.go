@@ -0,0 +1,127 @@
+package models
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestList() *ShoppingList {
+	return &ShoppingList{
+		Plan: Plan{
+			ID:        1,
+			StartDate: Date{Time: time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)},
+			EndDate:   Date{Time: time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)},
+		},
+		Ingredients: []ShoppingListItem{
+			{Name: "Chicken thighs", Amount: "2 lb"},
+			{Name: "Yellow onion", Amount: "1", Checked: true},
+			{Name: "Milk", Amount: "1 cup"},
+			{Name: "Paper towels", Amount: ""},
+		},
+	}
+}
+
+func TestCategorizeIngredient(t *testing.T) {
+	assert.Equal(t, "Produce", CategorizeIngredient("Red Onion"))
+	assert.Equal(t, "Meat & Seafood", CategorizeIngredient("ground beef"))
+	assert.Equal(t, "Dairy & Eggs", CategorizeIngredient("Eggs"))
+	assert.Equal(t, "Produce", CategorizeIngredient("Eggplant"))
+	assert.Equal(t, ShoppingCategoryOther, CategorizeIngredient("aluminum foil"))
+}
+
+func TestGroupShoppingList(t *testing.T) {
+	sections := GroupShoppingList(exportTestList())
+	require.Len(t, sections, 4)
+	assert.Equal(t, "Produce", sections[0].Category)
+	assert.Equal(t, "Meat & Seafood", sections[1].Category)
+	assert.Equal(t, "Dairy & Eggs", sections[2].Category)
+	assert.Equal(t, ShoppingCategoryOther, sections[3].Category)
+}
+
+func TestRenderShoppingList(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, RenderShoppingList(&b, exportTestList(), ExportFormatText))
+		out := b.String()
+		assert.Contains(t, out, "Shopping list for Jun 14 to Jun 20, 2025")
+		assert.Contains(t, out, "PRODUCE\n[x] 1 Yellow onion\n")
+		assert.Contains(t, out, "[ ] 2 lb Chicken thighs")
+		assert.Contains(t, out, "[ ] Paper towels")
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, RenderShoppingList(&b, exportTestList(), ExportFormatMarkdown))
+		out := b.String()
+		assert.Contains(t, out, "# Shopping list for")
+		assert.Contains(t, out, "## Dairy & Eggs\n\n- [ ] 1 cup Milk\n")
+		assert.Contains(t, out, "- [x] 1 Yellow onion")
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, RenderShoppingList(&b, exportTestList(), ExportFormatCSV))
+		assert.Equal(t, "category,name,amount,checked\n"+
+			"Produce,Yellow onion,1,true\n"+
+			"Meat & Seafood,Chicken thighs,2 lb,false\n"+
+			"Dairy & Eggs,Milk,1 cup,false\n"+
+			"Other,Paper towels,,false\n", b.String())
+	})
+
+	t.Run("html", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, RenderShoppingList(&b, exportTestList(), ExportFormatHTML))
+		out := b.String()
+		assert.Contains(t, out, "<h2>Meat &amp; Seafood</h2>")
+		assert.Contains(t, out, `<li class="checked"><input type="checkbox" disabled checked /> 1 Yellow onion</li>`)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		var b bytes.Buffer
+		assert.Equal(t, ErrUnknownExportFormat, RenderShoppingList(&b, exportTestList(), "pdf"))
+		_, err := ExportContentType("pdf")
+		assert.Equal(t, ErrUnknownExportFormat, err)
+	})
+}
+
+func TestShoppingListShares(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"token", "plan_id", "household_id", "created_at", "expires_at"}).
+		AddRow("TOKEN", 1, 42, now, now.Add(time.Hour))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO shopping_list_shares")).
+		WithArgs(sqlmock.AnyArg(), 1, 42, sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	share, err := CreateShoppingListShare(db, 1, 42, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "TOKEN", share.Token)
+	assert.Equal(t, 42, share.HouseholdID)
+
+	mock.ExpectQuery(`SELECT s.\* FROM shopping_list_shares s JOIN plans p ON p.id = s.plan_id\s+WHERE s.token=\$1 AND s.expires_at > NOW\(\) AND p.deleted_at IS NULL`).
+		WithArgs("MISSING").
+		WillReturnRows(sqlmock.NewRows([]string{"token"}))
+	_, err = GetShoppingListShare(db, "MISSING")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShareToken(t *testing.T) {
+	a, err := shareToken()
+	require.NoError(t, err)
+	b, err := shareToken()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 24)
+	assert.Regexp(t, `^[A-Za-z0-9_-]+$`, a)
+	assert.NotEqual(t, a, b)
+}
@@ -51,7 +51,7 @@ func TestGetShoppingList(t *testing.T) {
 		ingredientRows := sqlmock.NewRows([]string{"name", "amount"}).
 			AddRow("Flour", "1kg").
 			AddRow("Sugar", "500g")
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE pm.plan_id=$1 AND pm.household_id=$2")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p ON p.id = pm.plan_id JOIN meals m ON m.id = pm.meal_id WHERE pm.plan_id=$1 AND p.household_id=$2 AND m.deleted_at IS NULL")).
 			WithArgs(planID, householdID).
 			WillReturnRows(ingredientRows)
 
@@ -91,7 +91,7 @@ func TestGetShoppingList(t *testing.T) {
 		// Mock for GetPlanIngredients
 		ingredientRows := sqlmock.NewRows([]string{"name", "amount"}).
 			AddRow("Milk", "1L")
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE pm.plan_id=$1 AND pm.household_id=$2")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p ON p.id = pm.plan_id JOIN meals m ON m.id = pm.meal_id WHERE pm.plan_id=$1 AND p.household_id=$2 AND m.deleted_at IS NULL")).
 			WithArgs(planID, householdID).
 			WillReturnRows(ingredientRows)
 
@@ -134,7 +134,7 @@ func TestGetShoppingList(t *testing.T) {
 			WithArgs(planID, emptyStatusJSON).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id WHERE pm.plan_id=$1 AND pm.household_id=$2")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT i.name, i.amount FROM meal_ingredients i JOIN plan_meals pm ON pm.meal_id = i.meal_id JOIN plans p ON p.id = pm.plan_id JOIN meals m ON m.id = pm.meal_id WHERE pm.plan_id=$1 AND p.household_id=$2 AND m.deleted_at IS NULL")).
 			WithArgs(planID, householdID).
 			WillReturnError(errors.New("db error fetching ingredients"))
 
@@ -146,6 +146,35 @@ func TestGetShoppingList(t *testing.T) {
 	})
 }
 
+func TestShoppingListLeavesOutTrashedMeals(t *testing.T) {
+	sqlxDB, mock := setupMockDB(t)
+	defer sqlxDB.Close()
+
+	// Meal 5 is in plan 1 alongside meal 6, then goes to the trash
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE meals SET deleted_at=NOW() WHERE id=$1")).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, DeleteMeal(sqlxDB, 5))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM plans WHERE id = $1")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "household_id", "start_date", "end_date"}).
+			AddRow(1, 42, time.Now(), time.Now().AddDate(0, 0, 6)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM shopping_status WHERE plan_id = $1")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"plan_id", "status"}).AddRow(1, []byte(`{"items":[]}`)))
+	// Only meal 6's ingredients are left once trashed meals are filtered out
+	mock.ExpectQuery(`FROM meal_ingredients i .*JOIN meals m ON m.id = pm.meal_id .*AND m.deleted_at IS NULL`).
+		WithArgs(1, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "amount"}).AddRow("Rice", "2 cups"))
+
+	list, err := GetShoppingList(sqlxDB, 1)
+	require.NoError(t, err)
+	require.Len(t, list.Ingredients, 1)
+	assert.Equal(t, "Rice", list.Ingredients[0].Name)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateShoppingList(t *testing.T) {
 	sqlxDB, mock := setupMockDB(t)
 	defer sqlxDB.Close()
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrSubRecipe = errors.New("invalid sub-recipe")
+
+// checkSubRecipes validates the recipes used by ingredient lines of recipe
+// id: they must exist, and none of them may use recipe id, directly or
+// through their own components. id is 0 for a recipe not yet created.
+func checkSubRecipes(db sqlx.Queryer, id int, ingredients []RecipeIngredient) error {
+	subIDs := []int{}
+	for _, ingredient := range ingredients {
+		if ingredient.SubRecipeID == nil {
+			continue
+		}
+		if ingredient.Quantity != nil && *ingredient.Quantity <= 0 {
+			return fmt.Errorf("%w: quantity of %q must be more than 0", ErrSubRecipe, ingredient.Name)
+		}
+		if *ingredient.SubRecipeID == id {
+			return fmt.Errorf("%w: a recipe can't use itself", ErrSubRecipe)
+		}
+		subIDs = append(subIDs, *ingredient.SubRecipeID)
+	}
+	if len(subIDs) == 0 {
+		return nil
+	}
+	subIDs = uniqueInts(subIDs)
+
+	var found int
+	if err := sqlx.Get(db, &found, `SELECT COUNT(*) FROM recipes WHERE id = ANY($1)`, pq.Array(subIDs)); err != nil {
+		return err
+	}
+	if found != len(subIDs) {
+		return fmt.Errorf("%w: recipe not found", ErrSubRecipe)
+	}
+	if id == 0 {
+		return nil
+	}
+
+	// Walk down the components looking for the recipe being saved
+	seen := map[int]bool{}
+	for frontier := subIDs; len(frontier) > 0; {
+		next := []int{}
+		err := sqlx.Select(db, &next, `SELECT DISTINCT sub_recipe_id FROM recipe_ingredients
+			WHERE recipe_id = ANY($1) AND sub_recipe_id IS NOT NULL`, pq.Array(frontier))
+		if err != nil {
+			return err
+		}
+		frontier = []int{}
+		for _, subID := range next {
+			if subID == id {
+				return fmt.Errorf("%w: that would make the recipe part of itself", ErrSubRecipe)
+			}
+			if !seen[subID] {
+				seen[subID] = true
+				frontier = append(frontier, subID)
+			}
+		}
+	}
+	return nil
+}
+
+// RawIngredients flattens a loaded recipe's ingredients, replacing each line
+// that uses another recipe with that recipe's own raw ingredients scaled by
+// the line's quantity.
+func (r *Recipe) RawIngredients() []Ingredient {
+	ingredients := []Ingredient{}
+	r.expandIngredients(1, func(line RecipeIngredient, scale float64) {
+		ingredients = append(ingredients, Ingredient{Name: line.Name, Amount: ScaleAmount(line.Amount, scale)})
+	})
+	return ingredients
+}
+
+// expandIngredients calls fn with each raw ingredient line of a loaded
+// recipe and the factor its amount is scaled by, descending into the
+// recipes that lines use.
+func (r *Recipe) expandIngredients(scale float64, fn func(line RecipeIngredient, scale float64)) {
+	for _, line := range r.Ingredients {
+		if line.SubRecipe == nil {
+			fn(line, scale)
+			continue
+		}
+		batches := 1.0
+		if line.Quantity != nil {
+			batches = *line.Quantity
+		}
+		line.SubRecipe.expandIngredients(scale*batches, fn)
+	}
+}
+
+// componentsFirst orders recipes so each comes after the recipes its
+// ingredient lines use, as far as they are in the list, so they can be
+// created in that order.
+func componentsFirst(recipes []Recipe) []Recipe {
+	byID := map[int]int{}
+	for i, recipe := range recipes {
+		byID[recipe.ID] = i
+	}
+
+	ordered := make([]Recipe, 0, len(recipes))
+	visited := make([]bool, len(recipes))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		for _, line := range recipes[i].Ingredients {
+			if line.SubRecipeID == nil {
+				continue
+			}
+			if j, ok := byID[*line.SubRecipeID]; ok {
+				visit(j)
+			}
+		}
+		ordered = append(ordered, recipes[i])
+	}
+	for i := range recipes {
+		visit(i)
+	}
+	return ordered
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSubRecipes(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	bechamel, roux := 2, 3
+	lines := []RecipeIngredient{{Name: "noodles", Amount: "1 box"}, {Name: "béchamel", SubRecipeID: &bechamel}}
+
+	// Béchamel uses a roux, which doesn't lead back to lasagna
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM recipes WHERE id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT DISTINCT sub_recipe_id FROM recipe_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"sub_recipe_id"}).AddRow(roux))
+	mock.ExpectQuery("SELECT DISTINCT sub_recipe_id FROM recipe_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"sub_recipe_id"}))
+	assert.NoError(t, checkSubRecipes(db, 1, lines))
+
+	// The roux now uses lasagna
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM recipes WHERE id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT DISTINCT sub_recipe_id FROM recipe_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"sub_recipe_id"}).AddRow(roux))
+	mock.ExpectQuery("SELECT DISTINCT sub_recipe_id FROM recipe_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"sub_recipe_id"}).AddRow(1))
+	assert.ErrorIs(t, checkSubRecipes(db, 1, lines), ErrSubRecipe)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM recipes WHERE id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	assert.ErrorIs(t, checkSubRecipes(db, 0, lines), ErrSubRecipe)
+
+	self := 1
+	assert.ErrorIs(t, checkSubRecipes(db, 1, []RecipeIngredient{{Name: "more lasagna", SubRecipeID: &self}}), ErrSubRecipe)
+	assert.NoError(t, checkSubRecipes(db, 1, []RecipeIngredient{{Name: "salt"}}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRecipeWithSubRecipe(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	columns := []string{"id", "recipe_id", "name", "amount", "calories", "sub_recipe_id", "quantity"}
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug"}).AddRow(1, "Lasagna", "Layers", "lasagna"))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(10, 1, "noodles", "1 box", nil, nil, nil).
+			AddRow(11, 1, "béchamel", "", nil, 2, 0.5))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectQuery("SELECT \\* FROM recipes WHERE id=\\$1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "slug"}).AddRow(2, "Béchamel", "Sauce", "bechamel"))
+	mock.ExpectQuery("SELECT \\* FROM recipe_ingredients").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(20, 2, "milk", "2 cups", nil, nil, nil).
+			AddRow(21, 2, "butter", "4 tbsp", nil, nil, nil))
+	mock.ExpectQuery("SELECT \\* FROM recipe_steps").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipe_id", "order", "text"}))
+	mock.ExpectQuery("SELECT t.name FROM tags").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	recipe, err := GetRecipe(db, 1)
+	require.NoError(t, err)
+	require.NotNil(t, recipe.Ingredients[1].SubRecipe)
+	assert.Equal(t, "Béchamel", recipe.Ingredients[1].SubRecipe.Name)
+	assert.Equal(t, []Ingredient{
+		{Name: "noodles", Amount: "1 box"},
+		{Name: "milk", Amount: "1 cups"},
+		{Name: "butter", Amount: "2 tbsp"},
+	}, recipe.RawIngredients())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestComponentsFirst(t *testing.T) {
+	bechamel, roux := 2, 3
+	recipes := []Recipe{
+		{ID: 1, Name: "Lasagna", Ingredients: []RecipeIngredient{{SubRecipeID: &bechamel}}},
+		{ID: 2, Name: "Béchamel", Ingredients: []RecipeIngredient{{SubRecipeID: &roux}}},
+		{ID: 3, Name: "Roux"},
+	}
+	names := []string{}
+	for _, r := range componentsFirst(recipes) {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"Roux", "Béchamel", "Lasagna"}, names)
+}
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidTime = errors.New("invalid time")
+
+// StepTiming is how long a step takes and the heat it needs. Active minutes
+// need the cook's attention; passive minutes (baking, resting, marinating)
+// don't.
+type StepTiming struct {
+	ActiveMinutes  *int   `db:"active_minutes" json:"active_minutes,omitempty"`
+	PassiveMinutes *int   `db:"passive_minutes" json:"passive_minutes,omitempty"`
+	Temperature    string `db:"temperature" json:"temperature,omitempty"`
+}
+
+// CookingTime is how long a recipe or meal takes, in minutes. Prep is the
+// hands-on time and Cook the time spent waiting on it.
+type CookingTime struct {
+	Prep  int `json:"prep"`
+	Cook  int `json:"cook"`
+	Total int `json:"total"`
+}
+
+// stepMinutes adds up the timing of a recipe's or meal's steps. Timed is
+// false when none of them has a duration.
+type stepMinutes struct {
+	ID      int  `db:"id"`
+	Active  int  `db:"active"`
+	Passive int  `db:"passive"`
+	Timed   bool `db:"timed"`
+}
+
+func (t StepTiming) validate() error {
+	if (t.ActiveMinutes != nil && *t.ActiveMinutes < 0) || (t.PassiveMinutes != nil && *t.PassiveMinutes < 0) {
+		return fmt.Errorf("%w: step durations can't be negative", ErrInvalidTime)
+	}
+	return nil
+}
+
+// describe lists the timing for revision diffs, e.g. "10 min active, 180°C"
+func (t StepTiming) describe() string {
+	parts := []string{}
+	if t.ActiveMinutes != nil {
+		parts = append(parts, fmt.Sprintf("%d min active", *t.ActiveMinutes))
+	}
+	if t.PassiveMinutes != nil {
+		parts = append(parts, fmt.Sprintf("%d min passive", *t.PassiveMinutes))
+	}
+	if t.Temperature != "" {
+		parts = append(parts, t.Temperature)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (t StepTiming) add(total stepMinutes) stepMinutes {
+	if t.ActiveMinutes != nil {
+		total.Active += *t.ActiveMinutes
+		total.Timed = true
+	}
+	if t.PassiveMinutes != nil {
+		total.Passive += *t.PassiveMinutes
+		total.Timed = true
+	}
+	return total
+}
+
+// cookingTime combines steps with the times of the recipes they use. An
+// explicit prep or cook time replaces the one added up. It returns nil when
+// nothing is known about the time.
+func cookingTime(prep *int, cook *int, steps stepMinutes, components []*CookingTime) *CookingTime {
+	t := CookingTime{Prep: steps.Active, Cook: steps.Passive}
+	timed := steps.Timed
+	for _, component := range components {
+		if component == nil {
+			continue
+		}
+		t.Prep += component.Prep
+		t.Cook += component.Cook
+		timed = true
+	}
+	if prep != nil {
+		t.Prep = *prep
+		timed = true
+	}
+	if cook != nil {
+		t.Cook = *cook
+		timed = true
+	}
+	if !timed {
+		return nil
+	}
+	t.Total = t.Prep + t.Cook
+	return &t
+}
+
+func validateRecipeTimes(r *Recipe) error {
+	if (r.PrepMinutes != nil && *r.PrepMinutes < 0) || (r.CookMinutes != nil && *r.CookMinutes < 0) {
+		return fmt.Errorf("%w: prep and cook minutes can't be negative", ErrInvalidTime)
+	}
+	for _, step := range r.Steps {
+		if err := step.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMealTimes(m *Meal) error {
+	for _, step := range m.Steps {
+		if err := step.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setTime works out a loaded recipe's time from its steps and the recipes
+// its ingredient lines use
+func (r *Recipe) setTime() {
+	steps := stepMinutes{}
+	for _, step := range r.Steps {
+		steps = step.StepTiming.add(steps)
+	}
+	components := []*CookingTime{}
+	for _, line := range r.Ingredients {
+		if line.SubRecipe != nil {
+			components = append(components, line.SubRecipe.Time)
+		}
+	}
+	r.Time = cookingTime(r.PrepMinutes, r.CookMinutes, steps, components)
+}
+
+// GetRecipeTimes works out the time of every recipe that has one, with the
+// same rules as GetRecipe, without loading each recipe
+func GetRecipeTimes(db *sqlx.DB) (map[int]*CookingTime, error) {
+	recipes := []struct {
+		ID          int  `db:"id"`
+		PrepMinutes *int `db:"prep_minutes"`
+		CookMinutes *int `db:"cook_minutes"`
+	}{}
+	if err := db.Select(&recipes, `SELECT id, prep_minutes, cook_minutes FROM recipes`); err != nil {
+		return nil, err
+	}
+	steps := []stepMinutes{}
+	err := db.Select(&steps, `SELECT recipe_id AS id,
+			COALESCE(SUM(active_minutes), 0) AS active,
+			COALESCE(SUM(passive_minutes), 0) AS passive,
+			COUNT(active_minutes) + COUNT(passive_minutes) > 0 AS timed
+		FROM recipe_steps GROUP BY recipe_id`)
+	if err != nil {
+		return nil, err
+	}
+	edges := []struct {
+		RecipeID    int `db:"recipe_id"`
+		SubRecipeID int `db:"sub_recipe_id"`
+	}{}
+	err = db.Select(&edges, `SELECT recipe_id, sub_recipe_id FROM recipe_ingredients WHERE sub_recipe_id IS NOT NULL ORDER BY recipe_id, position`)
+	if err != nil {
+		return nil, err
+	}
+
+	stepsByID := map[int]stepMinutes{}
+	for _, s := range steps {
+		stepsByID[s.ID] = s
+	}
+	subIDs := map[int][]int{}
+	for _, edge := range edges {
+		subIDs[edge.RecipeID] = append(subIDs[edge.RecipeID], edge.SubRecipeID)
+	}
+
+	byID := map[int]int{}
+	for i, recipe := range recipes {
+		byID[recipe.ID] = i
+	}
+	times := map[int]*CookingTime{}
+	done := map[int]bool{}
+	var visit func(id int, parents []int)
+	visit = func(id int, parents []int) {
+		i, ok := byID[id]
+		if !ok || done[id] {
+			return
+		}
+		parents = append(parents, id)
+		components := []*CookingTime{}
+		for _, subID := range subIDs[id] {
+			if slices.Contains(parents, subID) {
+				continue
+			}
+			visit(subID, parents)
+			components = append(components, times[subID])
+		}
+		times[id] = cookingTime(recipes[i].PrepMinutes, recipes[i].CookMinutes, stepsByID[id], components)
+		done[id] = true
+	}
+	for _, recipe := range recipes {
+		visit(recipe.ID, nil)
+	}
+	return times, nil
+}
+
+// ApplyRecipeTimes sets each recipe's time from times
+func ApplyRecipeTimes(recipes []Recipe, times map[int]*CookingTime) {
+	for i := range recipes {
+		recipes[i].Time = times[recipes[i].ID]
+	}
+}
+
+// GetMealTimes works out the time of each of the meals: its own steps plus
+// the time of every recipe in it, made one after another.
+func GetMealTimes(db *sqlx.DB, ids []int) (map[int]*CookingTime, error) {
+	times := map[int]*CookingTime{}
+	if len(ids) == 0 {
+		return times, nil
+	}
+	recipeTimes, err := GetRecipeTimes(db)
+	if err != nil {
+		return nil, err
+	}
+	steps := []stepMinutes{}
+	err = db.Select(&steps, `SELECT meal_id AS id,
+			COALESCE(SUM(active_minutes), 0) AS active,
+			COALESCE(SUM(passive_minutes), 0) AS passive,
+			COUNT(active_minutes) + COUNT(passive_minutes) > 0 AS timed
+		FROM meal_steps WHERE meal_id = ANY($1) GROUP BY meal_id`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	mealRecipes := []MealRecipes{}
+	err = db.Select(&mealRecipes, `SELECT meal_id, recipe_id FROM meal_recipes WHERE meal_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	stepsByID := map[int]stepMinutes{}
+	for _, s := range steps {
+		stepsByID[s.ID] = s
+	}
+	components := map[int][]*CookingTime{}
+	for _, mr := range mealRecipes {
+		components[mr.MealID] = append(components[mr.MealID], recipeTimes[mr.RecipeID])
+	}
+	for _, id := range ids {
+		times[id] = cookingTime(nil, nil, stepsByID[id], components[id])
+	}
+	return times, nil
+}
+
+// ApplyMealTimes sets each meal's time from times
+func ApplyMealTimes(meals []Meal, times map[int]*CookingTime) {
+	for i := range meals {
+		meals[i].Time = times[meals[i].ID]
+	}
+}
+
+// WithinTime reports whether a known time is at most maxMinutes. Unknown
+// times never fit, since nothing says they are quick.
+func WithinTime(t *CookingTime, maxMinutes int) bool {
+	return t != nil && t.Total <= maxMinutes
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func minutes(n int) *int {
+	return &n
+}
+
+func TestSetRecipeTime(t *testing.T) {
+	crust := Recipe{Steps: []RecipeStep{
+		{Text: "Mix", StepTiming: StepTiming{ActiveMinutes: minutes(10)}},
+		{Text: "Chill", StepTiming: StepTiming{PassiveMinutes: minutes(30)}},
+	}}
+	crust.setTime()
+	require.NotNil(t, crust.Time)
+	assert.Equal(t, CookingTime{Prep: 10, Cook: 30, Total: 40}, *crust.Time)
+
+	pie := Recipe{
+		Ingredients: []RecipeIngredient{{Name: "crust", SubRecipe: &crust}},
+		Steps: []RecipeStep{
+			{Text: "Fill", StepTiming: StepTiming{ActiveMinutes: minutes(15)}},
+			{Text: "Bake", StepTiming: StepTiming{PassiveMinutes: minutes(45), Temperature: "190°C"}},
+		},
+	}
+	pie.setTime()
+	assert.Equal(t, CookingTime{Prep: 25, Cook: 75, Total: 100}, *pie.Time)
+
+	// Explicit times win over the steps
+	pie.CookMinutes = minutes(60)
+	pie.setTime()
+	assert.Equal(t, CookingTime{Prep: 25, Cook: 60, Total: 85}, *pie.Time)
+
+	untimed := Recipe{Steps: []RecipeStep{{Text: "Serve"}}}
+	untimed.setTime()
+	assert.Nil(t, untimed.Time)
+	assert.False(t, WithinTime(untimed.Time, 30))
+	assert.True(t, WithinTime(pie.Time, 85))
+}
+
+func TestGetMealTimes(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, prep_minutes, cook_minutes FROM recipes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "prep_minutes", "cook_minutes"}).
+			AddRow(1, nil, nil).AddRow(2, 5, nil).AddRow(3, nil, nil))
+	mock.ExpectQuery("SELECT recipe_id AS id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active", "passive", "timed"}).
+			AddRow(1, 10, 20, true).AddRow(2, 0, 15, true))
+	mock.ExpectQuery("SELECT recipe_id, sub_recipe_id FROM recipe_ingredients").
+		WillReturnRows(sqlmock.NewRows([]string{"recipe_id", "sub_recipe_id"}).AddRow(1, 2))
+	mock.ExpectQuery("SELECT meal_id AS id").WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active", "passive", "timed"}).AddRow(8, 5, 0, true))
+	mock.ExpectQuery("SELECT meal_id, recipe_id FROM meal_recipes").WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"meal_id", "recipe_id"}).AddRow(8, 1).AddRow(9, 3))
+
+	times, err := GetMealTimes(db, []int{8, 9})
+	require.NoError(t, err)
+	// Meal 8: its own 5 minutes plus recipe 1, which uses recipe 2
+	assert.Equal(t, CookingTime{Prep: 20, Cook: 35, Total: 55}, *times[8])
+	assert.Nil(t, times[9])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDurationMinutes(t *testing.T) {
+	assert.Equal(t, 90, *durationMinutes("PT1H30M"))
+	assert.Equal(t, 20, *durationMinutes("pt20m"))
+	assert.Equal(t, 1500, *durationMinutes("P1DT1H"))
+	assert.Equal(t, 0, *durationMinutes("PT30S"))
+	assert.Nil(t, durationMinutes("20 minutes"))
+	assert.Nil(t, durationMinutes("PT"))
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// TrashItem is a deleted recipe, meal or plan. Plans have dates instead of a
+// name and slug.
+type TrashItem struct {
+	ID        int        `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name,omitempty"`
+	Slug      string     `db:"slug" json:"slug,omitempty"`
+	StartDate *Date      `db:"start_date" json:"start_date,omitempty"`
+	EndDate   *Date      `db:"end_date" json:"end_date,omitempty"`
+	DeletedAt time.Time  `db:"deleted_at" json:"deleted_at"`
+	PurgeAt   *time.Time `db:"-" json:"purge_at,omitempty"`
+}
+
+// Trash lists deleted recipes and meals, and the household's deleted plans
+type Trash struct {
+	Recipes []TrashItem `json:"recipes"`
+	Meals   []TrashItem `json:"meals"`
+	Plans   []TrashItem `json:"plans"`
+}
+
+// AffectedMeal is a meal that uses a recipe about to be deleted
+type AffectedMeal struct {
+	ID   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+	Slug string `db:"slug" json:"slug"`
+}
+
+// AffectedPlan is a current or upcoming plan with a meal about to be deleted
+type AffectedPlan struct {
+	ID        int  `db:"id" json:"id"`
+	StartDate Date `db:"start_date" json:"start_date"`
+	EndDate   Date `db:"end_date" json:"end_date"`
+}
+
+// DeletionImpact lists what still uses a recipe or meal. Deleted items stay
+// in place until they are purged, when they are taken out of these meals and
+// plans.
+type DeletionImpact struct {
+	Meals []AffectedMeal `json:"meals"`
+	Plans []AffectedPlan `json:"plans"`
+}
+
+func (d *DeletionImpact) Empty() bool {
+	return len(d.Meals) == 0 && len(d.Plans) == 0
+}
+
+// PurgeResult counts what PurgeTrash deleted
+type PurgeResult struct {
+	Recipes int `json:"recipes"`
+	Meals   int `json:"meals"`
+	Plans   int `json:"plans"`
+}
+
+// trash and untrash move a row of recipes, meals or plans in and out of the
+// trash, returning sql.ErrNoRows if it was already there or not
+func trash(db *sqlx.DB, table string, id int) error {
+	res, err := db.Exec(`UPDATE `+table+` SET deleted_at=NOW() WHERE id=$1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func untrash(db *sqlx.DB, table string, id int) error {
+	res, err := db.Exec(`UPDATE `+table+` SET deleted_at=NULL WHERE id=$1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteRecipe moves a recipe to the trash. Meals keep it until it's purged.
+func DeleteRecipe(db *sqlx.DB, i int) error {
+	return trash(db, "recipes", i)
+}
+
+func RestoreRecipe(db *sqlx.DB, i int) error {
+	return untrash(db, "recipes", i)
+}
+
+// DeleteMeal moves a meal to the trash. Plans keep it until it's purged.
+func DeleteMeal(db *sqlx.DB, i int) error {
+	return trash(db, "meals", i)
+}
+
+func RestoreMeal(db *sqlx.DB, i int) error {
+	return untrash(db, "meals", i)
+}
+
+// DeletePlan moves a plan to the trash
+func DeletePlan(db *sqlx.DB, id int) error {
+	return trash(db, "plans", id)
+}
+
+// RestorePlan takes a plan out of the trash, unless a plan made since covers
+// any of its days.
+func RestorePlan(db *sqlx.DB, id int) error {
+	plan, err := GetPlan(db, id)
+	if err != nil {
+		return err
+	}
+	if plan.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	overlaps, err := GetOverlappingPlans(db, plan.HouseholdID, plan)
+	if err != nil {
+		return err
+	}
+	if len(overlaps) > 0 {
+		return &PlanOverlapError{Plans: overlaps}
+	}
+	return untrash(db, "plans", id)
+}
+
+// activeRecipes loads a meal's recipes, leaving out those in the trash so
+// they no longer count towards the meal's ingredients, nutrition or cost
+func activeRecipes(db *sqlx.DB, meal *Meal) ([]*Recipe, error) {
+	recipes := []*Recipe{}
+	for _, mr := range meal.MealRecipes {
+		recipe, err := GetRecipe(db, mr.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+		if recipe.DeletedAt == nil {
+			recipes = append(recipes, recipe)
+		}
+	}
+	return recipes, nil
+}
+
+// GetTrash lists what's in the trash, most recently deleted first. With a
+// retention, each item says when it will be purged.
+func GetTrash(db *sqlx.DB, householdID int, retention time.Duration) (*Trash, error) {
+	t := &Trash{Recipes: []TrashItem{}, Meals: []TrashItem{}, Plans: []TrashItem{}}
+	err := db.Select(&t.Recipes, `SELECT id, name, slug, deleted_at FROM recipes WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&t.Meals, `SELECT id, name, slug, deleted_at FROM meals WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&t.Plans, `SELECT id, start_date, end_date, deleted_at FROM plans
+		WHERE household_id=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC`, householdID)
+	if err != nil {
+		return nil, err
+	}
+
+	if retention > 0 {
+		for _, items := range [][]TrashItem{t.Recipes, t.Meals, t.Plans} {
+			for i := range items {
+				purgeAt := items[i].DeletedAt.Add(retention)
+				items[i].PurgeAt = &purgeAt
+			}
+		}
+	}
+	return t, nil
+}
+
+// plansUsingMeals returns the household's current and upcoming plans that
+// include any of the meals
+func plansUsingMeals(db *sqlx.DB, householdID int, mealIDs []int) ([]AffectedPlan, error) {
+	plans := []AffectedPlan{}
+	if householdID == 0 || len(mealIDs) == 0 {
+		return plans, nil
+	}
+	err := db.Select(&plans, `SELECT DISTINCT p.id, p.start_date, p.end_date FROM plans p
+		JOIN plan_meals pm ON pm.plan_id = p.id
+		WHERE pm.meal_id = ANY($1) AND p.household_id=$2 AND p.deleted_at IS NULL AND p.end_date >= $3
+		ORDER BY p.start_date`, pq.Array(mealIDs), householdID, startOfToday())
+	return plans, err
+}
+
+// RecipeDeletionImpact lists the meals using a recipe, and those of the
+// household's plans that include one of the meals.
+func RecipeDeletionImpact(db *sqlx.DB, id int, householdID int) (*DeletionImpact, error) {
+	impact := &DeletionImpact{Meals: []AffectedMeal{}}
+	err := db.Select(&impact.Meals, `SELECT m.id, m.name, m.slug FROM meals m
+		JOIN meal_recipes mr ON mr.meal_id = m.id
+		WHERE mr.recipe_id=$1 AND m.deleted_at IS NULL ORDER BY m.name`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mealIDs := []int{}
+	for _, m := range impact.Meals {
+		mealIDs = append(mealIDs, m.ID)
+	}
+	impact.Plans, err = plansUsingMeals(db, householdID, mealIDs)
+	if err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// MealDeletionImpact lists the household's plans that include a meal
+func MealDeletionImpact(db *sqlx.DB, id int, householdID int) (*DeletionImpact, error) {
+	plans, err := plansUsingMeals(db, householdID, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	return &DeletionImpact{Meals: []AffectedMeal{}, Plans: plans}, nil
+}
+
+// PurgeTrash permanently deletes everything that went in the trash before
+// cutoff
+func PurgeTrash(db *sqlx.DB, cutoff time.Time) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	var errs []error
+	for _, table := range []struct {
+		name  string
+		purge func(*sqlx.DB, int) error
+		count *int
+	}{
+		{"plans", purgePlan, &result.Plans},
+		{"meals", purgeMeal, &result.Meals},
+		{"recipes", purgeRecipe, &result.Recipes},
+	} {
+		ids := []int{}
+		if err := db.Select(&ids, `SELECT id FROM `+table.name+` WHERE deleted_at < $1 ORDER BY id`, cutoff); err != nil {
+			return result, err
+		}
+		for _, id := range ids {
+			if err := table.purge(db, id); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			*table.count++
+		}
+	}
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteRecipe(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE recipes SET deleted_at=NOW\\(\\) WHERE id=\\$1 AND deleted_at IS NULL").WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, DeleteRecipe(db, 1))
+
+	// Already in the trash
+	mock.ExpectExec("UPDATE recipes SET deleted_at").WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, DeleteRecipe(db, 1), sql.ErrNoRows)
+
+	mock.ExpectExec("UPDATE recipes SET deleted_at=NULL WHERE id=\\$1 AND deleted_at IS NOT NULL").WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, RestoreRecipe(db, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestorePlanOverlap(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	start := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 11, 8, 0, 0, 0, 0, time.UTC)
+	deleted := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE id=\\$1").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id", "deleted_at"}).
+			AddRow(3, start, end, 42, deleted))
+	mock.ExpectQuery("SELECT \\* FROM plan_meals WHERE plan_id").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "meal_id"}))
+	mock.ExpectQuery("SELECT \\* FROM plans WHERE household_id=\\$1 AND start_date <= \\$3").
+		WithArgs(42, sqlmock.AnyArg(), sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "household_id"}).
+			AddRow(7, start, end, 42))
+
+	err := RestorePlan(db, 3)
+	var overlap *PlanOverlapError
+	require.ErrorAs(t, err, &overlap)
+	assert.Equal(t, 7, overlap.Plans[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTrash(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	deleted := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, name, slug, deleted_at FROM recipes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "deleted_at"}).AddRow(1, "Stew", "stew", deleted))
+	mock.ExpectQuery("SELECT id, name, slug, deleted_at FROM meals").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "deleted_at"}))
+	mock.ExpectQuery("SELECT id, start_date, end_date, deleted_at FROM plans").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "deleted_at"}).
+			AddRow(3, deleted, deleted, deleted))
+
+	trash, err := GetTrash(db, 42, 30*24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, trash.Recipes, 1)
+	require.NotNil(t, trash.Recipes[0].PurgeAt)
+	assert.Equal(t, deleted.AddDate(0, 0, 30), *trash.Recipes[0].PurgeAt)
+	assert.Empty(t, trash.Meals)
+	require.Len(t, trash.Plans, 1)
+	require.NotNil(t, trash.Plans[0].PurgeAt)
+	assert.Equal(t, deleted.AddDate(0, 0, 30), *trash.Plans[0].PurgeAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Without a retention nothing is purged
+	mock.ExpectQuery("SELECT id, name, slug, deleted_at FROM recipes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "deleted_at"}).AddRow(1, "Stew", "stew", deleted))
+	mock.ExpectQuery("SELECT id, name, slug, deleted_at FROM meals").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "deleted_at"}))
+	mock.ExpectQuery("SELECT id, start_date, end_date, deleted_at FROM plans").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date", "deleted_at"}))
+
+	trash, err = GetTrash(db, 42, 0)
+	require.NoError(t, err)
+	require.Len(t, trash.Recipes, 1)
+	assert.Nil(t, trash.Recipes[0].PurgeAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecipeDeletionImpact(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT m.id, m.name, m.slug FROM meals m").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug"}).AddRow(5, "Taco night", "taco-night"))
+	mock.ExpectQuery("SELECT DISTINCT p.id, p.start_date, p.end_date FROM plans p").
+		WithArgs(sqlmock.AnyArg(), 42, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_date", "end_date"}).
+			AddRow(3, time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 8, 0, 0, 0, 0, time.UTC)))
+
+	impact, err := RecipeDeletionImpact(db, 1, 42)
+	require.NoError(t, err)
+	assert.False(t, impact.Empty())
+	assert.Equal(t, "Taco night", impact.Meals[0].Name)
+	assert.Equal(t, 3, impact.Plans[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTrash(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	cutoff := time.Date(2026, 9, 18, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id FROM plans WHERE deleted_at < \\$1").WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT id FROM meals WHERE deleted_at < \\$1").WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectBegin()
+	for _, table := range []string{"meal_ingredients", "meal_steps", "meal_recipes", "plan_meals"} {
+		mock.ExpectExec("DELETE FROM " + table + " WHERE meal_id=\\$1").WithArgs(4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("DELETE FROM meals WHERE id=\\$1").WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT id FROM recipes WHERE deleted_at < \\$1").WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	result, err := PurgeTrash(db, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, PurgeResult{Meals: 1}, *result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}